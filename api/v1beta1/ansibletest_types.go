@@ -26,6 +26,7 @@ import (
 type AnsibleTestSpec struct {
 	CommonOptions         `json:",inline"`
 	CommonOpenstackConfig `json:",inline"`
+	WorkflowOptions       `json:",inline"`
 
 	// +kubebuilder:default:={limits: {cpu: "4000m", memory: "4Gi"}, requests: {cpu: "2000m", memory: "2Gi"}}
 	// The desired amount of resources that should be assigned to each test pod
@@ -116,6 +117,22 @@ type AnsibleTestWorkflowSpec struct {
 	// a logs directory.
 	StepName string `json:"stepName"`
 
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// RunIf makes the execution of the workflow step conditional on the outcome
+	// of an earlier workflow step. When the condition is not met the step is
+	// marked as Skipped.
+	RunIf *WorkflowStepRunIf `json:"runIf,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// DependsOn is a list of names of workflow steps that have to finish before
+	// this step is executed. When at least one step of the workflow uses
+	// dependsOn, the steps are no longer executed sequentially. Instead, every
+	// step whose dependencies finished is started, so independent steps run at
+	// the same time. Steps without dependsOn are started right away.
+	DependsOn []string `json:"dependsOn,omitempty"`
+
 	// The desired amount of resources that should be assigned to each test pod
 	// spawned using the AnsibleTest CR. https://pkg.go.dev/k8s.io/api/core/v1#ResourceRequirements
 	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
@@ -231,7 +248,90 @@ func (instance *AnsibleTest) GetStorageClass() string {
 	return instance.Spec.StorageClass
 }
 
+// GetPriority - return the priority of the instance in the lock queue
+func (instance *AnsibleTest) GetPriority() int32 {
+	return instance.Spec.Priority
+}
+
+// GetConcurrencyGroup - return the concurrency group of the instance
+func (instance *AnsibleTest) GetConcurrencyGroup() string {
+	return instance.Spec.ConcurrencyGroup
+}
+
+// GetLockScope - return which instances share the lock with the instance
+func (instance *AnsibleTest) GetLockScope() LockScope {
+	return instance.Spec.LockScope
+}
+
+// GetLockGroup - return the name of the lock shared across namespaces
+func (instance *AnsibleTest) GetLockGroup() string {
+	return instance.Spec.LockGroup
+}
+
+// IsSuspended - return whether the instance is suspended
+func (instance *AnsibleTest) IsSuspended() bool {
+	return instance.Spec.Suspend
+}
+
+// GetSuspendPolicy - return what happens with the running test pods when the
+// instance is suspended
+func (instance *AnsibleTest) GetSuspendPolicy() SuspendPolicy {
+	return instance.Spec.SuspendPolicy
+}
+
+// GetTerminationGracePeriod - return the number of seconds a terminated test
+// pod gets to store its logs
+func (instance *AnsibleTest) GetTerminationGracePeriod() int64 {
+	return instance.Spec.TerminationGracePeriodSeconds
+}
+
+// GetLogsPVCRetentionPolicy - return what happens with the logs PVCs when the
+// instance is deleted
+func (instance *AnsibleTest) GetLogsPVCRetentionPolicy() LogsPVCRetentionPolicy {
+	return instance.Spec.LogsPVCRetentionPolicy
+}
+
+// GetLogsVolume - return the options of the volume that stores the logs of
+// the test pods
+func (instance *AnsibleTest) GetLogsVolume() *LogsVolume {
+	return instance.Spec.LogsVolume
+}
+
+// GetLogsRetention - return when the logs PVCs of the finished runs are
+// deleted
+func (instance *AnsibleTest) GetLogsRetention() *LogsRetention {
+	return instance.Spec.LogsRetention
+}
+
+// IsArtifactsServerEnabled - return true when the artifacts server should
+// serve the logs of the finished testing
+func (instance *AnsibleTest) IsArtifactsServerEnabled() bool {
+	return instance.Spec.ArtifactsServer
+}
+
+// GetArtifactsServerTTL - return the number of seconds the artifacts server
+// runs before it is deleted
+func (instance *AnsibleTest) GetArtifactsServerTTL() int64 {
+	return instance.Spec.ArtifactsServerTTLSeconds
+}
+
+// GetArtifactsUpload - return the bucket the logs of the finished workflow
+// steps are uploaded to
+func (instance *AnsibleTest) GetArtifactsUpload() *ArtifactsUpload {
+	return instance.Spec.ArtifactsUpload
+}
+
+// GetOpenStackConfigMap - return the name of the ConfigMap with clouds.yaml
+func (instance *AnsibleTest) GetOpenStackConfigMap() string {
+	return instance.Spec.OpenStackConfigMap
+}
+
 // SetObservedGeneration - set the observed generation to the current generation
 func (instance *AnsibleTest) SetObservedGeneration() {
 	instance.Status.ObservedGeneration = instance.Generation
 }
+
+// GetStatus - return the common test status
+func (instance *AnsibleTest) GetStatus() *CommonTestStatus {
+	return &instance.Status
+}
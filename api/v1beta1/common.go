@@ -20,6 +20,28 @@ import (
 	"github.com/openstack-k8s-operators/lib-common/modules/common/condition"
 	"github.com/openstack-k8s-operators/lib-common/modules/storage"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// RerunAnnotation is the annotation that starts a new run of a test CR
+	// whose current run finished. A new run is started every time the
+	// annotation is set to a new value.
+	RerunAnnotation = "test.openstack.org/rerun"
+
+	// RerunModeAnnotation is the annotation that selects which workflow steps
+	// are executed by the run started by the RerunAnnotation
+	RerunModeAnnotation = "test.openstack.org/rerun-mode"
+
+	// RerunModeAll - all workflow steps are executed again. This is the
+	// default.
+	RerunModeAll = "all"
+
+	// RerunModeFailed - only the workflow steps whose latest test pod failed
+	// are executed again. The other workflow steps keep their status and
+	// test results.
+	RerunModeFailed = "failed"
 )
 
 // WARNING: This parameter will be deprecated!
@@ -79,10 +101,170 @@ type CommonOptions struct {
 
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	// BackoffLimit allows to define the maximum number of retried executions (defaults to 0).
+	// A failed test pod is recreated by the test-operator up to BackoffLimit times.
 	// +kubebuilder:default:=0
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,xDescriptors={"urn:alm:descriptor:com.tectonic.ui:number"}
 	BackoffLimit *int32 `json:"backoffLimit,omitempty"`
 
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:=10
+	// +kubebuilder:validation:Minimum=0
+	// BackoffDelay is the number of seconds the test-operator waits before it
+	// retries a failed test pod. The delay doubles with each further retry.
+	BackoffDelay int32 `json:"backoffDelay"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:=300
+	// +kubebuilder:validation:Minimum=0
+	// BackoffMaxDelay is the maximum number of seconds the test-operator waits
+	// before it retries a failed test pod.
+	BackoffMaxDelay int32 `json:"backoffMaxDelay"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:=0
+	// +kubebuilder:validation:Minimum=0
+	// Timeout is the maximum number of seconds a test pod is allowed to run.
+	// When the timeout expires the test pod is terminated and the workflow step
+	// is marked as TimedOut. A timed out step counts as a failed step for the
+	// workflow failure policy. Zero means no timeout.
+	Timeout int64 `json:"timeout"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:=0
+	// +kubebuilder:validation:Minimum=0
+	// PendingTimeout is the maximum number of seconds a test pod is allowed to
+	// stay in the Pending phase (e.g. because its image can not be pulled or it
+	// can not be scheduled). When the timeout expires the test pod is marked as
+	// failed and the reason is reported in the TestPodsStarted condition. Zero
+	// means no timeout.
+	PendingTimeout int64 `json:"pendingTimeout"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:=0
+	// Priority of the instance in the queue of instances waiting for the
+	// test-operator-lock. Instances with a higher priority acquire the lock
+	// first. Instances with the same priority acquire the lock in the order in
+	// which they were created.
+	Priority int32 `json:"priority"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxLength=40
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// ConcurrencyGroup is the name of the pool of slots shared by the instances
+	// from all namespaces. The instance acquires a slot for each test pod it
+	// spawns and releases the slot once the pod finishes. The number of test
+	// pods of the group that can run at the same time is configured in the
+	// concurrency-groups key of the test-operator-config ConfigMap in the
+	// namespace of the test-operator and defaults to one. When set, the
+	// instance does not use the test-operator-lock, the Parallel option is
+	// ignored and LockScope can not be Cluster.
+	ConcurrencyGroup string `json:"concurrencyGroup,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:=Namespace
+	// LockScope defines which instances share the test-operator-lock with the
+	// instance. With Namespace the instance waits for the instances from its
+	// namespace. With Cluster the instance waits for the instances from all
+	// namespaces that use the same LockGroup. Instances that do not set
+	// LockGroup share the lock with all instances testing the same cloud
+	// (identified by the auth URL of the default cloud in clouds.yaml). The
+	// Cluster scope can not be used together with ConcurrencyGroup.
+	LockScope LockScope `json:"lockScope"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxLength=40
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// LockGroup is the name of the lock shared by instances from different
+	// namespaces. It is used only when LockScope is Cluster.
+	LockGroup string `json:"lockGroup,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:=false
+	// Suspend stops the test-operator from spawning new test pods for the
+	// instance. The test-operator-lock is released once no test pod of the
+	// instance is running so that other instances can go first. When Suspend
+	// is unset the testing continues with the next unfinished workflow step.
+	// Changing Suspend does not restart the testing.
+	Suspend bool `json:"suspend"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:=Wait
+	// SuspendPolicy defines what happens with the running test pods when the
+	// instance is suspended. With Wait the running test pods are allowed to
+	// finish. With Terminate the running test pods are deleted gracefully and
+	// their workflow steps are executed again once the instance is resumed.
+	SuspendPolicy SuspendPolicy `json:"suspendPolicy"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:=30
+	// +kubebuilder:validation:Minimum=0
+	// TerminationGracePeriodSeconds is the number of seconds a test pod gets
+	// to store its logs in the logs PVC after it receives SIGTERM because the
+	// instance was deleted or suspended with the Terminate SuspendPolicy, or
+	// because the test pod exceeded its timeout. The test pod is killed once
+	// the grace period expires.
+	TerminationGracePeriodSeconds int64 `json:"terminationGracePeriodSeconds"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:=Delete
+	// LogsPVCRetentionPolicy defines what happens with the logs PVCs when the
+	// instance is deleted. With Delete the logs PVCs are deleted together with
+	// the instance. With Retain the logs PVCs are kept and have to be deleted
+	// manually.
+	LogsPVCRetentionPolicy LogsPVCRetentionPolicy `json:"logsPVCRetentionPolicy"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:Optional
+	// LogsVolume defines the volume that stores the logs of the test pods. By
+	// default the logs are stored in a 1Gi ReadWriteOnce PVC.
+	LogsVolume *LogsVolume `json:"logsVolume,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:Optional
+	// LogsRetention defines when the test-operator deletes the logs PVCs of
+	// the finished runs of the instance. The logs PVCs are kept as long as the
+	// instance exists when LogsRetention is not set.
+	LogsRetention *LogsRetention `json:"logsRetention,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:=false
+	// ArtifactsServer - when true, the test-operator starts a read-only HTTP
+	// file server once the testing is finished. The server mounts the logs
+	// PVCs of the instance and its URL is stored in status.artifactsURL. The
+	// server is deleted together with the instance. The server does not
+	// require any authentication and is reachable by every pod that can reach
+	// the namespace of the instance. Several logs PVCs are served by a single
+	// pod, so they should be ReadWriteMany.
+	ArtifactsServer bool `json:"artifactsServer"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// ArtifactsServerTTLSeconds is the number of seconds the artifacts server
+	// runs before it is deleted. The artifacts server runs as long as the
+	// instance exists when ArtifactsServerTTLSeconds is not set.
+	ArtifactsServerTTLSeconds int64 `json:"artifactsServerTTLSeconds,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:Optional
+	// ArtifactsUpload - when set, the test-operator uploads the logs PVC of
+	// each finished workflow step to the S3-compatible bucket. The URL prefix
+	// of the uploaded objects is stored in the status of the workflow step.
+	ArtifactsUpload *ArtifactsUpload `json:"artifactsUpload,omitempty"`
+
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	// +kubebuilder:validation:Optional
 	// Extra configmaps for mounting inside the pod
@@ -122,6 +304,406 @@ type CommonOpenstackConfig struct {
 	OpenStackConfigSecret string `json:"openStackConfigSecret"`
 }
 
+// LockScope defines which instances share the test-operator-lock
+// +kubebuilder:validation:Enum=Namespace;Cluster
+type LockScope string
+
+const (
+	// LockScopeNamespace - the lock is shared by the instances from the same
+	// namespace
+	LockScopeNamespace LockScope = "Namespace"
+
+	// LockScopeCluster - the lock is shared by the instances from all
+	// namespaces that use the same lock group or test the same cloud
+	LockScopeCluster LockScope = "Cluster"
+)
+
+// SuspendPolicy defines what happens with the running test pods when
+// a test CR is suspended
+// +kubebuilder:validation:Enum=Wait;Terminate
+type SuspendPolicy string
+
+const (
+	// SuspendPolicyWait - the running test pods are allowed to finish
+	SuspendPolicyWait SuspendPolicy = "Wait"
+
+	// SuspendPolicyTerminate - the running test pods are deleted gracefully
+	SuspendPolicyTerminate SuspendPolicy = "Terminate"
+)
+
+// LogsVolumeType defines which kind of volume stores the logs of the test
+// pods
+// +kubebuilder:validation:Enum=PersistentVolumeClaim;EmptyDir
+type LogsVolumeType string
+
+const (
+	// LogsVolumeTypePersistentVolumeClaim - the logs are stored in a PVC that
+	// is kept after the test pod finished
+	LogsVolumeTypePersistentVolumeClaim LogsVolumeType = "PersistentVolumeClaim"
+
+	// LogsVolumeTypeEmptyDir - the logs are stored in an emptyDir volume that
+	// is removed together with the test pod
+	LogsVolumeTypeEmptyDir LogsVolumeType = "EmptyDir"
+)
+
+// LogsVolume defines the volume that stores the logs of the test pods
+type LogsVolume struct {
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:=PersistentVolumeClaim
+	// Type of the volume. PersistentVolumeClaim keeps the logs after the test
+	// pod finished. EmptyDir is meant for throwaway runs whose logs are not
+	// needed once the test pod is deleted.
+	Type LogsVolumeType `json:"type,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// Size of the logs PVC or the size limit of the emptyDir volume. Defaults
+	// to 1Gi.
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// AccessModes of the logs PVC. Defaults to ReadWriteOnce.
+	AccessModes []corev1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// VolumeMode of the logs PVC. Only Filesystem is supported because the
+	// logs PVC is mounted into the test pods.
+	VolumeMode *corev1.PersistentVolumeMode `json:"volumeMode,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// Selector of the persistent volumes that can be bound to the logs PVC
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// DataSource of the logs PVC (e.g. a VolumeSnapshot or another PVC)
+	DataSource *corev1.TypedLocalObjectReference `json:"dataSource,omitempty"`
+}
+
+// LogsRetention defines when the logs PVCs of the finished runs of a test CR
+// are deleted. A logs PVC is deleted when any of the rules matches unless
+// KeepOnFailure protects it.
+type LogsRetention struct {
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// KeepRuns is the number of the latest finished runs whose logs PVCs are
+	// kept. The logs PVCs of the older runs are deleted. Zero keeps the logs
+	// PVCs of all runs.
+	KeepRuns int32 `json:"keepRuns,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// TTLSecondsAfterFinished is the number of seconds after which the logs
+	// PVCs of a finished run are deleted. Zero means no TTL.
+	TTLSecondsAfterFinished int64 `json:"ttlSecondsAfterFinished,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// DeleteOnSuccess deletes the logs PVCs of a run as soon as it finished and
+	// all its tests passed.
+	DeleteOnSuccess bool `json:"deleteOnSuccess,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// KeepOnFailure keeps the logs PVCs of the runs whose tests did not pass
+	// regardless of KeepRuns and TTLSecondsAfterFinished.
+	KeepOnFailure bool `json:"keepOnFailure,omitempty"`
+}
+
+// ArtifactsUpload defines the S3-compatible bucket the logs of the finished
+// workflow steps are uploaded to
+type ArtifactsUpload struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	// SecretName is the name of the Secret with the AWS_ACCESS_KEY_ID and
+	// AWS_SECRET_ACCESS_KEY keys used to access the bucket
+	SecretName string `json:"secretName"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	// Bucket the logs are uploaded to
+	Bucket string `json:"bucket"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^https?://`
+	// Endpoint of the S3-compatible object storage (e.g. a MinIO service).
+	// The AWS S3 endpoint is used when Endpoint is not set.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// Region of the bucket
+	Region string `json:"region,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// Prefix of the object keys. The logs of each workflow step are uploaded
+	// under <prefix>/<namespace>/<name>/<pod name>/.
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// LogsPVCRetentionPolicy defines what happens with the logs PVCs when
+// a test CR is deleted
+// +kubebuilder:validation:Enum=Delete;Retain
+type LogsPVCRetentionPolicy string
+
+const (
+	// LogsPVCRetentionPolicyDelete - the logs PVCs are deleted together with
+	// the test CR
+	LogsPVCRetentionPolicyDelete LogsPVCRetentionPolicy = "Delete"
+
+	// LogsPVCRetentionPolicyRetain - the logs PVCs are kept after the test CR
+	// is deleted
+	LogsPVCRetentionPolicyRetain LogsPVCRetentionPolicy = "Retain"
+)
+
+// WorkflowFailurePolicy defines how the test-operator reacts to a failed
+// workflow step
+// +kubebuilder:validation:Enum=Continue;StopOnFirstFailure;StopAfterN
+type WorkflowFailurePolicy string
+
+const (
+	// WorkflowFailurePolicyContinue - all workflow steps are executed no matter
+	// whether the previous steps failed
+	WorkflowFailurePolicyContinue WorkflowFailurePolicy = "Continue"
+
+	// WorkflowFailurePolicyStopOnFirstFailure - the workflow is stopped right
+	// after the first failed step
+	WorkflowFailurePolicyStopOnFirstFailure WorkflowFailurePolicy = "StopOnFirstFailure"
+
+	// WorkflowFailurePolicyStopAfterN - the workflow is stopped once the number
+	// of failed steps reaches WorkflowMaxFailures
+	WorkflowFailurePolicyStopAfterN WorkflowFailurePolicy = "StopAfterN"
+)
+
+// WorkflowOptions defines how the workflow reacts to failed and timed out steps
+type WorkflowOptions struct {
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:=Continue
+	// WorkflowFailurePolicy defines what happens when a workflow step fails.
+	// Continue executes all remaining steps, StopOnFirstFailure stops the
+	// workflow after the first failed step and StopAfterN stops the workflow
+	// once the number of failed steps reaches WorkflowMaxFailures. Steps that
+	// are not executed because of the policy are marked as Skipped.
+	WorkflowFailurePolicy WorkflowFailurePolicy `json:"workflowFailurePolicy"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default:=1
+	// WorkflowMaxFailures is the number of failed workflow steps after which
+	// the workflow is stopped. Used only with the StopAfterN failure policy.
+	WorkflowMaxFailures int32 `json:"workflowMaxFailures"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default:=0
+	// WorkflowTimeout is the maximum number of seconds the whole workflow is
+	// allowed to run, counted from the creation of the first test pod. When
+	// the timeout expires the running test pods are terminated and marked as
+	// TimedOut and the remaining steps are skipped. Zero means no timeout.
+	WorkflowTimeout int64 `json:"workflowTimeout"`
+}
+
+// WorkflowStepPhase is a label for the state of a workflow step
+type WorkflowStepPhase string
+
+const (
+	// WorkflowStepPending - the test pod for the step has not been started yet
+	WorkflowStepPending WorkflowStepPhase = "Pending"
+
+	// WorkflowStepRunning - the test pod for the step is running
+	WorkflowStepRunning WorkflowStepPhase = "Running"
+
+	// WorkflowStepSucceeded - the test pod for the step finished successfully
+	WorkflowStepSucceeded WorkflowStepPhase = "Succeeded"
+
+	// WorkflowStepFailed - the test pod for the step failed
+	WorkflowStepFailed WorkflowStepPhase = "Failed"
+
+	// WorkflowStepTimedOut - the test pod for the step was terminated because
+	// it exceeded its timeout
+	WorkflowStepTimedOut WorkflowStepPhase = "TimedOut"
+
+	// WorkflowStepRetrying - the test pod for the step failed and the step
+	// waits until the test pod is recreated (see BackoffLimit)
+	WorkflowStepRetrying WorkflowStepPhase = "Retrying"
+
+	// WorkflowStepSkipped - the step was not executed
+	WorkflowStepSkipped WorkflowStepPhase = "Skipped"
+)
+
+// WorkflowStepOutcome is the outcome of an earlier workflow step that is
+// required to execute a workflow step
+// +kubebuilder:validation:Enum=Succeeded;Failed;Finished
+type WorkflowStepOutcome string
+
+const (
+	// WorkflowStepOutcomeSucceeded - the referenced step finished successfully
+	WorkflowStepOutcomeSucceeded WorkflowStepOutcome = "Succeeded"
+
+	// WorkflowStepOutcomeFailed - the referenced step failed
+	WorkflowStepOutcomeFailed WorkflowStepOutcome = "Failed"
+
+	// WorkflowStepOutcomeFinished - the referenced step either finished
+	// successfully or failed
+	WorkflowStepOutcomeFinished WorkflowStepOutcome = "Finished"
+)
+
+// WorkflowStepRunIf defines a condition that has to be met in order to
+// execute a workflow step
+type WorkflowStepRunIf struct {
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern:=^[a-z0-9-]+$
+	// StepName is the name of an earlier workflow step
+	StepName string `json:"stepName"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:Required
+	// Outcome of the referenced step that is required to execute the step.
+	// Finished matches both Succeeded and Failed.
+	Outcome WorkflowStepOutcome `json:"outcome"`
+}
+
+// WorkflowStepStatus defines the observed state of a single workflow step
+type WorkflowStepStatus struct {
+	// Name of the workflow step
+	Name string `json:"name"`
+
+	// Index of the workflow step
+	Index int `json:"index"`
+
+	// Phase of the workflow step
+	Phase WorkflowStepPhase `json:"phase"`
+
+	// Attempts - number of test pods spawned for the workflow step. Each
+	// retry of a failed step spawns a new test pod.
+	Attempts int `json:"attempts,omitempty"`
+
+	// DependsOn lists the steps that have to finish before the step is executed
+	DependsOn []string `json:"dependsOn,omitempty"`
+
+	// PodName - name of the latest test pod spawned for the workflow step
+	PodName string `json:"podName,omitempty"`
+
+	// CreationTime - time when the latest test pod was created
+	CreationTime *metav1.Time `json:"creationTime,omitempty"`
+
+	// StartTime - time when the latest test pod was started on a node
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// FinishTime - time when the latest test pod finished
+	FinishTime *metav1.Time `json:"finishTime,omitempty"`
+
+	// ExitCode - exit code of the test container of the latest test pod
+	ExitCode *int32 `json:"exitCode,omitempty"`
+
+	// Reason - reason why the latest test pod terminated (e.g. Completed,
+	// Error, OOMKilled, DeadlineExceeded)
+	Reason string `json:"reason,omitempty"`
+
+	// Message - details about why the latest test pod terminated
+	Message string `json:"message,omitempty"`
+
+	// LogsPVC - name of the PVC that holds the logs of the workflow step
+	LogsPVC string `json:"logsPVC,omitempty"`
+
+	// PodDeleted - the latest test pod was deleted because it exceeded its
+	// timeout before it started on a node or because it exceeded its
+	// pendingTimeout. The step keeps the outcome of the deleted pod.
+	PodDeleted bool `json:"podDeleted,omitempty"`
+
+	// RunIndex - index of the run that executed the workflow step. A run
+	// started in the failed rerun mode executes only the failed steps, their
+	// status from the earlier run is kept next to the new one.
+	RunIndex int `json:"runIndex,omitempty"`
+
+	// ResultsCollected - the output of the latest test pod was already parsed
+	// for test results. The output of a pod is parsed only once even when it
+	// contains no results.
+	ResultsCollected bool `json:"resultsCollected,omitempty"`
+
+	// ArtifactsUploadPhase - phase of the pod that uploads the logs of the
+	// workflow step to the bucket set in ArtifactsUpload. The phase is Failed
+	// only when all attempts to upload the logs failed.
+	ArtifactsUploadPhase corev1.PodPhase `json:"artifactsUploadPhase,omitempty"`
+
+	// ArtifactsUploadAttempts - number of pods spawned to upload the logs of
+	// the workflow step. Each retry of a failed upload spawns a new pod.
+	ArtifactsUploadAttempts int `json:"artifactsUploadAttempts,omitempty"`
+
+	// ArtifactsURL - URL prefix of the objects the logs of the workflow step
+	// were uploaded to. Empty until the upload succeeded.
+	ArtifactsURL string `json:"artifactsURL,omitempty"`
+}
+
+// WorkflowStepsSummary defines the summary of the workflow steps
+type WorkflowStepsSummary struct {
+	// Total - number of workflow steps
+	Total int `json:"total"`
+
+	// Succeeded - number of workflow steps that finished successfully
+	Succeeded int `json:"succeeded"`
+
+	// Failed - number of workflow steps that failed or timed out
+	Failed int `json:"failed"`
+}
+
+// TestResults defines the results of the tests executed by a workflow step
+type TestResults struct {
+	// StepName - name of the workflow step
+	StepName string `json:"stepName"`
+
+	// Index of the workflow step
+	Index int `json:"index"`
+
+	// PodName - name of the test pod the results were collected from
+	PodName string `json:"podName"`
+
+	// Passed - number of tests that passed
+	Passed int `json:"passed"`
+
+	// Failed - number of tests that failed
+	Failed int `json:"failed"`
+
+	// Skipped - number of tests that were skipped
+	Skipped int `json:"skipped"`
+
+	// ExpectedFailures - number of tests that failed as expected
+	ExpectedFailures int `json:"expectedFailures"`
+
+	// FailedTests - names of the tests that failed. The list is truncated
+	// when too many tests failed.
+	FailedTests []string `json:"failedTests,omitempty"`
+}
+
+// TestRunStatus defines the observed state of a finished run of a test CR
+type TestRunStatus struct {
+	// Index of the run. The first run has index zero.
+	Index int `json:"index"`
+
+	// RunID - value of the rerun annotation that started the run
+	RunID string `json:"runID,omitempty"`
+
+	// StartTime - time when the first test pod of the run was started
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// FinishTime - time when the last test pod of the run finished
+	FinishTime *metav1.Time `json:"finishTime,omitempty"`
+
+	// Passed - whether all tests executed by the run passed
+	Passed bool `json:"passed"`
+
+	// Reason - reason of the TestsPassed condition when the run did not pass
+	Reason string `json:"reason,omitempty"`
+
+	// Steps - status of the workflow steps of the run
+	Steps []WorkflowStepStatus `json:"steps,omitempty"`
+
+	// StepsSummary - summary of the workflow steps of the run
+	StepsSummary *WorkflowStepsSummary `json:"stepsSummary,omitempty"`
+
+	// Results - test results of the workflow steps of the run
+	Results []TestResults `json:"results,omitempty"`
+}
+
 // CommonTestStatus defines the observed state of the controller
 type CommonTestStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
@@ -141,6 +723,49 @@ type CommonTestStatus struct {
 
 	// NetworkAttachments status of the deployment pods
 	NetworkAttachments map[string][]string `json:"networkAttachments,omitempty"`
+
+	// Steps - status of the individual workflow steps. When no workflow is
+	// defined the list contains a single step representing the test pod.
+	Steps []WorkflowStepStatus `json:"steps,omitempty"`
+
+	// StepsSummary - summary of the workflow steps
+	StepsSummary *WorkflowStepsSummary `json:"stepsSummary,omitempty"`
+
+	// Results - test results of the finished workflow steps
+	Results []TestResults `json:"results,omitempty"`
+
+	// QueuePosition - position of the instance in the queue of instances
+	// waiting for the test-operator-lock. One means that the instance acquires
+	// the lock next. Zero means that the instance does not wait for the lock.
+	QueuePosition int32 `json:"queuePosition,omitempty"`
+
+	// LockName - name of the lock shared with instances from other namespaces
+	// the instance waits for or holds. Empty when the lock is shared only by
+	// the instances from the namespace of the instance.
+	LockName string `json:"lockName,omitempty"`
+
+	// RunIndex - index of the current run of the instance. The first run has
+	// index zero. The pods and PVCs of each run carry the runIndex label.
+	RunIndex int `json:"runIndex,omitempty"`
+
+	// RunID - value of the test.openstack.org/rerun annotation that started
+	// the current run
+	RunID string `json:"runID,omitempty"`
+
+	// Runs - history of the previous runs of the instance, oldest first
+	Runs []TestRunStatus `json:"runs,omitempty"`
+
+	// ReclaimedLogsStorage - storage requested by the logs PVCs deleted
+	// because of the LogsRetention rules
+	ReclaimedLogsStorage *resource.Quantity `json:"reclaimedLogsStorage,omitempty"`
+
+	// ArtifactsURL - URL of the artifacts server that serves the logs of the
+	// instance. Empty when the artifacts server is not running.
+	ArtifactsURL string `json:"artifactsURL,omitempty"`
+
+	// ArtifactsServerStartTime - time when the artifacts server was started.
+	// The ArtifactsServerTTLSeconds are counted from this time.
+	ArtifactsServerStartTime *metav1.Time `json:"artifactsServerStartTime,omitempty"`
 }
 
 type WorkflowCommonOptions struct {
@@ -175,10 +800,33 @@ type WorkflowCommonOptions struct {
 
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	// BackoffLimit allows to define the maximum number of retried executions (defaults to 0).
+	// A failed test pod is recreated by the test-operator up to BackoffLimit times.
 	// +kubebuilder:default:=0
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,xDescriptors={"urn:alm:descriptor:com.tectonic.ui:number"}
 	BackoffLimit *int32 `json:"backoffLimit,omitempty"`
 
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// Timeout is the maximum number of seconds the test pod of the workflow
+	// step is allowed to run. Zero means no timeout.
+	Timeout *int64 `json:"timeout,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// PendingTimeout is the maximum number of seconds the test pod of the
+	// workflow step is allowed to stay in the Pending phase. Zero means no
+	// timeout.
+	PendingTimeout *int64 `json:"pendingTimeout,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +kubebuilder:validation:Optional
+	// LogsVolume defines the volume that stores the logs of the test pod of
+	// the workflow step. A workflow step that sets LogsVolume gets its own
+	// logs PVC.
+	LogsVolume *LogsVolume `json:"logsVolume,omitempty"`
+
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	// +kubebuilder:validation:Optional
 	// Extra configmaps for mounting inside the pod
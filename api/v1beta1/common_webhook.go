@@ -3,13 +3,19 @@ package v1beta1
 import (
 	"fmt"
 	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/openstack-k8s-operators/lib-common/modules/common/util"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/ptr"
 	goClient "sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
@@ -56,6 +62,41 @@ const (
 	// ErrNameTooLong
 	ErrNameTooLong = "The combined length of %s pod name exceeds the maximum of %d " +
 		"characters. Shorten the CR name or workflow step name to proceed."
+
+	// ErrRunIfUnknownStep
+	ErrRunIfUnknownStep = "%s.Spec.Workflow[%d].RunIf refers to step %s which is not " +
+		"defined earlier in the workflow."
+
+	// ErrDuplicateStepName
+	ErrDuplicateStepName = "%s.Spec.Workflow[%d].StepName %s is already used by " +
+		"another workflow step."
+
+	// ErrDependsOnUnknownStep
+	ErrDependsOnUnknownStep = "%s.Spec.Workflow[%d].DependsOn refers to step %s which " +
+		"is not defined in the workflow."
+
+	// ErrDependsOnCycle
+	ErrDependsOnCycle = "%s.Spec.Workflow contains a dependency cycle: %s."
+
+	// ErrLogsVolumeSize
+	ErrLogsVolumeSize = "%s.Spec.%s.Size must be greater than zero."
+
+	// ErrLogsVolumeAccessMode
+	ErrLogsVolumeAccessMode = "%s.Spec.%s.AccessModes contains %s. The logs volume " +
+		"has to be writable (ReadWriteOnce, ReadWriteMany or ReadWriteOncePod)."
+
+	// ErrLogsVolumeMode
+	ErrLogsVolumeMode = "%s.Spec.%s.VolumeMode %s is not supported. The logs volume " +
+		"is mounted into the test pods and has to use the Filesystem volume mode."
+
+	// ErrLogsVolumeEmptyDir
+	ErrLogsVolumeEmptyDir = "%s.Spec.%s.%s can not be used with the EmptyDir logs " +
+		"volume type."
+
+	// ErrConcurrencyGroupLockScope
+	ErrConcurrencyGroupLockScope = "%[1]s.Spec.ConcurrencyGroup can not be used together " +
+		"with the Cluster %[1]s.Spec.LockScope. The instances of a concurrency group " +
+		"share the slots of the group across namespaces instead of the lock."
 )
 
 const (
@@ -79,6 +120,17 @@ const (
 
 	// WarnSpecUpdated
 	WarnSpecUpdated = "%s CR updated. The associated pods will be recreated to apply changes."
+
+	// WarnWorkflowFailurePolicy
+	WarnWorkflowFailurePolicy = "%[1]s.Spec.WorkflowFailurePolicy is set to %[2]s but " +
+		"%[1]s.Spec.Workflow is empty. The failure policy is applied only to " +
+		"workflow steps."
+
+	// WarnLockGroup is a warning message for LockGroup used without the
+	// Cluster lock scope
+	WarnLockGroup = "%[1]s.Spec.LockGroup is set but %[1]s.Spec.LockScope is " +
+		"not Cluster. The lock group is used only by instances that share the " +
+		"lock across namespaces."
 )
 
 const (
@@ -101,25 +153,67 @@ func SetupDefaults() {
 	SetupTestDefaults(testDefaults)
 }
 
-// ValidatePodName checks if CR name exceeds DNS label length limit
-func ValidatePodName(allErrs field.ErrorList, name, kind string) field.ErrorList {
-	if len(name) >= validation.DNS1123LabelMaxLength {
+// GetPodNameSuffixLength returns the length of the suffixes the test-operator
+// can append to the names of the test pods of the instance: the suffix of the
+// retried test pods (-retry-N), of the runs started by the RerunAnnotation
+// (-run-N) and of the pods that upload the logs (-upload-N). Room for up to
+// 999 runs is reserved once the instance is rerun.
+func GetPodNameSuffixLength(
+	annotations map[string]string,
+	backoffLimit *int32,
+	artifactsUpload *ArtifactsUpload,
+	workflow interface{},
+) int {
+	maxRetries := ptr.Deref(backoffLimit, 0)
+	if workflow != nil {
+		v := reflect.ValueOf(workflow)
+		for i := 0; i < v.Len(); i++ {
+			stepBackoffLimit := v.Index(i).FieldByName("BackoffLimit")
+			if stepBackoffLimit.IsValid() && !stepBackoffLimit.IsNil() {
+				maxRetries = max(maxRetries, int32(stepBackoffLimit.Elem().Int())) // #nosec G115
+			}
+		}
+	}
+
+	suffixLength := 0
+	if maxRetries > 0 {
+		suffixLength += len("-retry-") + len(strconv.Itoa(int(maxRetries)))
+	}
+
+	if _, ok := annotations[RerunAnnotation]; ok {
+		suffixLength += len("-run-999")
+	}
+
+	if artifactsUpload != nil {
+		suffixLength += len("-upload-N")
+	}
+
+	return suffixLength
+}
+
+// ValidatePodName checks if the name of the test pod exceeds DNS label length
+// limit. The suffixLength is the length of the suffixes appended to the CR name
+// (see GetPodNameSuffixLength).
+func ValidatePodName(allErrs field.ErrorList, name, kind string, suffixLength int) field.ErrorList {
+	if podNameLength := len(name) + suffixLength; podNameLength >= validation.DNS1123LabelMaxLength {
 		allErrs = append(allErrs, &field.Error{
 			Type:     field.ErrorTypeInvalid,
-			BadValue: len(name),
+			BadValue: podNameLength,
 			Detail:   fmt.Sprintf(ErrNameTooLong, kind, validation.DNS1123LabelMaxLength),
 		})
 	}
 	return allErrs
 }
 
-// ValidateWorkflowPodNames checks if workflow step pod names exceed DNS label length limit
-func ValidateWorkflowPodNames(allErrs field.ErrorList, name, kind string, workflow interface{}) field.ErrorList {
+// ValidateWorkflowPodNames checks if workflow step pod names exceed DNS label
+// length limit. The suffixLength is the length of the suffixes appended to the
+// pod names (see GetPodNameSuffixLength).
+func ValidateWorkflowPodNames(allErrs field.ErrorList, name, kind string, workflow interface{}, suffixLength int) field.ErrorList {
 	v := reflect.ValueOf(workflow)
 
 	for i := 0; i < v.Len(); i++ {
 		stepName := v.Index(i).FieldByName("StepName").String()
-		podNameLength := len(name) + len(stepName) + len("-sXX-")
+		podNameLength := len(name) + len(stepName) + len("-sXX-") + suffixLength
 		if podNameLength >= validation.DNS1123LabelMaxLength {
 			allErrs = append(allErrs, &field.Error{
 				Type:     field.ErrorTypeInvalid,
@@ -131,6 +225,145 @@ func ValidateWorkflowPodNames(allErrs field.ErrorList, name, kind string, workfl
 	return allErrs
 }
 
+// ValidateWorkflowRunIf checks that runIf conditions of the workflow steps refer
+// only to steps that are defined earlier in the workflow
+func ValidateWorkflowRunIf(allErrs field.ErrorList, kind string, workflow interface{}) field.ErrorList {
+	v := reflect.ValueOf(workflow)
+
+	earlierSteps := map[string]bool{}
+	for i := 0; i < v.Len(); i++ {
+		step := v.Index(i)
+		if runIf := step.FieldByName("RunIf"); runIf.IsValid() && !runIf.IsNil() {
+			stepName := runIf.Elem().FieldByName("StepName").String()
+			if !earlierSteps[stepName] {
+				allErrs = append(allErrs, &field.Error{
+					Type:     field.ErrorTypeInvalid,
+					BadValue: stepName,
+					Detail:   fmt.Sprintf(ErrRunIfUnknownStep, kind, i, stepName),
+				})
+			}
+		}
+		earlierSteps[step.FieldByName("StepName").String()] = true
+	}
+	return allErrs
+}
+
+// ValidateWorkflowDependsOn checks that the workflow steps have unique names,
+// that dependsOn refers only to existing steps and that the dependencies do
+// not form a cycle. Linear workflows that do not use dependsOn are allowed to
+// repeat step names.
+func ValidateWorkflowDependsOn(allErrs field.ErrorList, kind string, workflow interface{}) field.ErrorList {
+	v := reflect.ValueOf(workflow)
+
+	usesDependsOn := false
+	for i := 0; i < v.Len(); i++ {
+		if dependsOn := v.Index(i).FieldByName("DependsOn"); dependsOn.IsValid() && dependsOn.Len() > 0 {
+			usesDependsOn = true
+		}
+	}
+
+	if !usesDependsOn {
+		return allErrs
+	}
+
+	stepNames := make([]string, v.Len())
+	stepIndexes := map[string]int{}
+	for i := 0; i < v.Len(); i++ {
+		stepNames[i] = v.Index(i).FieldByName("StepName").String()
+		if _, ok := stepIndexes[stepNames[i]]; ok {
+			allErrs = append(allErrs, &field.Error{
+				Type:     field.ErrorTypeDuplicate,
+				BadValue: stepNames[i],
+				Detail:   fmt.Sprintf(ErrDuplicateStepName, kind, i, stepNames[i]),
+			})
+			continue
+		}
+		stepIndexes[stepNames[i]] = i
+	}
+
+	dependencies := make([][]int, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		dependsOn := v.Index(i).FieldByName("DependsOn")
+		if !dependsOn.IsValid() {
+			continue
+		}
+
+		for j := 0; j < dependsOn.Len(); j++ {
+			stepName := dependsOn.Index(j).String()
+			dependencyIdx, ok := stepIndexes[stepName]
+			if !ok {
+				allErrs = append(allErrs, &field.Error{
+					Type:     field.ErrorTypeNotFound,
+					BadValue: stepName,
+					Detail:   fmt.Sprintf(ErrDependsOnUnknownStep, kind, i, stepName),
+				})
+				continue
+			}
+			dependencies[i] = append(dependencies[i], dependencyIdx)
+		}
+	}
+
+	if cycle := findDependencyCycle(dependencies); len(cycle) > 0 {
+		cycleNames := make([]string, len(cycle))
+		for i, stepIdx := range cycle {
+			cycleNames[i] = stepNames[stepIdx]
+		}
+
+		allErrs = append(allErrs, &field.Error{
+			Type:     field.ErrorTypeInvalid,
+			BadValue: cycleNames,
+			Detail:   fmt.Sprintf(ErrDependsOnCycle, kind, strings.Join(cycleNames, " -> ")),
+		})
+	}
+
+	return allErrs
+}
+
+// findDependencyCycle returns indexes of the steps that form a dependency
+// cycle (the first step is repeated at the end) or nil when there is no cycle
+func findDependencyCycle(dependencies [][]int) []int {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make([]int, len(dependencies))
+	path := []int{}
+
+	var visit func(stepIdx int) []int
+	visit = func(stepIdx int) []int {
+		state[stepIdx] = visiting
+		path = append(path, stepIdx)
+
+		for _, dependencyIdx := range dependencies[stepIdx] {
+			switch state[dependencyIdx] {
+			case visiting:
+				start := slices.Index(path, dependencyIdx)
+				return append(slices.Clone(path[start:]), dependencyIdx)
+			case unvisited:
+				if cycle := visit(dependencyIdx); cycle != nil {
+					return cycle
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[stepIdx] = visited
+		return nil
+	}
+
+	for stepIdx := range dependencies {
+		if state[stepIdx] == unvisited {
+			if cycle := visit(stepIdx); cycle != nil {
+				return cycle
+			}
+		}
+	}
+
+	return nil
+}
+
 // CheckExtraConfigmapsDeprecation returns warning if ExtraConfigmapsMounts is used
 func CheckExtraConfigmapsDeprecation(allWarn admission.Warnings, extraConfigmaps interface{}) admission.Warnings {
 	if v := reflect.ValueOf(extraConfigmaps); v.Len() > 0 {
@@ -169,6 +402,25 @@ func CheckSELinuxWarning(allWarn admission.Warnings, privileged bool, seLinuxLev
 	return allWarn
 }
 
+// CheckWorkflowFailurePolicyWarning returns warning if a failure policy other
+// than Continue is set for a CR without workflow
+func CheckWorkflowFailurePolicyWarning(allWarn admission.Warnings, options WorkflowOptions, workflowLength int, kind string) admission.Warnings {
+	policy := options.WorkflowFailurePolicy
+	if workflowLength == 0 && policy != "" && policy != WorkflowFailurePolicyContinue {
+		allWarn = append(allWarn, fmt.Sprintf(WarnWorkflowFailurePolicy, kind, policy))
+	}
+	return allWarn
+}
+
+// CheckLockGroupWarning returns warning if LockGroup is set but the lock is
+// not shared across namespaces
+func CheckLockGroupWarning(allWarn admission.Warnings, lockScope LockScope, lockGroup string, kind string) admission.Warnings {
+	if lockGroup != "" && lockScope != LockScopeCluster {
+		allWarn = append(allWarn, fmt.Sprintf(WarnLockGroup, kind))
+	}
+	return allWarn
+}
+
 // ValidateDebugWorkflow validates that debug mode and workflow are not both set
 func ValidateDebugWorkflow(allErrs field.ErrorList, debug bool, kind string) field.ErrorList {
 	if debug {
@@ -181,6 +433,96 @@ func ValidateDebugWorkflow(allErrs field.ErrorList, debug bool, kind string) fie
 	return allErrs
 }
 
+// ValidateConcurrencyGroup validates that a concurrency group is not combined
+// with the lock shared across namespaces
+func ValidateConcurrencyGroup(allErrs field.ErrorList, lockScope LockScope, concurrencyGroup string, kind string) field.ErrorList {
+	if concurrencyGroup != "" && lockScope == LockScopeCluster {
+		allErrs = append(allErrs, &field.Error{
+			Type:     field.ErrorTypeForbidden,
+			BadValue: concurrencyGroup,
+			Detail:   fmt.Sprintf(ErrConcurrencyGroupLockScope, kind),
+		})
+	}
+	return allErrs
+}
+
+// ValidateLogsVolume checks that the logs volume options can be used to store
+// the logs of the test pods. The path tells where the options are defined in
+// the spec (e.g. LogsVolume or Workflow[0].LogsVolume).
+func ValidateLogsVolume(allErrs field.ErrorList, logsVolume *LogsVolume, path, kind string) field.ErrorList {
+	if logsVolume == nil {
+		return allErrs
+	}
+
+	if logsVolume.Size != nil && logsVolume.Size.Sign() <= 0 {
+		allErrs = append(allErrs, &field.Error{
+			Type:     field.ErrorTypeInvalid,
+			BadValue: logsVolume.Size.String(),
+			Detail:   fmt.Sprintf(ErrLogsVolumeSize, kind, path),
+		})
+	}
+
+	writableAccessModes := []corev1.PersistentVolumeAccessMode{
+		corev1.ReadWriteOnce,
+		corev1.ReadWriteMany,
+		corev1.ReadWriteOncePod,
+	}
+	for _, accessMode := range logsVolume.AccessModes {
+		if !slices.Contains(writableAccessModes, accessMode) {
+			allErrs = append(allErrs, &field.Error{
+				Type:     field.ErrorTypeNotSupported,
+				BadValue: accessMode,
+				Detail:   fmt.Sprintf(ErrLogsVolumeAccessMode, kind, path, accessMode),
+			})
+		}
+	}
+
+	if logsVolume.VolumeMode != nil && *logsVolume.VolumeMode != corev1.PersistentVolumeFilesystem {
+		allErrs = append(allErrs, &field.Error{
+			Type:     field.ErrorTypeNotSupported,
+			BadValue: *logsVolume.VolumeMode,
+			Detail:   fmt.Sprintf(ErrLogsVolumeMode, kind, path, *logsVolume.VolumeMode),
+		})
+	}
+
+	if logsVolume.Type == LogsVolumeTypeEmptyDir {
+		pvcOptions := map[string]bool{
+			"AccessModes": len(logsVolume.AccessModes) > 0,
+			"VolumeMode":  logsVolume.VolumeMode != nil,
+			"Selector":    logsVolume.Selector != nil,
+			"DataSource":  logsVolume.DataSource != nil,
+		}
+		for _, option := range []string{"AccessModes", "VolumeMode", "Selector", "DataSource"} {
+			if pvcOptions[option] {
+				allErrs = append(allErrs, &field.Error{
+					Type:     field.ErrorTypeForbidden,
+					BadValue: option,
+					Detail:   fmt.Sprintf(ErrLogsVolumeEmptyDir, kind, path, option),
+				})
+			}
+		}
+	}
+
+	return allErrs
+}
+
+// ValidateWorkflowLogsVolume checks the logs volume options of the workflow
+// steps
+func ValidateWorkflowLogsVolume(allErrs field.ErrorList, kind string, workflow interface{}) field.ErrorList {
+	v := reflect.ValueOf(workflow)
+
+	for i := 0; i < v.Len(); i++ {
+		logsVolume, ok := v.Index(i).FieldByName("LogsVolume").Interface().(*LogsVolume)
+		if !ok {
+			continue
+		}
+
+		path := fmt.Sprintf("Workflow[%d].LogsVolume", i)
+		allErrs = ValidateLogsVolume(allErrs, logsVolume, path, kind)
+	}
+	return allErrs
+}
+
 // BuildValidationError constructs an Invalid error from field errors
 func BuildValidationError(kind, name string, errs field.ErrorList) error {
 	// red error prefix
@@ -198,9 +540,27 @@ func BuildValidationError(kind, name string, errs field.ErrorList) error {
 	return nil
 }
 
-// CheckSpecUpdated returns warning if spec has changed
+// CheckSpecUpdated returns warning if spec has changed. Changing when the test
+// pods are scheduled, suspending or resuming the instance and changing how its
+// test pods are terminated, how long its logs are kept, served or uploaded do
+// not recreate the pods.
 func CheckSpecUpdated(allWarn admission.Warnings, oldSpec, newSpec interface{}, kind string) admission.Warnings {
-	if !cmp.Equal(oldSpec, newSpec) {
+	ignoredFields := cmpopts.IgnoreFields(
+		CommonOptions{},
+		"Priority",
+		"ConcurrencyGroup",
+		"LockScope",
+		"LockGroup",
+		"Suspend",
+		"SuspendPolicy",
+		"TerminationGracePeriodSeconds",
+		"LogsPVCRetentionPolicy",
+		"LogsRetention",
+		"ArtifactsServer",
+		"ArtifactsServerTTLSeconds",
+		"ArtifactsUpload",
+	)
+	if !cmp.Equal(oldSpec, newSpec, ignoredFields) {
 		allWarn = append(allWarn, fmt.Sprintf(WarnSpecUpdated, kind))
 	}
 	return allWarn
@@ -0,0 +1,179 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"github.com/openstack-k8s-operators/lib-common/modules/common/condition"
+)
+
+// Conditions used by the test-operator CRs
+const (
+	// ExecutionCompletedCondition - all test pods finished without any
+	// infrastructure failure
+	ExecutionCompletedCondition condition.Type = "ExecutionCompleted"
+
+	// TestsPassedCondition - all executed tests passed
+	TestsPassedCondition condition.Type = "TestsPassed"
+
+	// TestPodsStartedCondition - no test pod is stuck in the Pending phase
+	TestPodsStartedCondition condition.Type = "TestPodsStarted"
+
+	// ArtifactsUploadedCondition - the logs of all finished workflow steps
+	// were uploaded to the bucket set in ArtifactsUpload
+	ArtifactsUploadedCondition condition.Type = "ArtifactsUploaded"
+)
+
+// Condition reasons used by the test-operator CRs
+const (
+	// TestsFailedReason - a test pod finished with a non-zero exit code
+	TestsFailedReason condition.Reason = "TestsFailed"
+
+	// InfrastructureFailureReason - a test pod could not execute the tests
+	// because of a problem with the cluster (e.g. ImagePullBackOff,
+	// OOMKilled, evicted or unschedulable pod)
+	InfrastructureFailureReason condition.Reason = "InfrastructureFailure"
+
+	// TimedOutReason - a test pod was terminated because it exceeded its
+	// timeout
+	TimedOutReason condition.Reason = "TimedOut"
+
+	// ImagePullFailureReason - the image of a test pod can not be pulled
+	ImagePullFailureReason condition.Reason = "ImagePullFailure"
+
+	// SchedulingFailureReason - a test pod can not be scheduled on any node
+	SchedulingFailureReason condition.Reason = "SchedulingFailure"
+
+	// VolumeNotBoundReason - a PersistentVolumeClaim used by a test pod is
+	// not bound
+	VolumeNotBoundReason condition.Reason = "VolumeNotBound"
+
+	// VolumeMountFailureReason - a volume of a test pod can not be mounted
+	// (e.g. the ConfigMap or Secret it refers to does not exist)
+	VolumeMountFailureReason condition.Reason = "VolumeMountFailure"
+
+	// ContainerConfigFailureReason - the container of a test pod can not be
+	// created from its configuration (e.g. a missing ConfigMap or Secret key)
+	ContainerConfigFailureReason condition.Reason = "ContainerConfigFailure"
+
+	// SecurityContextDeniedReason - a test pod was rejected by the security
+	// context constraints of the cluster
+	SecurityContextDeniedReason condition.Reason = "SecurityContextDenied"
+
+	// PodPendingReason - a test pod is pending because of a reason reported
+	// in a warning event
+	PodPendingReason condition.Reason = "PodPending"
+
+	// PendingTimeoutReason - a test pod exceeded its pendingTimeout
+	PendingTimeoutReason condition.Reason = "PendingTimeout"
+
+	// SuspendedReason - the test CR is suspended and does not spawn new test
+	// pods
+	SuspendedReason condition.Reason = "Suspended"
+
+	// CancelledReason - the test CR was deleted before the test execution
+	// completed
+	CancelledReason condition.Reason = "Cancelled"
+
+	// ArtifactsUploadFailedReason - all attempts to upload the logs of a
+	// workflow step failed
+	ArtifactsUploadFailedReason condition.Reason = "ArtifactsUploadFailed"
+)
+
+// Condition messages used by the test-operator CRs
+const (
+	// ExecutionCompletedInitMessage
+	ExecutionCompletedInitMessage = "Test execution not started"
+
+	// ExecutionCompletedRunningMessage
+	ExecutionCompletedRunningMessage = "Test execution in progress"
+
+	// ExecutionCompletedMessage
+	ExecutionCompletedMessage = "Test execution completed"
+
+	// ExecutionCompletedInfrastructureErrorMessage
+	ExecutionCompletedInfrastructureErrorMessage = "Test execution failed because of an infrastructure problem in workflow steps: %s"
+
+	// ExecutionCompletedTimedOutMessage
+	ExecutionCompletedTimedOutMessage = "Test execution exceeded its timeout in workflow steps: %s"
+
+	// ExecutionCompletedSuspendedMessage
+	ExecutionCompletedSuspendedMessage = "Test execution suspended"
+
+	// ExecutionCompletedCancelledMessage
+	ExecutionCompletedCancelledMessage = "Test execution cancelled because the test CR was deleted"
+
+	// TestsPassedInitMessage
+	TestsPassedInitMessage = "Test results not available yet"
+
+	// TestsPassedMessage
+	TestsPassedMessage = "All tests passed"
+
+	// TestsPassedErrorMessage
+	TestsPassedErrorMessage = "Tests failed in workflow steps: %s"
+
+	// TestsPassedUnavailableMessage
+	TestsPassedUnavailableMessage = "Test results not available because the test execution did not complete"
+
+	// TestPodsStartedInitMessage
+	TestPodsStartedInitMessage = "Test pods not created yet"
+
+	// TestPodsStartedMessage
+	TestPodsStartedMessage = "Test pods started"
+
+	// TestPodsStartedPendingMessage
+	TestPodsStartedPendingMessage = "Test pod %s can not start: %s"
+
+	// TestPodsStartedPendingTimeoutMessage
+	TestPodsStartedPendingTimeoutMessage = "Test pod %s did not start before its pendingTimeout expired: %s"
+
+	// TestPodsStartedCreateErrorMessage
+	TestPodsStartedCreateErrorMessage = "Test pod can not be created: %s"
+
+	// ArtifactsUploadedMessage
+	ArtifactsUploadedMessage = "Logs uploaded"
+
+	// ArtifactsUploadedRunningMessage
+	ArtifactsUploadedRunningMessage = "Logs upload in progress"
+
+	// ArtifactsUploadedErrorMessage
+	ArtifactsUploadedErrorMessage = "Logs upload failed in workflow steps: %s"
+
+	// ArtifactsUploadedImageErrorMessage
+	ArtifactsUploadedImageErrorMessage = "Logs can not be uploaded because the image of the uploader is not set " +
+		"in the %s key of the test-operator-config config map"
+)
+
+// Condition messages used by the TestSchedule CR
+const (
+	// TestScheduleInvalidMessage
+	TestScheduleInvalidMessage = "Invalid schedule %q: %s"
+
+	// TestScheduleCreateErrorMessage
+	TestScheduleCreateErrorMessage = "Test CR can not be created from the template: %s"
+
+	// TestScheduleMessage
+	TestScheduleMessage = "Next run scheduled at %s"
+)
+
+// Condition messages used by the TestSuite CR
+const (
+	// TestSuiteTemplateErrorMessage
+	TestSuiteTemplateErrorMessage = "Template of step %s can not be read: %s"
+
+	// TestSuiteCreateErrorMessage
+	TestSuiteCreateErrorMessage = "Test CR for step %s can not be created: %s"
+)
@@ -209,7 +209,90 @@ func (instance *HorizonTest) GetStorageClass() string {
 	return instance.Spec.StorageClass
 }
 
+// GetPriority - return the priority of the instance in the lock queue
+func (instance *HorizonTest) GetPriority() int32 {
+	return instance.Spec.Priority
+}
+
+// GetConcurrencyGroup - return the concurrency group of the instance
+func (instance *HorizonTest) GetConcurrencyGroup() string {
+	return instance.Spec.ConcurrencyGroup
+}
+
+// GetLockScope - return which instances share the lock with the instance
+func (instance *HorizonTest) GetLockScope() LockScope {
+	return instance.Spec.LockScope
+}
+
+// GetLockGroup - return the name of the lock shared across namespaces
+func (instance *HorizonTest) GetLockGroup() string {
+	return instance.Spec.LockGroup
+}
+
+// IsSuspended - return whether the instance is suspended
+func (instance *HorizonTest) IsSuspended() bool {
+	return instance.Spec.Suspend
+}
+
+// GetSuspendPolicy - return what happens with the running test pods when the
+// instance is suspended
+func (instance *HorizonTest) GetSuspendPolicy() SuspendPolicy {
+	return instance.Spec.SuspendPolicy
+}
+
+// GetTerminationGracePeriod - return the number of seconds a terminated test
+// pod gets to store its logs
+func (instance *HorizonTest) GetTerminationGracePeriod() int64 {
+	return instance.Spec.TerminationGracePeriodSeconds
+}
+
+// GetLogsPVCRetentionPolicy - return what happens with the logs PVCs when the
+// instance is deleted
+func (instance *HorizonTest) GetLogsPVCRetentionPolicy() LogsPVCRetentionPolicy {
+	return instance.Spec.LogsPVCRetentionPolicy
+}
+
+// GetLogsVolume - return the options of the volume that stores the logs of
+// the test pods
+func (instance *HorizonTest) GetLogsVolume() *LogsVolume {
+	return instance.Spec.LogsVolume
+}
+
+// GetLogsRetention - return when the logs PVCs of the finished runs are
+// deleted
+func (instance *HorizonTest) GetLogsRetention() *LogsRetention {
+	return instance.Spec.LogsRetention
+}
+
+// IsArtifactsServerEnabled - return true when the artifacts server should
+// serve the logs of the finished testing
+func (instance *HorizonTest) IsArtifactsServerEnabled() bool {
+	return instance.Spec.ArtifactsServer
+}
+
+// GetArtifactsServerTTL - return the number of seconds the artifacts server
+// runs before it is deleted
+func (instance *HorizonTest) GetArtifactsServerTTL() int64 {
+	return instance.Spec.ArtifactsServerTTLSeconds
+}
+
+// GetArtifactsUpload - return the bucket the logs of the finished workflow
+// steps are uploaded to
+func (instance *HorizonTest) GetArtifactsUpload() *ArtifactsUpload {
+	return instance.Spec.ArtifactsUpload
+}
+
+// GetOpenStackConfigMap - return the name of the ConfigMap with clouds.yaml
+func (instance *HorizonTest) GetOpenStackConfigMap() string {
+	return instance.Spec.OpenStackConfigMap
+}
+
 // SetObservedGeneration - set the observed generation to the current generation
 func (instance *HorizonTest) SetObservedGeneration() {
 	instance.Status.ObservedGeneration = instance.Generation
 }
+
+// GetStatus - return the common test status
+func (instance *HorizonTest) GetStatus() *CommonTestStatus {
+	return &instance.Status
+}
@@ -26,6 +26,7 @@ import (
 	"errors"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -44,10 +45,20 @@ func (r *HorizonTest) Default() {
 func (r *HorizonTest) ValidateCreate() (admission.Warnings, error) {
 	horizontestlog.Info("validate create", "name", r.Name)
 
+	var allErrs field.ErrorList
 	var allWarnings admission.Warnings
+	suffixLength := GetPodNameSuffixLength(r.GetAnnotations(), r.Spec.BackoffLimit, r.Spec.ArtifactsUpload, nil)
 
+	allErrs = ValidatePodName(allErrs, r.Name, r.Kind, suffixLength)
+	allErrs = ValidateLogsVolume(allErrs, r.Spec.LogsVolume, "LogsVolume", r.Kind)
+	allErrs = ValidateConcurrencyGroup(allErrs, r.Spec.LockScope, r.Spec.ConcurrencyGroup, r.Kind)
 	allWarnings = CheckPrivilegedWarning(allWarnings, r.Spec.Privileged, r.Kind)
 	allWarnings = CheckExtraConfigmapsDeprecation(allWarnings, r.Spec.ExtraConfigmapsMounts)
+	allWarnings = CheckLockGroupWarning(allWarnings, r.Spec.LockScope, r.Spec.LockGroup, r.Kind)
+
+	if err := BuildValidationError(r.Kind, r.GetName(), allErrs); err != nil {
+		return allWarnings, err
+	}
 
 	return allWarnings, nil
 }
@@ -63,6 +74,16 @@ func (r *HorizonTest) ValidateUpdate(old runtime.Object) (admission.Warnings, er
 
 	allWarnings := admission.Warnings{}
 	allWarnings = CheckSpecUpdated(allWarnings, oldHorizonTest.Spec, r.Spec, r.Kind)
+
+	var allErrs field.ErrorList
+	suffixLength := GetPodNameSuffixLength(r.GetAnnotations(), r.Spec.BackoffLimit, r.Spec.ArtifactsUpload, nil)
+	allErrs = ValidatePodName(allErrs, r.Name, r.Kind, suffixLength)
+	allErrs = ValidateLogsVolume(allErrs, r.Spec.LogsVolume, "LogsVolume", r.Kind)
+	allErrs = ValidateConcurrencyGroup(allErrs, r.Spec.LockScope, r.Spec.ConcurrencyGroup, r.Kind)
+	if err := BuildValidationError(r.Kind, r.GetName(), allErrs); err != nil {
+		return allWarnings, err
+	}
+
 	return allWarnings, nil
 }
 
@@ -389,6 +389,7 @@ type TempestconfRunSpec struct {
 type TempestSpec struct {
 	CommonOptions         `json:",inline"`
 	CommonOpenstackConfig `json:",inline"`
+	WorkflowOptions       `json:",inline"`
 
 	// +kubebuilder:default:={limits: {cpu: "8000m", memory: "4Gi"}, requests: {cpu: "4000m", memory: "2Gi"}}
 	// The desired amount of resources that should be assigned to each test pod
@@ -533,7 +534,90 @@ func (instance *Tempest) GetStorageClass() string {
 	return instance.Spec.StorageClass
 }
 
+// GetPriority - return the priority of the instance in the lock queue
+func (instance *Tempest) GetPriority() int32 {
+	return instance.Spec.Priority
+}
+
+// GetConcurrencyGroup - return the concurrency group of the instance
+func (instance *Tempest) GetConcurrencyGroup() string {
+	return instance.Spec.ConcurrencyGroup
+}
+
+// GetLockScope - return which instances share the lock with the instance
+func (instance *Tempest) GetLockScope() LockScope {
+	return instance.Spec.LockScope
+}
+
+// GetLockGroup - return the name of the lock shared across namespaces
+func (instance *Tempest) GetLockGroup() string {
+	return instance.Spec.LockGroup
+}
+
+// IsSuspended - return whether the instance is suspended
+func (instance *Tempest) IsSuspended() bool {
+	return instance.Spec.Suspend
+}
+
+// GetSuspendPolicy - return what happens with the running test pods when the
+// instance is suspended
+func (instance *Tempest) GetSuspendPolicy() SuspendPolicy {
+	return instance.Spec.SuspendPolicy
+}
+
+// GetTerminationGracePeriod - return the number of seconds a terminated test
+// pod gets to store its logs
+func (instance *Tempest) GetTerminationGracePeriod() int64 {
+	return instance.Spec.TerminationGracePeriodSeconds
+}
+
+// GetLogsPVCRetentionPolicy - return what happens with the logs PVCs when the
+// instance is deleted
+func (instance *Tempest) GetLogsPVCRetentionPolicy() LogsPVCRetentionPolicy {
+	return instance.Spec.LogsPVCRetentionPolicy
+}
+
+// GetLogsVolume - return the options of the volume that stores the logs of
+// the test pods
+func (instance *Tempest) GetLogsVolume() *LogsVolume {
+	return instance.Spec.LogsVolume
+}
+
+// GetLogsRetention - return when the logs PVCs of the finished runs are
+// deleted
+func (instance *Tempest) GetLogsRetention() *LogsRetention {
+	return instance.Spec.LogsRetention
+}
+
+// IsArtifactsServerEnabled - return true when the artifacts server should
+// serve the logs of the finished testing
+func (instance *Tempest) IsArtifactsServerEnabled() bool {
+	return instance.Spec.ArtifactsServer
+}
+
+// GetArtifactsServerTTL - return the number of seconds the artifacts server
+// runs before it is deleted
+func (instance *Tempest) GetArtifactsServerTTL() int64 {
+	return instance.Spec.ArtifactsServerTTLSeconds
+}
+
+// GetArtifactsUpload - return the bucket the logs of the finished workflow
+// steps are uploaded to
+func (instance *Tempest) GetArtifactsUpload() *ArtifactsUpload {
+	return instance.Spec.ArtifactsUpload
+}
+
+// GetOpenStackConfigMap - return the name of the ConfigMap with clouds.yaml
+func (instance *Tempest) GetOpenStackConfigMap() string {
+	return instance.Spec.OpenStackConfigMap
+}
+
 // SetObservedGeneration - set the observed generation to the current generation
 func (instance *Tempest) SetObservedGeneration() {
 	instance.Status.ObservedGeneration = instance.Generation
 }
+
+// GetStatus - return the common test status
+func (instance *Tempest) GetStatus() *CommonTestStatus {
+	return &instance.Status
+}
@@ -253,6 +253,22 @@ type WorkflowTempestSpec struct {
 	// a logs directory.
 	StepName string `json:"stepName"`
 
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// RunIf makes the execution of the workflow step conditional on the outcome
+	// of an earlier workflow step. When the condition is not met the step is
+	// marked as Skipped.
+	RunIf *WorkflowStepRunIf `json:"runIf,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// DependsOn is a list of names of workflow steps that have to finish before
+	// this step is executed. When at least one step of the workflow uses
+	// dependsOn, the steps are no longer executed sequentially. Instead, every
+	// step whose dependencies finished is started, so independent steps run at
+	// the same time. Steps without dependsOn are started right away.
+	DependsOn []string `json:"dependsOn,omitempty"`
+
 	// +kubebuilder:validation:Optional
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	// By default test-operator executes the test-pods sequentially if multiple
@@ -63,16 +63,24 @@ func (r *Tempest) ValidateCreate() (admission.Warnings, error) {
 
 	var allErrs field.ErrorList
 	var allWarnings admission.Warnings
+	suffixLength := GetPodNameSuffixLength(r.GetAnnotations(), r.Spec.BackoffLimit, r.Spec.ArtifactsUpload, r.Spec.Workflow)
 
 	// Common validations
-	allErrs = ValidatePodName(allErrs, r.Name, r.Kind)
+	allErrs = ValidatePodName(allErrs, r.Name, r.Kind, suffixLength)
+	allErrs = ValidateLogsVolume(allErrs, r.Spec.LogsVolume, "LogsVolume", r.Kind)
+	allErrs = ValidateConcurrencyGroup(allErrs, r.Spec.LockScope, r.Spec.ConcurrencyGroup, r.Kind)
 	allWarnings = CheckPrivilegedWarning(allWarnings, r.Spec.Privileged, r.Kind)
 	allWarnings = CheckExtraConfigmapsDeprecation(allWarnings, r.Spec.ExtraConfigmapsMounts)
+	allWarnings = CheckLockGroupWarning(allWarnings, r.Spec.LockScope, r.Spec.LockGroup, r.Kind)
+	allWarnings = CheckWorkflowFailurePolicyWarning(allWarnings, r.Spec.WorkflowOptions, len(r.Spec.Workflow), r.Kind)
 
 	// Workflow-specific validations
 	if len(r.Spec.Workflow) > 0 {
 		allErrs = ValidateDebugWorkflow(allErrs, r.Spec.Debug, r.Kind)
-		allErrs = ValidateWorkflowPodNames(allErrs, r.Name, r.Kind, r.Spec.Workflow)
+		allErrs = ValidateWorkflowPodNames(allErrs, r.Name, r.Kind, r.Spec.Workflow, suffixLength)
+		allErrs = ValidateWorkflowRunIf(allErrs, r.Kind, r.Spec.Workflow)
+		allErrs = ValidateWorkflowDependsOn(allErrs, r.Kind, r.Spec.Workflow)
+		allErrs = ValidateWorkflowLogsVolume(allErrs, r.Kind, r.Spec.Workflow)
 		allWarnings = CheckSELinuxWarning(allWarnings, r.Spec.Privileged, r.Spec.SELinuxLevel, r.Kind)
 		allWarnings = CheckWorkflowExtraConfigmapsDeprecation(allWarnings, r.Spec.Workflow)
 	}
@@ -104,6 +112,23 @@ func (r *Tempest) ValidateUpdate(old runtime.Object) (admission.Warnings, error)
 
 	allWarnings := admission.Warnings{}
 	allWarnings = CheckSpecUpdated(allWarnings, oldTempest.Spec, r.Spec, r.Kind)
+
+	var allErrs field.ErrorList
+	suffixLength := GetPodNameSuffixLength(r.GetAnnotations(), r.Spec.BackoffLimit, r.Spec.ArtifactsUpload, r.Spec.Workflow)
+	allErrs = ValidatePodName(allErrs, r.Name, r.Kind, suffixLength)
+	allErrs = ValidateLogsVolume(allErrs, r.Spec.LogsVolume, "LogsVolume", r.Kind)
+	allErrs = ValidateConcurrencyGroup(allErrs, r.Spec.LockScope, r.Spec.ConcurrencyGroup, r.Kind)
+	allErrs = ValidateWorkflowLogsVolume(allErrs, r.Kind, r.Spec.Workflow)
+	if len(r.Spec.Workflow) > 0 {
+		allErrs = ValidateWorkflowPodNames(allErrs, r.Name, r.Kind, r.Spec.Workflow, suffixLength)
+		allErrs = ValidateWorkflowRunIf(allErrs, r.Kind, r.Spec.Workflow)
+		allErrs = ValidateWorkflowDependsOn(allErrs, r.Kind, r.Spec.Workflow)
+	}
+
+	if err := BuildValidationError(r.Kind, r.GetName(), allErrs); err != nil {
+		return allWarnings, err
+	}
+
 	return allWarnings, nil
 }
 
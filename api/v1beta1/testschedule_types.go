@@ -0,0 +1,170 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"github.com/openstack-k8s-operators/lib-common/modules/common/condition"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ConcurrencyPolicy describes how the TestSchedule treats a scheduled run
+// while the test CR created by the previous run did not finish yet
+// +kubebuilder:validation:Enum=Allow;Forbid;Replace
+type ConcurrencyPolicy string
+
+const (
+	// AllowConcurrent - the scheduled runs can run at the same time
+	AllowConcurrent ConcurrencyPolicy = "Allow"
+
+	// ForbidConcurrent - the scheduled run is skipped while the previous run
+	// did not finish
+	ForbidConcurrent ConcurrencyPolicy = "Forbid"
+
+	// ReplaceConcurrent - the unfinished previous run is deleted and replaced
+	// by the scheduled run
+	ReplaceConcurrent ConcurrencyPolicy = "Replace"
+)
+
+// TestScheduleSpec defines the desired state of TestSchedule
+type TestScheduleSpec struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Schedule in the cron format (minute hour day-of-month month day-of-week)
+	// evaluated in UTC, e.g. "0 2 * * *" for every night at 2 AM. The macros
+	// @yearly, @monthly, @weekly, @daily and @hourly are supported as well.
+	Schedule string `json:"schedule"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:=Allow
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// ConcurrencyPolicy defines what happens when a run is scheduled while the
+	// test CR created by the previous run did not finish yet. Allow (default)
+	// creates the new test CR anyway, Forbid skips the new run while the
+	// previous one did not finish and Replace deletes the unfinished test CR.
+	ConcurrencyPolicy ConcurrencyPolicy `json:"concurrencyPolicy"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// StartingDeadlineSeconds is the number of seconds after the scheduled time
+	// within which a missed run (e.g. missed while the operator was not
+	// running) can still be started. Runs that miss the deadline are skipped.
+	// When not set, missed runs are never skipped.
+	StartingDeadlineSeconds *int64 `json:"startingDeadlineSeconds,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:=3
+	// +kubebuilder:validation:Minimum=0
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// HistoryLimit is the number of finished test CRs created by the schedule
+	// that are kept. Older finished test CRs are deleted.
+	HistoryLimit int32 `json:"historyLimit"`
+
+	// +kubebuilder:validation:Required
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Template of the test CR created for each scheduled run
+	Template TestTemplate `json:"template"`
+}
+
+// TestTemplate describes the test CR created by a TestSchedule
+type TestTemplate struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=Tempest;Tobiko;AnsibleTest;HorizonTest
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Kind of the created test CRs
+	Kind string `json:"kind"`
+
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Labels added to the created test CRs
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Annotations added to the created test CRs
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Spec of the created test CRs. The spec is validated and defaulted when
+	// the test CR is created.
+	Spec runtime.RawExtension `json:"spec"`
+}
+
+// TestScheduleStatus defines the observed state of TestSchedule
+type TestScheduleStatus struct {
+	// Conditions
+	Conditions condition.Conditions `json:"conditions,omitempty" optional:"true"`
+
+	// ObservedGeneration - the most recent generation observed for this
+	// schedule
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Active - test CRs created by the schedule that did not finish yet
+	Active []corev1.ObjectReference `json:"active,omitempty"`
+
+	// LastScheduleTime - the time of the last scheduled run
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
+	// LastSuccessfulTime - the scheduled time of the last run whose tests
+	// passed
+	LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty"`
+
+	// NextScheduleTime - the time of the next scheduled run
+	NextScheduleTime *metav1.Time `json:"nextScheduleTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Schedule",type="string",JSONPath=".spec.schedule",description="Schedule"
+//+kubebuilder:printcolumn:name="Last Schedule",type="date",JSONPath=".status.lastScheduleTime",description="Last Schedule"
+//+kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.conditions[0].status",description="Status"
+//+kubebuilder:printcolumn:name="Message",type="string",JSONPath=".status.conditions[0].message",description="Message"
+
+// TestSchedule is the Schema for the testschedules API. It creates test CRs
+// based on a cron schedule.
+type TestSchedule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   TestScheduleSpec   `json:"spec,omitempty"`
+	Status TestScheduleStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// TestScheduleList contains a list of TestSchedule
+type TestScheduleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TestSchedule `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TestSchedule{}, &TestScheduleList{})
+}
+
+// GetConditions - return the conditions from the status
+func (instance *TestSchedule) GetConditions() *condition.Conditions {
+	return &instance.Status.Conditions
+}
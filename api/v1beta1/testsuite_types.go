@@ -0,0 +1,178 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"github.com/openstack-k8s-operators/lib-common/modules/common/condition"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestTemplateRef refers to an existing test CR whose spec is used as
+// a template
+type TestTemplateRef struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=Tempest;Tobiko;AnsibleTest;HorizonTest
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Kind of the referenced test CR
+	Kind string `json:"kind"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Name of the referenced test CR. The test CR has to exist in the
+	// namespace of the TestSuite.
+	Name string `json:"name"`
+}
+
+// TestSuiteStep defines a single test CR created by the TestSuite
+// +kubebuilder:validation:XValidation:rule="has(self.template) != has(self.templateRef)",message="exactly one of template and templateRef has to be set"
+type TestSuiteStep struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern:=^[a-z0-9-]+$
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// StepName is the name of the step. The test CR created for the step is
+	// named <TestSuite name>-<step name>.
+	StepName string `json:"stepName"`
+
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// RunIf makes the execution of the step conditional on the outcome of an
+	// earlier step. When the condition is not met the step is marked as
+	// Skipped.
+	RunIf *WorkflowStepRunIf `json:"runIf,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// DependsOn is a list of names of steps that have to finish before this
+	// step is executed. When at least one step uses dependsOn, the steps are
+	// no longer executed sequentially. Instead, every step whose dependencies
+	// finished is started, so independent steps run at the same time.
+	DependsOn []string `json:"dependsOn,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Template of the test CR created for the step
+	Template *TestTemplate `json:"template,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// TemplateRef refers to an existing test CR. The test CR created for the
+	// step gets a copy of its spec.
+	TemplateRef *TestTemplateRef `json:"templateRef,omitempty"`
+}
+
+// TestSuiteSpec defines the desired state of TestSuite
+type TestSuiteSpec struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems=1
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Workflow is the list of test CRs created by the TestSuite. By default
+	// the test CRs are created one after another and each test CR is created
+	// once the previous one finished.
+	Workflow []TestSuiteStep `json:"workflow"`
+
+	// WorkflowOptions defines how the TestSuite reacts to failed steps. The
+	// workflow timeout is counted from the creation of the first test CR.
+	WorkflowOptions `json:",inline"`
+}
+
+// TestSuiteStepStatus defines the observed state of a single TestSuite step
+type TestSuiteStepStatus struct {
+	// Name of the step
+	Name string `json:"name"`
+
+	// Index of the step
+	Index int `json:"index"`
+
+	// Phase of the step
+	Phase WorkflowStepPhase `json:"phase"`
+
+	// DependsOn lists the steps that have to finish before the step is executed
+	DependsOn []string `json:"dependsOn,omitempty"`
+
+	// Kind of the test CR created for the step
+	Kind string `json:"kind"`
+
+	// InstanceName - name of the test CR created for the step
+	InstanceName string `json:"instanceName,omitempty"`
+
+	// StartTime - time when the test CR was created
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// FinishTime - time when the test CR finished
+	FinishTime *metav1.Time `json:"finishTime,omitempty"`
+
+	// Reason - reason why the step failed or was skipped
+	Reason string `json:"reason,omitempty"`
+}
+
+// TestSuiteStatus defines the observed state of TestSuite
+type TestSuiteStatus struct {
+	// Conditions
+	Conditions condition.Conditions `json:"conditions,omitempty" optional:"true"`
+
+	// ObservedGeneration - the most recent generation observed for this
+	// suite
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Steps - the state of each step of the suite
+	Steps []TestSuiteStepStatus `json:"steps,omitempty"`
+
+	// StepsSummary - the summary of the steps of the suite
+	StepsSummary *WorkflowStepsSummary `json:"stepsSummary,omitempty"`
+
+	// Results - the test results collected by the test CRs created by the
+	// suite. The step name of each result is prefixed with the name of the
+	// TestSuite step.
+	Results []TestResults `json:"results,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Steps",type="integer",JSONPath=".status.stepsSummary.total",description="Steps"
+//+kubebuilder:printcolumn:name="Succeeded",type="integer",JSONPath=".status.stepsSummary.succeeded",description="Succeeded"
+//+kubebuilder:printcolumn:name="Failed",type="integer",JSONPath=".status.stepsSummary.failed",description="Failed"
+//+kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.conditions[0].status",description="Status"
+//+kubebuilder:printcolumn:name="Message",type="string",JSONPath=".status.conditions[0].message",description="Message"
+
+// TestSuite is the Schema for the testsuites API. It creates test CRs of
+// different kinds in order or as a DAG and combines their results.
+type TestSuite struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   TestSuiteSpec   `json:"spec,omitempty"`
+	Status TestSuiteStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// TestSuiteList contains a list of TestSuite
+type TestSuiteList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TestSuite `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TestSuite{}, &TestSuiteList{})
+}
+
+// GetConditions - return the conditions from the status
+func (instance *TestSuite) GetConditions() *condition.Conditions {
+	return &instance.Status.Conditions
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	goClient "sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// WarnTestSuiteUpdated
+	WarnTestSuiteUpdated = "%s CR updated. The changes are applied only to the steps " +
+		"whose test CRs were not created yet."
+)
+
+// log is for logging in this package.
+var testsuitelog = logf.Log.WithName("testsuite-resource")
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *TestSuite) ValidateCreate() (admission.Warnings, error) {
+	testsuitelog.Info("validate create", "name", r.Name)
+
+	allErrs := r.validateWorkflow()
+	if err := BuildValidationError(r.Kind, r.GetName(), allErrs); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *TestSuite) ValidateUpdate(old runtime.Object) (admission.Warnings, error) {
+	testsuitelog.Info("validate update", "name", r.Name)
+
+	oldTestSuite, ok := old.(*TestSuite)
+	if !ok || oldTestSuite == nil {
+		return nil, errors.New("unable to convert existing object")
+	}
+
+	allErrs := r.validateWorkflow()
+	if err := BuildValidationError(r.Kind, r.GetName(), allErrs); err != nil {
+		return nil, err
+	}
+
+	allWarnings := admission.Warnings{}
+	if !cmp.Equal(oldTestSuite.Spec, r.Spec) {
+		allWarnings = append(allWarnings, fmt.Sprintf(WarnTestSuiteUpdated, r.Kind))
+	}
+
+	return allWarnings, nil
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *TestSuite) ValidateDelete() (admission.Warnings, error) {
+	testsuitelog.Info("validate delete", "name", r.Name)
+
+	return nil, nil
+}
+
+// validateWorkflow checks the names, runIf conditions and dependencies of the
+// TestSuite steps
+func (r *TestSuite) validateWorkflow() field.ErrorList {
+	var allErrs field.ErrorList
+
+	allErrs = r.validateStepPodNames(allErrs)
+	allErrs = ValidateWorkflowRunIf(allErrs, r.Kind, r.Spec.Workflow)
+	allErrs = ValidateWorkflowDependsOn(allErrs, r.Kind, r.Spec.Workflow)
+
+	return allErrs
+}
+
+// validateStepPodNames checks that the names of the test pods spawned by the
+// test CRs of the steps fit in the DNS label length limit. The test CR of a
+// step is named <TestSuite name>-<step name>. The names of its test pods are
+// checked the same way the webhook of the test CR checks them.
+func (r *TestSuite) validateStepPodNames(allErrs field.ErrorList) field.ErrorList {
+	for _, step := range r.Spec.Workflow {
+		name := fmt.Sprintf("%s-%s", r.Name, step.StepName)
+
+		template := step.Template
+		if template == nil && step.TemplateRef != nil {
+			template = r.getReferencedTemplate(*step.TemplateRef)
+		}
+
+		if template == nil {
+			allErrs = ValidatePodName(allErrs, name, r.Kind, 0)
+			continue
+		}
+
+		allErrs = validateTemplatePodNames(allErrs, name, *template)
+	}
+
+	return allErrs
+}
+
+// getReferencedTemplate returns a template holding the spec of the referenced
+// test CR. Nil is returned when the test CR can not be read. The missing test
+// CR is reported once the TestSuite reaches the step.
+func (r *TestSuite) getReferencedTemplate(ref TestTemplateRef) *TestTemplate {
+	if webhookClient == nil {
+		return nil
+	}
+
+	test := &unstructured.Unstructured{}
+	test.SetAPIVersion(GroupVersion.String())
+	test.SetKind(ref.Kind)
+	key := goClient.ObjectKey{Namespace: r.Namespace, Name: ref.Name}
+	if err := webhookClient.Get(context.TODO(), key, test); err != nil {
+		return nil
+	}
+
+	spec, err := json.Marshal(test.Object["spec"])
+	if err != nil {
+		return nil
+	}
+
+	return &TestTemplate{Kind: ref.Kind, Spec: runtime.RawExtension{Raw: spec}}
+}
+
+// validateTemplatePodNames checks the names of the test pods of the test CR
+// with the given name created from the template
+func validateTemplatePodNames(allErrs field.ErrorList, name string, template TestTemplate) field.ErrorList {
+	// The fields shared by all test CRs that change the names of the test
+	// pods. The rest of the spec is validated once the test CR is created.
+	spec := struct {
+		BackoffLimit    *int32           `json:"backoffLimit,omitempty"`
+		ArtifactsUpload *ArtifactsUpload `json:"artifactsUpload,omitempty"`
+		Workflow        []struct {
+			StepName     string `json:"stepName"`
+			BackoffLimit *int32 `json:"backoffLimit,omitempty"`
+		} `json:"workflow,omitempty"`
+	}{}
+	if len(template.Spec.Raw) > 0 {
+		if err := json.Unmarshal(template.Spec.Raw, &spec); err != nil {
+			return allErrs
+		}
+	}
+
+	suffixLength := GetPodNameSuffixLength(template.Annotations, spec.BackoffLimit, spec.ArtifactsUpload, spec.Workflow)
+	allErrs = ValidatePodName(allErrs, name, template.Kind, suffixLength)
+	if len(spec.Workflow) > 0 {
+		allErrs = ValidateWorkflowPodNames(allErrs, name, template.Kind, spec.Workflow, suffixLength)
+	}
+
+	return allErrs
+}
@@ -38,6 +38,7 @@ type PatchType struct {
 type TobikoSpec struct {
 	CommonOptions         `json:",inline"`
 	CommonOpenstackConfig `json:",inline"`
+	WorkflowOptions       `json:",inline"`
 
 	// +kubebuilder:default:={limits: {cpu: "8000m", memory: "8Gi"}, requests: {cpu: "4000m", memory: "4Gi"}}
 	// The desired amount of resources that should be assigned to each test pod
@@ -218,6 +219,22 @@ type TobikoWorkflowSpec struct {
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,xDescriptors={"urn:alm:descriptor:com.tectonic.ui:number"}
 	// A parameter that contains a definition of a single workflow step.
 	StepName string `json:"stepName"`
+
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// RunIf makes the execution of the workflow step conditional on the outcome
+	// of an earlier workflow step. When the condition is not met the step is
+	// marked as Skipped.
+	RunIf *WorkflowStepRunIf `json:"runIf,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// DependsOn is a list of names of workflow steps that have to finish before
+	// this step is executed. When at least one step of the workflow uses
+	// dependsOn, the steps are no longer executed sequentially. Instead, every
+	// step whose dependencies finished is started, so independent steps run at
+	// the same time. Steps without dependsOn are started right away.
+	DependsOn []string `json:"dependsOn,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -271,7 +288,90 @@ func (instance *Tobiko) GetStorageClass() string {
 	return instance.Spec.StorageClass
 }
 
+// GetPriority - return the priority of the instance in the lock queue
+func (instance *Tobiko) GetPriority() int32 {
+	return instance.Spec.Priority
+}
+
+// GetConcurrencyGroup - return the concurrency group of the instance
+func (instance *Tobiko) GetConcurrencyGroup() string {
+	return instance.Spec.ConcurrencyGroup
+}
+
+// GetLockScope - return which instances share the lock with the instance
+func (instance *Tobiko) GetLockScope() LockScope {
+	return instance.Spec.LockScope
+}
+
+// GetLockGroup - return the name of the lock shared across namespaces
+func (instance *Tobiko) GetLockGroup() string {
+	return instance.Spec.LockGroup
+}
+
+// IsSuspended - return whether the instance is suspended
+func (instance *Tobiko) IsSuspended() bool {
+	return instance.Spec.Suspend
+}
+
+// GetSuspendPolicy - return what happens with the running test pods when the
+// instance is suspended
+func (instance *Tobiko) GetSuspendPolicy() SuspendPolicy {
+	return instance.Spec.SuspendPolicy
+}
+
+// GetTerminationGracePeriod - return the number of seconds a terminated test
+// pod gets to store its logs
+func (instance *Tobiko) GetTerminationGracePeriod() int64 {
+	return instance.Spec.TerminationGracePeriodSeconds
+}
+
+// GetLogsPVCRetentionPolicy - return what happens with the logs PVCs when the
+// instance is deleted
+func (instance *Tobiko) GetLogsPVCRetentionPolicy() LogsPVCRetentionPolicy {
+	return instance.Spec.LogsPVCRetentionPolicy
+}
+
+// GetLogsVolume - return the options of the volume that stores the logs of
+// the test pods
+func (instance *Tobiko) GetLogsVolume() *LogsVolume {
+	return instance.Spec.LogsVolume
+}
+
+// GetLogsRetention - return when the logs PVCs of the finished runs are
+// deleted
+func (instance *Tobiko) GetLogsRetention() *LogsRetention {
+	return instance.Spec.LogsRetention
+}
+
+// IsArtifactsServerEnabled - return true when the artifacts server should
+// serve the logs of the finished testing
+func (instance *Tobiko) IsArtifactsServerEnabled() bool {
+	return instance.Spec.ArtifactsServer
+}
+
+// GetArtifactsServerTTL - return the number of seconds the artifacts server
+// runs before it is deleted
+func (instance *Tobiko) GetArtifactsServerTTL() int64 {
+	return instance.Spec.ArtifactsServerTTLSeconds
+}
+
+// GetArtifactsUpload - return the bucket the logs of the finished workflow
+// steps are uploaded to
+func (instance *Tobiko) GetArtifactsUpload() *ArtifactsUpload {
+	return instance.Spec.ArtifactsUpload
+}
+
+// GetOpenStackConfigMap - return the name of the ConfigMap with clouds.yaml
+func (instance *Tobiko) GetOpenStackConfigMap() string {
+	return instance.Spec.OpenStackConfigMap
+}
+
 // SetObservedGeneration - set the observed generation to the current generation
 func (instance *Tobiko) SetObservedGeneration() {
 	instance.Status.ObservedGeneration = instance.Generation
 }
+
+// GetStatus - return the common test status
+func (instance *Tobiko) GetStatus() *CommonTestStatus {
+	return &instance.Status
+}
@@ -48,11 +48,16 @@ func (r *Tobiko) ValidateCreate() (admission.Warnings, error) {
 
 	var allErrs field.ErrorList
 	var allWarnings admission.Warnings
+	suffixLength := GetPodNameSuffixLength(r.GetAnnotations(), r.Spec.BackoffLimit, r.Spec.ArtifactsUpload, r.Spec.Workflow)
 
 	// Common validations
-	allErrs = ValidatePodName(allErrs, r.Name, r.Kind)
+	allErrs = ValidatePodName(allErrs, r.Name, r.Kind, suffixLength)
+	allErrs = ValidateLogsVolume(allErrs, r.Spec.LogsVolume, "LogsVolume", r.Kind)
+	allErrs = ValidateConcurrencyGroup(allErrs, r.Spec.LockScope, r.Spec.ConcurrencyGroup, r.Kind)
 	allWarnings = CheckPrivilegedWarning(allWarnings, r.Spec.Privileged, r.Kind)
 	allWarnings = CheckExtraConfigmapsDeprecation(allWarnings, r.Spec.ExtraConfigmapsMounts)
+	allWarnings = CheckLockGroupWarning(allWarnings, r.Spec.LockScope, r.Spec.LockGroup, r.Kind)
+	allWarnings = CheckWorkflowFailurePolicyWarning(allWarnings, r.Spec.WorkflowOptions, len(r.Spec.Workflow), r.Kind)
 
 	// Special warning if privileged mode is off
 	if !r.Spec.Privileged {
@@ -62,7 +67,10 @@ func (r *Tobiko) ValidateCreate() (admission.Warnings, error) {
 	// Workflow-specific validations
 	if len(r.Spec.Workflow) > 0 {
 		allErrs = ValidateDebugWorkflow(allErrs, r.Spec.Debug, r.Kind)
-		allErrs = ValidateWorkflowPodNames(allErrs, r.Name, r.Kind, r.Spec.Workflow)
+		allErrs = ValidateWorkflowPodNames(allErrs, r.Name, r.Kind, r.Spec.Workflow, suffixLength)
+		allErrs = ValidateWorkflowRunIf(allErrs, r.Kind, r.Spec.Workflow)
+		allErrs = ValidateWorkflowDependsOn(allErrs, r.Kind, r.Spec.Workflow)
+		allErrs = ValidateWorkflowLogsVolume(allErrs, r.Kind, r.Spec.Workflow)
 		allWarnings = CheckSELinuxWarning(allWarnings, r.Spec.Privileged, r.Spec.SELinuxLevel, r.Kind)
 		allWarnings = CheckWorkflowExtraConfigmapsDeprecation(allWarnings, r.Spec.Workflow)
 	}
@@ -85,6 +93,23 @@ func (r *Tobiko) ValidateUpdate(old runtime.Object) (admission.Warnings, error)
 
 	allWarnings := admission.Warnings{}
 	allWarnings = CheckSpecUpdated(allWarnings, oldTobiko.Spec, r.Spec, r.Kind)
+
+	var allErrs field.ErrorList
+	suffixLength := GetPodNameSuffixLength(r.GetAnnotations(), r.Spec.BackoffLimit, r.Spec.ArtifactsUpload, r.Spec.Workflow)
+	allErrs = ValidatePodName(allErrs, r.Name, r.Kind, suffixLength)
+	allErrs = ValidateLogsVolume(allErrs, r.Spec.LogsVolume, "LogsVolume", r.Kind)
+	allErrs = ValidateConcurrencyGroup(allErrs, r.Spec.LockScope, r.Spec.ConcurrencyGroup, r.Kind)
+	allErrs = ValidateWorkflowLogsVolume(allErrs, r.Kind, r.Spec.Workflow)
+	if len(r.Spec.Workflow) > 0 {
+		allErrs = ValidateWorkflowPodNames(allErrs, r.Name, r.Kind, r.Spec.Workflow, suffixLength)
+		allErrs = ValidateWorkflowRunIf(allErrs, r.Kind, r.Spec.Workflow)
+		allErrs = ValidateWorkflowDependsOn(allErrs, r.Kind, r.Spec.Workflow)
+	}
+
+	if err := BuildValidationError(r.Kind, r.GetName(), allErrs); err != nil {
+		return allWarnings, err
+	}
+
 	return allWarnings, nil
 }
 
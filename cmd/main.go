@@ -22,6 +22,7 @@ import (
 	"flag"
 	"os"
 	"path/filepath"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -31,6 +32,7 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
@@ -71,6 +73,7 @@ func main() {
 	var webhookPort int
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var logsRetentionInterval time.Duration
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -91,6 +94,8 @@ func main() {
 	flag.IntVar(&webhookPort, "webhook-bind-address", 9443, "The port the webhook server binds to.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.DurationVar(&logsRetentionInterval, "logs-retention-interval", controller.DefaultLogsRetentionInterval,
+		"How often the logsRetention rules of the test CRs are enforced.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -197,6 +202,9 @@ func main() {
 		PprofBindAddress:       pprofBindAddress,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "6cce095b.openstack.org",
+		Cache: cache.Options{
+			ByObject: controller.LeaseCacheByObject(),
+		},
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -226,10 +234,16 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Locks shared by test CRs from different namespaces are stored in the
+	// namespace of the operator
+	lockNamespace := os.Getenv("OPERATOR_NAMESPACE")
+
 	tempestReconciler := &controller.TempestReconciler{}
 	tempestReconciler.Client = mgr.GetClient()
 	tempestReconciler.Scheme = mgr.GetScheme()
 	tempestReconciler.Kclient = kclient
+	tempestReconciler.LockNamespace = lockNamespace
+	tempestReconciler.Recorder = mgr.GetEventRecorderFor("tempest-controller")
 	if err := tempestReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Tempest")
 		os.Exit(1)
@@ -238,6 +252,8 @@ func main() {
 	tobikoReconciler.Client = mgr.GetClient()
 	tobikoReconciler.Scheme = mgr.GetScheme()
 	tobikoReconciler.Kclient = kclient
+	tobikoReconciler.LockNamespace = lockNamespace
+	tobikoReconciler.Recorder = mgr.GetEventRecorderFor("tobiko-controller")
 	if err := tobikoReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Tobiko")
 		os.Exit(1)
@@ -246,6 +262,8 @@ func main() {
 	horizonTestReconciler.Client = mgr.GetClient()
 	horizonTestReconciler.Scheme = mgr.GetScheme()
 	horizonTestReconciler.Kclient = kclient
+	horizonTestReconciler.LockNamespace = lockNamespace
+	horizonTestReconciler.Recorder = mgr.GetEventRecorderFor("horizontest-controller")
 	if err := horizonTestReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "HorizonTest")
 		os.Exit(1)
@@ -254,10 +272,41 @@ func main() {
 	ansibleTestReconciler.Client = mgr.GetClient()
 	ansibleTestReconciler.Scheme = mgr.GetScheme()
 	ansibleTestReconciler.Kclient = kclient
+	ansibleTestReconciler.LockNamespace = lockNamespace
+	ansibleTestReconciler.Recorder = mgr.GetEventRecorderFor("ansibletest-controller")
 	if err := ansibleTestReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "AnsibleTest")
 		os.Exit(1)
 	}
+	testScheduleReconciler := &controller.TestScheduleReconciler{}
+	testScheduleReconciler.Client = mgr.GetClient()
+	testScheduleReconciler.Scheme = mgr.GetScheme()
+	testScheduleReconciler.Kclient = kclient
+	testScheduleReconciler.Recorder = mgr.GetEventRecorderFor("testschedule-controller")
+	if err := testScheduleReconciler.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "TestSchedule")
+		os.Exit(1)
+	}
+	testSuiteReconciler := &controller.TestSuiteReconciler{}
+	testSuiteReconciler.Client = mgr.GetClient()
+	testSuiteReconciler.Scheme = mgr.GetScheme()
+	testSuiteReconciler.Kclient = kclient
+	testSuiteReconciler.Recorder = mgr.GetEventRecorderFor("testsuite-controller")
+	if err := testSuiteReconciler.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "TestSuite")
+		os.Exit(1)
+	}
+
+	logsRetentionReconciler := &controller.LogsRetentionReconciler{}
+	logsRetentionReconciler.Client = mgr.GetClient()
+	logsRetentionReconciler.Scheme = mgr.GetScheme()
+	logsRetentionReconciler.Kclient = kclient
+	logsRetentionReconciler.Recorder = mgr.GetEventRecorderFor("logsretention-controller")
+	logsRetentionReconciler.Interval = logsRetentionInterval
+	if err := logsRetentionReconciler.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "LogsRetention")
+		os.Exit(1)
+	}
 
 	testv1beta1.SetupDefaults()
 
@@ -281,6 +330,10 @@ func main() {
 			setupLog.Error(err, "unable to create webhook", "webhook", "AnsibleTest")
 			os.Exit(1)
 		}
+		if err := webhookv1beta1.SetupTestSuiteWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "TestSuite")
+			os.Exit(1)
+		}
 
 		checker = mgr.GetWebhookServer().StartedChecker()
 	}
@@ -9,3 +9,4 @@ oc delete validatingwebhookconfiguration/vhorizontest.kb.io --ignore-not-found
 oc delete mutatingwebhookconfiguration/mhorizontest.kb.io --ignore-not-found
 oc delete validatingwebhookconfiguration/vansibletest.kb.io --ignore-not-found
 oc delete mutatingwebhookconfiguration/mansibletest.kb.io --ignore-not-found
+oc delete validatingwebhookconfiguration/vtestsuite.kb.io --ignore-not-found
@@ -15,6 +15,7 @@ func Pod(
 	annotations map[string]string,
 	podName string,
 	logsPVCName string,
+	logsSubPath string,
 	mountCerts bool,
 	envVars map[string]env.Setter,
 	externalWorkflowCounter int,
@@ -37,7 +38,7 @@ func Pod(
 		PodRunAsUser,
 		instance.Spec.SELinuxLevel,
 		instance.Spec.Tolerations,
-		GetVolumeMounts(instance, mountCerts, AnsibleTestPropagation, externalWorkflowCounter),
+		GetVolumeMounts(instance, logsSubPath, mountCerts, AnsibleTestPropagation, externalWorkflowCounter),
 		GetVolumes(instance, logsPVCName, mountCerts, AnsibleTestPropagation, externalWorkflowCounter),
 	)
 }
@@ -0,0 +1,108 @@
+package ansibletest
+
+import (
+	"bufio"
+	"regexp"
+	"strconv"
+	"strings"
+
+	testv1beta1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
+	util "github.com/openstack-k8s-operators/test-operator/internal/util"
+)
+
+var (
+	// recapRegex matches the line of a host in the PLAY RECAP printed by
+	// ansible-playbook, e.g. "localhost : ok=10 changed=2 unreachable=0
+	// failed=1 skipped=3 rescued=0 ignored=0"
+	recapRegex = regexp.MustCompile(`^\S+\s+:\s+(ok=\d+.*)$`)
+
+	// recapCountRegex matches a single count in the line of a host in the
+	// PLAY RECAP
+	recapCountRegex = regexp.MustCompile(`(\w+)=(\d+)`)
+
+	// taskRegex matches the header of a task, e.g. "TASK [Run tests] ****"
+	taskRegex = regexp.MustCompile(`^TASK \[(.+)\] \**$`)
+
+	// fatalRegex matches the result of a task that failed on a host, e.g.
+	// "fatal: [localhost]: FAILED! => {...}"
+	fatalRegex = regexp.MustCompile(`^fatal: \[.+\]: (FAILED|UNREACHABLE)!`)
+)
+
+// ParseResults parses the output of ansible-playbook and returns the number of
+// passed, failed, skipped and expected-failure tasks together with the names
+// of the failed tasks. The counts of all hosts in all PLAY RECAP sections are
+// summed up. The failures ignored by ignore_errors and the rescued failures
+// are counted as expected failures. The failed tasks whose failure was
+// ignored are not listed. Nil is returned when the output does not contain any
+// PLAY RECAP.
+func ParseResults(logs string) *testv1beta1.TestResults {
+	var results *testv1beta1.TestResults
+
+	scanner := bufio.NewScanner(strings.NewReader(logs))
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), 1024*1024)
+	failedTests := []string{}
+	task := ""
+	failedTask := ""
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "...ignoring" {
+			failedTask = ""
+			continue
+		}
+
+		if match := taskRegex.FindStringSubmatch(line); match != nil {
+			if failedTask != "" {
+				failedTests = util.AppendFailedTest(failedTests, failedTask)
+				failedTask = ""
+			}
+			task = match[1]
+			continue
+		}
+
+		if fatalRegex.MatchString(line) {
+			failedTask = task
+			continue
+		}
+
+		if strings.HasPrefix(line, "PLAY RECAP") {
+			if failedTask != "" {
+				failedTests = util.AppendFailedTest(failedTests, failedTask)
+				failedTask = ""
+			}
+			if results == nil {
+				results = &testv1beta1.TestResults{}
+			}
+			continue
+		}
+
+		match := recapRegex.FindStringSubmatch(line)
+		if match == nil || results == nil {
+			continue
+		}
+
+		for _, count := range recapCountRegex.FindAllStringSubmatch(match[1], -1) {
+			value, err := strconv.Atoi(count[2])
+			if err != nil {
+				continue
+			}
+
+			switch count[1] {
+			case "ok":
+				results.Passed += value
+			case "failed", "unreachable":
+				results.Failed += value
+			case "skipped":
+				results.Skipped += value
+			case "ignored", "rescued":
+				results.ExpectedFailures += value
+			}
+		}
+	}
+
+	if results != nil && len(failedTests) > 0 {
+		results.FailedTests = failedTests
+	}
+
+	return results
+}
@@ -0,0 +1,95 @@
+package ansibletest
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega" //revive:disable:dot-imports
+
+	testv1beta1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
+)
+
+const ansiblePassedOutput = `PLAY [Run the tests] ***********************************************************
+
+TASK [Gathering Facts] *********************************************************
+ok: [localhost]
+
+TASK [Check the endpoints] *****************************************************
+changed: [localhost]
+
+TASK [Check the optional service] **********************************************
+skipping: [localhost]
+
+PLAY RECAP *********************************************************************
+localhost                  : ok=2    changed=1    unreachable=0    failed=0    skipped=1    rescued=0    ignored=0
+`
+
+const ansibleFailedOutput = `PLAY [Run the tests] ***********************************************************
+
+TASK [Gathering Facts] *********************************************************
+ok: [localhost]
+
+TASK [Clean up the previous run] ***********************************************
+fatal: [localhost]: FAILED! => {"changed": false, "msg": "No such file or directory"}
+...ignoring
+
+TASK [Run the smoke tests] *****************************************************
+fatal: [localhost]: FAILED! => {"changed": true, "msg": "non-zero return code", "rc": 1}
+
+PLAY RECAP *********************************************************************
+localhost                  : ok=1    changed=0    unreachable=0    failed=1    skipped=0    rescued=0    ignored=1
+`
+
+func TestParseResults(t *testing.T) {
+	tests := []struct {
+		name     string
+		logs     string
+		expected *testv1beta1.TestResults
+	}{
+		{
+			name:     "empty output",
+			logs:     "",
+			expected: nil,
+		},
+		{
+			name:     "output without recap",
+			logs:     "ERROR! the playbook: tests.yaml could not be found\n",
+			expected: nil,
+		},
+		{
+			name: "passed tasks",
+			logs: ansiblePassedOutput,
+			expected: &testv1beta1.TestResults{
+				Passed:  2,
+				Skipped: 1,
+			},
+		},
+		{
+			name: "failed tasks",
+			logs: ansibleFailedOutput,
+			expected: &testv1beta1.TestResults{
+				Passed:           1,
+				Failed:           1,
+				ExpectedFailures: 1,
+				FailedTests:      []string{"Run the smoke tests"},
+			},
+		},
+		{
+			name: "multiple playbook runs are summed up",
+			logs: ansiblePassedOutput + ansibleFailedOutput,
+			expected: &testv1beta1.TestResults{
+				Passed:           3,
+				Failed:           1,
+				Skipped:          1,
+				ExpectedFailures: 1,
+				FailedTests:      []string{"Run the smoke tests"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(ParseResults(tt.logs)).To(Equal(tt.expected))
+		})
+	}
+}
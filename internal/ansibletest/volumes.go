@@ -52,9 +52,12 @@ func GetVolumes(
 	return volumes
 }
 
-// GetVolumeMounts - returns a list of volume mounts for the test container
+// GetVolumeMounts - returns a list of volume mounts for the test container.
+// When logsSubPath is set only the given subdirectory of the logs PVC is
+// mounted.
 func GetVolumeMounts(
 	instance *testv1beta1.AnsibleTest,
+	logsSubPath string,
 	mountCerts bool,
 	svc []storage.PropagationType,
 	externalWorkflowCounter int,
@@ -62,7 +65,7 @@ func GetVolumeMounts(
 	volumeMounts := []corev1.VolumeMount{
 		util.CreateVolumeMount(util.TestOperatorEphemeralVolumeNameWorkdir, "/var/lib/ansible", false),
 		util.CreateVolumeMount(util.TestOperatorEphemeralVolumeNameTmp, "/tmp", false),
-		util.CreateVolumeMount(util.TestOperatorLogsVolumeName, "/var/lib/AnsibleTests/external_files", false),
+		util.CreateVolumeMountWithSubPath(util.TestOperatorLogsVolumeName, "/var/lib/AnsibleTests/external_files", logsSubPath, false),
 		util.CreateOpenstackConfigVolumeMount(instance.Spec.OpenStackConfigMap, "/etc/openstack/clouds.yaml"),
 		util.CreateOpenstackConfigVolumeMount(instance.Spec.OpenStackConfigMap, "/var/lib/ansible/.config/openstack/clouds.yaml"),
 		util.CreateOpenstackConfigSecretVolumeMount(instance.Spec.OpenStackConfigSecret, "/var/lib/ansible/.config/openstack/secure.yaml"),
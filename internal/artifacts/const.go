@@ -0,0 +1,66 @@
+// Package artifacts provides the read-only HTTP file server that serves the
+// logs of the finished test CRs and the pod that uploads them to an
+// S3-compatible bucket
+package artifacts
+
+const (
+	// ServiceName is the name of the artifacts server service
+	ServiceName = "artifacts-server"
+
+	// NameSuffix is appended to the name of the instance to get the name of
+	// the artifacts server pod and service
+	NameSuffix = "-artifacts"
+
+	// Port is the port the artifacts server listens on
+	Port = int32(8080)
+
+	// MountPath is the directory served by the artifacts server. Each logs
+	// PVC is mounted in a subdirectory named after the PVC.
+	MountPath = "/var/lib/test-operator-artifacts"
+
+	// PodRunAsUser is the UID to run the artifacts server pod as
+	PodRunAsUser = int64(1001)
+
+	// ImageKey is the key of the test-operator-config ConfigMap that
+	// overrides the image of the artifacts server
+	ImageKey = "artifacts-server-image"
+
+	// RelatedImage is the environment variable with the default image of
+	// the artifacts server
+	RelatedImage = "RELATED_IMAGE_TEST_ARTIFACTS_SERVER_IMAGE_URL_DEFAULT"
+
+	// DefaultContainerImageURL is the image of the artifacts server used when
+	// neither the ImageKey nor the RelatedImage is set. The server needs only
+	// python3, so it reuses the Tempest image that is listed in the related
+	// images of the operator and is already present on the nodes that ran
+	// the tests.
+	DefaultContainerImageURL = "quay.io/podified-antelope-centos9/openstack-tempest-all:current-podified"
+
+	// UploaderNameSuffix is appended to the name of the test pod to get the
+	// name of the pod that uploads its logs. The number of the attempt
+	// follows the suffix.
+	UploaderNameSuffix = "-upload"
+
+	// UploaderMaxAttempts is the number of pods spawned to upload the logs of
+	// a test pod before the upload is reported as failed
+	UploaderMaxAttempts = 3
+
+	// UploaderImageKey is the key of the test-operator-config ConfigMap that
+	// overrides the image of the uploader
+	UploaderImageKey = "artifacts-uploader-image"
+
+	// UploaderRelatedImage is the environment variable with the default
+	// image of the uploader. The uploader has no built-in default image as
+	// the AWS CLI is not shipped in the images of the operator. The logs are
+	// not uploaded until either the UploaderImageKey or the
+	// UploaderRelatedImage is set.
+	UploaderRelatedImage = "RELATED_IMAGE_TEST_ARTIFACTS_UPLOADER_IMAGE_URL_DEFAULT"
+
+	// LogsDirAnnotation stores the directory of the logs PVC the test pod
+	// writes its logs to. The directory is uploaded by the uploader.
+	LogsDirAnnotation = "test.openstack.org/logs-dir"
+
+	// PVCHashAnnotation stores the hash of the logs PVCs mounted in the
+	// artifacts server pod
+	PVCHashAnnotation = "test.openstack.org/artifacts-pvc-hash"
+)
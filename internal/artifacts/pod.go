@@ -0,0 +1,127 @@
+package artifacts
+
+import (
+	"fmt"
+	"path/filepath"
+
+	util "github.com/openstack-k8s-operators/test-operator/internal/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Pod - prepare pod that serves the logs PVCs of the instance read-only
+func Pod(
+	name string,
+	namespace string,
+	labels map[string]string,
+	annotations map[string]string,
+	containerImage string,
+	logsPVCNames []string,
+	nodeSelector map[string]string,
+	tolerations []corev1.Toleration,
+) *corev1.Pod {
+	falseVar := false
+	runAsUser := PodRunAsUser
+	securityContext := util.GetSecurityContext(runAsUser, nil, false)
+
+	volumes := []corev1.Volume{}
+	volumeMounts := []corev1.VolumeMount{}
+	for idx, logsPVCName := range logsPVCNames {
+		volumeName := fmt.Sprintf("%s-%d", util.TestOperatorLogsVolumeName, idx)
+		volumes = append(volumes, corev1.Volume{
+			Name: volumeName,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: logsPVCName,
+					ReadOnly:  true,
+				},
+			},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      volumeName,
+			MountPath: filepath.Join(MountPath, logsPVCName),
+			ReadOnly:  true,
+		})
+	}
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: corev1.PodSpec{
+			AutomountServiceAccountToken: &falseVar,
+			RestartPolicy:                corev1.RestartPolicyAlways,
+			NodeSelector:                 nodeSelector,
+			Tolerations:                  tolerations,
+			SecurityContext: &corev1.PodSecurityContext{
+				RunAsUser:  &runAsUser,
+				RunAsGroup: &runAsUser,
+			},
+			Containers: []corev1.Container{
+				{
+					Name:            ServiceName,
+					Image:           containerImage,
+					ImagePullPolicy: corev1.PullIfNotPresent,
+					Command: []string{
+						"python3", "-m", "http.server", fmt.Sprint(Port),
+						"--directory", MountPath,
+					},
+					Ports: []corev1.ContainerPort{
+						{
+							Name:          "http",
+							ContainerPort: Port,
+							Protocol:      corev1.ProtocolTCP,
+						},
+					},
+					ReadinessProbe: &corev1.Probe{
+						ProbeHandler: corev1.ProbeHandler{
+							TCPSocket: &corev1.TCPSocketAction{
+								Port: intstr.FromInt32(Port),
+							},
+						},
+					},
+					VolumeMounts:    volumeMounts,
+					SecurityContext: &securityContext,
+				},
+			},
+			Volumes: volumes,
+		},
+	}
+}
+
+// Service - prepare service that exposes the artifacts server pod
+func Service(
+	name string,
+	namespace string,
+	labels map[string]string,
+	selector map[string]string,
+) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: corev1.ServiceSpec{
+			Type:     corev1.ServiceTypeClusterIP,
+			Selector: selector,
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "http",
+					Port:       Port,
+					TargetPort: intstr.FromInt32(Port),
+					Protocol:   corev1.ProtocolTCP,
+				},
+			},
+		},
+	}
+}
+
+// URL - return the in-cluster URL of the artifacts server service
+func URL(name string, namespace string) string {
+	return fmt.Sprintf("http://%s.%s.svc:%d/", name, namespace, Port)
+}
@@ -0,0 +1,162 @@
+package artifacts
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	testv1beta1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
+	util "github.com/openstack-k8s-operators/test-operator/internal/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// UploaderPod - prepare pod that uploads the logs of a finished test pod to
+// the bucket. Only the logsDir directory of the logs PVC is uploaded as the
+// PVC can hold the logs of other test pods as well. The whole PVC is uploaded
+// when logsDir is empty. The uploader is scheduled to the nodeName node when
+// it is set so that it can mount a ReadWriteOnce logs PVC of a test pod that
+// ran on that node.
+func UploaderPod(
+	name string,
+	namespace string,
+	labels map[string]string,
+	containerImage string,
+	upload testv1beta1.ArtifactsUpload,
+	objectKey string,
+	logsPVCName string,
+	logsDir string,
+	nodeName string,
+	testPod *corev1.Pod,
+) *corev1.Pod {
+	falseVar := false
+	runAsUser := PodRunAsUser
+	securityContext := util.GetSecurityContext(runAsUser, nil, false)
+
+	command := []string{
+		"aws", "s3", "cp", "--recursive", "--no-progress",
+		path.Join(MountPath, logsDir), "s3://" + upload.Bucket + "/" + objectKey,
+	}
+	if upload.Endpoint != "" {
+		command = append(command, "--endpoint-url", upload.Endpoint)
+	}
+
+	envVars := []corev1.EnvVar{
+		// The aws cli stores its cache in the home directory
+		{Name: "HOME", Value: "/tmp"},
+	}
+	if upload.Region != "" {
+		envVars = append(envVars, corev1.EnvVar{Name: "AWS_DEFAULT_REGION", Value: upload.Region})
+	}
+
+	// The node affinity lets the scheduler check the taints and resources of
+	// the node, unlike setting the node name of the pod
+	var affinity *corev1.Affinity
+	if nodeName != "" {
+		affinity = &corev1.Affinity{
+			NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{
+						{
+							MatchFields: []corev1.NodeSelectorRequirement{
+								{
+									Key:      "metadata.name",
+									Operator: corev1.NodeSelectorOpIn,
+									Values:   []string{nodeName},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: corev1.PodSpec{
+			AutomountServiceAccountToken: &falseVar,
+			RestartPolicy:                corev1.RestartPolicyNever,
+			Affinity:                     affinity,
+			NodeSelector:                 testPod.Spec.NodeSelector,
+			Tolerations:                  testPod.Spec.Tolerations,
+			SecurityContext: &corev1.PodSecurityContext{
+				RunAsUser:  &runAsUser,
+				RunAsGroup: &runAsUser,
+			},
+			Containers: []corev1.Container{
+				{
+					Name:            "artifacts-uploader",
+					Image:           containerImage,
+					ImagePullPolicy: corev1.PullIfNotPresent,
+					Command:         command,
+					Env:             envVars,
+					EnvFrom: []corev1.EnvFromSource{
+						{
+							SecretRef: &corev1.SecretEnvSource{
+								LocalObjectReference: corev1.LocalObjectReference{
+									Name: upload.SecretName,
+								},
+							},
+						},
+					},
+					VolumeMounts: []corev1.VolumeMount{
+						{
+							Name:      util.TestOperatorLogsVolumeName,
+							MountPath: MountPath,
+							ReadOnly:  true,
+						},
+						{
+							Name:      util.TestOperatorEphemeralVolumeNameTmp,
+							MountPath: "/tmp",
+						},
+					},
+					SecurityContext: &securityContext,
+				},
+			},
+			Volumes: []corev1.Volume{
+				{
+					Name: util.TestOperatorLogsVolumeName,
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+							ClaimName: logsPVCName,
+							ReadOnly:  true,
+						},
+					},
+				},
+				{
+					Name: util.TestOperatorEphemeralVolumeNameTmp,
+					VolumeSource: corev1.VolumeSource{
+						EmptyDir: &corev1.EmptyDirVolumeSource{},
+					},
+				},
+			},
+		},
+	}
+}
+
+// UploaderName - return the name of the pod that uploads the logs of the test
+// pod in the given attempt. The first attempt is one.
+func UploaderName(testPodName string, attempt int) string {
+	return fmt.Sprintf("%s%s-%d", testPodName, UploaderNameSuffix, attempt)
+}
+
+// ObjectKey - return the key prefix of the objects the logs of the test pod
+// are uploaded to
+func ObjectKey(upload testv1beta1.ArtifactsUpload, namespace string, instanceName string, podName string) string {
+	return strings.TrimPrefix(path.Join(upload.Prefix, namespace, instanceName, podName), "/") + "/"
+}
+
+// ObjectURL - return the URL prefix of the objects with the given key prefix.
+// The path-style URL is used with a custom endpoint (e.g. MinIO).
+func ObjectURL(upload testv1beta1.ArtifactsUpload, objectKey string) string {
+	if upload.Endpoint != "" {
+		return strings.TrimSuffix(upload.Endpoint, "/") + "/" + upload.Bucket + "/" + objectKey
+	}
+
+	return "s3://" + upload.Bucket + "/" + objectKey
+}
@@ -25,9 +25,14 @@ import (
 	"github.com/openstack-k8s-operators/lib-common/modules/common/env"
 	testv1beta1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
 	"github.com/openstack-k8s-operators/test-operator/internal/ansibletest"
+	coordinationv1 "k8s.io/api/coordination/v1"
 	corev1 "k8s.io/api/core/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
 
 // AnsibleTestReconciler reconciles an AnsibleTest object
@@ -43,12 +48,18 @@ func (r *AnsibleTestReconciler) GetLogger(ctx context.Context) logr.Logger {
 // +kubebuilder:rbac:groups=test.openstack.org,resources=ansibletests,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=test.openstack.org,resources=ansibletests/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=test.openstack.org,resources=ansibletests/finalizers,verbs=update;patch
+// +kubebuilder:rbac:groups=test.openstack.org,resources=tempests;tobikoes;ansibletests;horizontests,verbs=get;list;watch
 // +kubebuilder:rbac:groups=k8s.cni.cncf.io,resources=network-attachment-definitions,verbs=get;list;watch
 // +kubebuilder:rbac:groups="security.openshift.io",resourceNames=anyuid;privileged;nonroot;nonroot-v2,resources=securitycontextconstraints,verbs=use
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete;
 // +kubebuilder:rbac:groups="",resources=pods,verbs=create;delete;get;list;patch;update;watch
+// +kubebuilder:rbac:groups="",resources=pods/status,verbs=get;patch;update
+// +kubebuilder:rbac:groups="",resources=events,verbs=get;list;create;patch
+// +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=pods/log,verbs=get
 // +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;create;update;watch;patch;delete
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile - AnsibleTest
 func (r *AnsibleTestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -58,16 +69,19 @@ func (r *AnsibleTestReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		ServiceName:             ansibletest.ServiceName,
 		NeedsNetworkAttachments: false,
 		NeedsConfigMaps:         false,
-		NeedsFinalizer:          false,
+		NeedsFinalizer:          true,
 		SupportsWorkflow:        true,
 
-		BuildPod: func(ctx context.Context, instance *testv1beta1.AnsibleTest, labels, annotations map[string]string, workflowStepIndex int, pvcIndex int) (*corev1.Pod, error) {
-			return r.buildAnsibleTestPod(ctx, instance, labels, annotations, workflowStepIndex, pvcIndex)
+		BuildPod: func(ctx context.Context, instance *testv1beta1.AnsibleTest, labels, annotations map[string]string, workflowStepIndex int, attempt int, pvcIndex int) (*corev1.Pod, error) {
+			return r.buildAnsibleTestPod(ctx, instance, labels, annotations, workflowStepIndex, attempt, pvcIndex)
 		},
 
 		GetInitialConditions: func() []*condition.Condition {
 			return []*condition.Condition{
 				condition.UnknownCondition(condition.ReadyCondition, condition.InitReason, condition.ReadyInitMessage),
+				condition.UnknownCondition(testv1beta1.ExecutionCompletedCondition, condition.InitReason, testv1beta1.ExecutionCompletedInitMessage),
+				condition.UnknownCondition(testv1beta1.TestsPassedCondition, condition.InitReason, testv1beta1.TestsPassedInitMessage),
+				condition.UnknownCondition(testv1beta1.TestPodsStartedCondition, condition.InitReason, testv1beta1.TestPodsStartedInitMessage),
 				condition.UnknownCondition(condition.InputReadyCondition, condition.InitReason, condition.InputReadyInitMessage),
 				condition.UnknownCondition(condition.DeploymentReadyCondition, condition.InitReason, condition.DeploymentReadyInitMessage),
 			}
@@ -77,6 +91,16 @@ func (r *AnsibleTestReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			return r.ValidateOpenstackInputs(ctx, instance, instance.Spec.OpenStackConfigMap, instance.Spec.OpenStackConfigSecret)
 		},
 
+		ParseTestResults: ansibletest.ParseResults,
+
+		// The first attempt stores its logs in the root of the logs PVC
+		GetLogsDirName: func(instance *testv1beta1.AnsibleTest, workflowStepIndex int, attempt int) string {
+			if attempt == 0 {
+				return ""
+			}
+			return r.GetPodName(instance, workflowStepIndex, attempt)
+		},
+
 		GetSpec: func(instance *testv1beta1.AnsibleTest) interface{} {
 			return &instance.Spec
 		},
@@ -88,6 +112,10 @@ func (r *AnsibleTestReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		GetWorkflowLength: func(instance *testv1beta1.AnsibleTest) int {
 			return len(instance.Spec.Workflow)
 		},
+
+		GetWorkflowOptions: func(instance *testv1beta1.AnsibleTest) testv1beta1.WorkflowOptions {
+			return instance.Spec.WorkflowOptions
+		},
 	}
 
 	return CommonReconcile(ctx, &r.Reconciler, req, instance, config, r.GetLogger(ctx))
@@ -98,14 +126,22 @@ func (r *AnsibleTestReconciler) buildAnsibleTestPod(
 	instance *testv1beta1.AnsibleTest,
 	labels, annotations map[string]string,
 	workflowStepIndex int,
+	attempt int,
 	pvcIndex int,
 ) (*corev1.Pod, error) {
 	mountCerts := r.CheckSecretExists(ctx, instance, "combined-ca-bundle")
 	envVars := r.PrepareAnsibleEnv(instance)
 
-	podName := r.GetPodName(instance, workflowStepIndex)
+	podName := r.GetPodName(instance, workflowStepIndex, attempt)
 	logsPVCName := r.GetPVCLogsName(instance, pvcIndex)
 
+	// Retries of a failed test pod store their logs in a separate
+	// subdirectory of the logs PVC so that earlier attempts are kept.
+	logsSubPath := ""
+	if attempt > 0 {
+		logsSubPath = podName
+	}
+
 	containerImage, err := r.GetContainerImage(ctx, instance)
 	if err != nil {
 		return nil, err
@@ -117,6 +153,7 @@ func (r *AnsibleTestReconciler) buildAnsibleTestPod(
 		annotations,
 		podName,
 		logsPVCName,
+		logsSubPath,
 		mountCerts,
 		envVars,
 		workflowStepIndex,
@@ -131,6 +168,13 @@ func (r *AnsibleTestReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&corev1.Pod{}).
 		Owns(&corev1.Secret{}).
 		Owns(&corev1.ConfigMap{}).
+		Watches(
+			&coordinationv1.Lease{},
+			handler.EnqueueRequestsFromMapFunc(r.LockQueueRequests(func() client.ObjectList {
+				return &testv1beta1.AnsibleTestList{}
+			})),
+			builder.WithPredicates(predicate.NewPredicateFuncs(IsTestOperatorLease)),
+		).
 		Complete(r)
 }
 
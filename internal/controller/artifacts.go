@@ -0,0 +1,480 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/openstack-k8s-operators/lib-common/modules/common/condition"
+	"github.com/openstack-k8s-operators/lib-common/modules/common/util"
+	testv1beta1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
+	"github.com/openstack-k8s-operators/test-operator/internal/artifacts"
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// ReconcileArtifactsServer serves the logs PVCs of the finished instance with a
+// read-only HTTP file server when the ArtifactsServer is enabled. The server
+// pod and its service are created once the testing is finished and their URL
+// is stored in the status. They are deleted when the ArtifactsServer is
+// disabled, a new run of the instance starts or the ArtifactsServerTTLSeconds
+// expire. The server is deleted together with the instance because the
+// instance owns it.
+func (r *Reconciler) ReconcileArtifactsServer(
+	ctx context.Context,
+	instance TestResource,
+	testingFinished bool,
+) (ctrl.Result, error) {
+	Log := r.GetLogger(ctx)
+	status := instance.GetStatus()
+
+	if !instance.IsArtifactsServerEnabled() || !testingFinished {
+		if status.ArtifactsServerStartTime == nil && status.ArtifactsURL == "" {
+			return ctrl.Result{}, nil
+		}
+
+		status.ArtifactsURL = ""
+		status.ArtifactsServerStartTime = nil
+		return ctrl.Result{}, r.DeleteArtifactsServer(ctx, instance)
+	}
+
+	requeueAfter := time.Duration(0)
+	if ttl := instance.GetArtifactsServerTTL(); ttl > 0 && status.ArtifactsServerStartTime != nil {
+		requeueAfter = time.Until(status.ArtifactsServerStartTime.Add(time.Duration(ttl) * time.Second))
+		if requeueAfter <= 0 {
+			// Keep the start time so that the expired server is not created
+			// again
+			if status.ArtifactsURL != "" {
+				Log.Info(InfoArtifactsServerExpired)
+				status.ArtifactsURL = ""
+			}
+
+			return ctrl.Result{}, r.DeleteArtifactsServer(ctx, instance)
+		}
+	}
+
+	logsPVCNames, rwoPVCNames, err := r.GetArtifactsPVCNames(ctx, instance)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if len(logsPVCNames) == 0 {
+		if status.ArtifactsURL != "" || status.ArtifactsServerStartTime == nil {
+			Log.Info(InfoArtifactsServerNoLogs)
+		}
+
+		status.ArtifactsURL = ""
+		status.ArtifactsServerStartTime = nil
+		return ctrl.Result{}, r.DeleteArtifactsServer(ctx, instance)
+	}
+
+	serverName := instance.GetName() + artifacts.NameSuffix
+	labels := map[string]string{
+		artifactsServerLabel: instance.GetName(),
+		operatorNameLabel:    "test-operator",
+	}
+
+	service := &corev1.Service{}
+	objectKey := client.ObjectKey{Namespace: instance.GetNamespace(), Name: serverName}
+	if err := r.Client.Get(ctx, objectKey, service); k8s_errors.IsNotFound(err) {
+		service = artifacts.Service(serverName, instance.GetNamespace(), labels, labels)
+		if err := controllerutil.SetControllerReference(instance, service, r.GetScheme()); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if err := r.Client.Create(ctx, service); err != nil {
+			return ctrl.Result{}, err
+		}
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// The artifacts server pod is created again when the logs PVCs change
+	// (e.g. some of them were deleted because of the LogsRetention rules)
+	pvcHash := GetStringHash(strings.Join(logsPVCNames, ","), 10)
+	pod, err := r.GetPod(ctx, serverName, instance.GetNamespace())
+	if k8s_errors.IsNotFound(err) {
+		pod = nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if pod != nil && pod.DeletionTimestamp != nil {
+		return ctrl.Result{RequeueAfter: RequeueAfterCancelValue}, nil
+	}
+
+	if pod != nil && (pod.Annotations[artifacts.PVCHashAnnotation] != pvcHash ||
+		pod.Status.Phase == corev1.PodFailed || pod.Status.Phase == corev1.PodSucceeded) {
+		if err := client.IgnoreNotFound(r.Client.Delete(ctx, pod)); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{RequeueAfter: RequeueAfterCancelValue}, nil
+	}
+
+	if pod == nil {
+		containerImage, err := r.GetArtifactsImage(
+			ctx,
+			instance.GetNamespace(),
+			artifacts.ImageKey,
+			artifacts.RelatedImage,
+			artifacts.DefaultContainerImageURL,
+		)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		spec, err := SafetyCheck(reflect.ValueOf(instance), "Spec")
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		nodeSelector, _ := spec.FieldByName("NodeSelector").Interface().(map[string]string)
+		tolerations, _ := spec.FieldByName("Tolerations").Interface().([]corev1.Toleration)
+
+		// A single pod mounts all logs PVCs. The PVCs that can not be
+		// shared between nodes have to be reachable from the same node.
+		if len(logsPVCNames) > 1 && len(rwoPVCNames) > 0 {
+			message := fmt.Sprintf(InfoArtifactsServerRWOLogsPVCs, strings.Join(rwoPVCNames, ", "))
+			Log.Info(message)
+			if r.Recorder != nil {
+				r.Recorder.Event(instance, corev1.EventTypeWarning, eventReasonRWOLogsPVCs, message)
+			}
+		}
+
+		pod = artifacts.Pod(
+			serverName,
+			instance.GetNamespace(),
+			labels,
+			map[string]string{artifacts.PVCHashAnnotation: pvcHash},
+			containerImage,
+			logsPVCNames,
+			nodeSelector,
+			tolerations,
+		)
+		if err := controllerutil.SetControllerReference(instance, pod, r.GetScheme()); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if err := r.Client.Create(ctx, pod); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	artifactsURL := artifacts.URL(serverName, instance.GetNamespace())
+	if status.ArtifactsURL != artifactsURL {
+		Log.Info(fmt.Sprintf(InfoArtifactsServerStarted, artifactsURL))
+		status.ArtifactsURL = artifactsURL
+	}
+
+	if status.ArtifactsServerStartTime == nil {
+		now := metav1.Now()
+		status.ArtifactsServerStartTime = &now
+		if ttl := instance.GetArtifactsServerTTL(); ttl > 0 {
+			requeueAfter = time.Duration(ttl) * time.Second
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// GetArtifactsPVCNames returns the sorted names of the logs PVCs owned by the
+// instance that are not being deleted. The names of the PVCs that can not be
+// mounted by pods on different nodes (neither ReadWriteMany nor ReadOnlyMany)
+// are returned as well.
+func (r *Reconciler) GetArtifactsPVCNames(ctx context.Context, instance client.Object) ([]string, []string, error) {
+	pvcList := &corev1.PersistentVolumeClaimList{}
+	err := r.Client.List(
+		ctx,
+		pvcList,
+		client.InNamespace(instance.GetNamespace()),
+		client.MatchingLabels{instanceNameLabel: instance.GetName()},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	isInstanceRef := func(ref metav1.OwnerReference) bool {
+		return ref.UID == instance.GetUID()
+	}
+
+	logsPVCNames := []string{}
+	rwoPVCNames := []string{}
+	for _, logsPVC := range pvcList.Items {
+		if !logsPVC.DeletionTimestamp.IsZero() || !slices.ContainsFunc(logsPVC.OwnerReferences, isInstanceRef) {
+			continue
+		}
+
+		logsPVCNames = append(logsPVCNames, logsPVC.Name)
+		if IsReadWriteOncePVC(&logsPVC) {
+			rwoPVCNames = append(rwoPVCNames, logsPVC.Name)
+		}
+	}
+
+	slices.Sort(logsPVCNames)
+	slices.Sort(rwoPVCNames)
+	return logsPVCNames, rwoPVCNames, nil
+}
+
+// IsReadWriteOncePVC returns true when the PVC can be mounted only on a
+// single node
+func IsReadWriteOncePVC(pvc *corev1.PersistentVolumeClaim) bool {
+	return !slices.Contains(pvc.Spec.AccessModes, corev1.ReadWriteMany) &&
+		!slices.Contains(pvc.Spec.AccessModes, corev1.ReadOnlyMany)
+}
+
+// DeleteArtifactsServer deletes the artifacts server pod and service of the
+// instance
+func (r *Reconciler) DeleteArtifactsServer(ctx context.Context, instance client.Object) error {
+	objectMeta := metav1.ObjectMeta{
+		Name:      instance.GetName() + artifacts.NameSuffix,
+		Namespace: instance.GetNamespace(),
+	}
+
+	if err := r.Client.Delete(ctx, &corev1.Pod{ObjectMeta: objectMeta}); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+
+	return client.IgnoreNotFound(r.Client.Delete(ctx, &corev1.Service{ObjectMeta: objectMeta}))
+}
+
+// GetArtifactsImage returns the image of the artifacts server or uploader. The
+// image set under imageKey in the test-operator-config ConfigMap takes
+// precedence over the default image of the test-operator.
+func (r *Reconciler) GetArtifactsImage(
+	ctx context.Context,
+	namespace string,
+	imageKey string,
+	relatedImage string,
+	defaultImage string,
+) (string, error) {
+	cm := &corev1.ConfigMap{}
+	objectKey := client.ObjectKey{Namespace: namespace, Name: testOperatorConfigName}
+	if err := r.Client.Get(ctx, objectKey, cm); client.IgnoreNotFound(err) != nil {
+		return "", err
+	}
+
+	if image := cm.Data[imageKey]; image != "" {
+		return image, nil
+	}
+
+	return util.GetEnvVar(relatedImage, defaultImage), nil
+}
+
+// UploadStepArtifacts uploads the logs of each finished workflow step to the
+// bucket set in ArtifactsUpload. Only the directory of the logs PVC stored in
+// the artifacts.LogsDirAnnotation of the test pod is uploaded. The upload is
+// executed by a pod spawned on the node of the finished test pod when the logs
+// PVC is ReadWriteOnce. A failed upload is retried with a new pod up to
+// artifacts.UploaderMaxAttempts times. The phase of the upload and, once it
+// succeeded, the URL prefix of the uploaded objects are stored in the status
+// of the workflow step. The pods of the succeeded uploads are deleted. The
+// outcome of the uploads is reported in the ArtifactsUploaded condition.
+func (r *Reconciler) UploadStepArtifacts(
+	ctx context.Context,
+	instance TestResource,
+	conditions *condition.Conditions,
+	state *WorkflowState,
+) error {
+	Log := r.GetLogger(ctx)
+
+	upload := instance.GetArtifactsUpload()
+	if upload == nil {
+		return nil
+	}
+
+	labels := map[string]string{
+		artifactsUploadLabel: instance.GetName(),
+		operatorNameLabel:    "test-operator",
+	}
+
+	containerImage := ""
+	steps := instance.GetStatus().Steps
+	for idx := range steps {
+		step := &steps[idx]
+
+		// The steps of the earlier runs keep their upload status
+		pod, ok := state.Pods[step.Index]
+		if !ok || pod.Name != step.PodName || step.LogsPVC == "" || step.PodDeleted ||
+			(pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed) {
+			continue
+		}
+
+		if IsArtifactsUploadFinished(*step) {
+			continue
+		}
+
+		if step.ArtifactsUploadAttempts == 0 {
+			step.ArtifactsUploadAttempts = 1
+		}
+
+		objectKey := artifacts.ObjectKey(*upload, instance.GetNamespace(), instance.GetName(), pod.Name)
+		uploaderName := artifacts.UploaderName(pod.Name, step.ArtifactsUploadAttempts)
+		uploader, err := r.GetPod(ctx, uploaderName, instance.GetNamespace())
+		if k8s_errors.IsNotFound(err) {
+			if containerImage == "" {
+				containerImage, err = r.GetArtifactsImage(
+					ctx,
+					instance.GetNamespace(),
+					artifacts.UploaderImageKey,
+					artifacts.UploaderRelatedImage,
+					"",
+				)
+				if err != nil {
+					return err
+				}
+			}
+
+			if containerImage == "" {
+				Log.Info(fmt.Sprintf(testv1beta1.ArtifactsUploadedImageErrorMessage, artifacts.UploaderImageKey))
+				conditions.Set(condition.FalseCondition(
+					testv1beta1.ArtifactsUploadedCondition,
+					condition.ErrorReason,
+					condition.SeverityWarning,
+					testv1beta1.ArtifactsUploadedImageErrorMessage,
+					artifacts.UploaderImageKey))
+				return nil
+			}
+
+			// A ReadWriteOnce logs PVC can be mounted only on the node of
+			// the test pod
+			logsPVC := &corev1.PersistentVolumeClaim{}
+			pvcKey := client.ObjectKey{Namespace: instance.GetNamespace(), Name: step.LogsPVC}
+			if err := r.Client.Get(ctx, pvcKey, logsPVC); err != nil {
+				return err
+			}
+
+			nodeName := ""
+			if IsReadWriteOncePVC(logsPVC) {
+				nodeName = pod.Spec.NodeName
+			}
+
+			uploader = artifacts.UploaderPod(
+				uploaderName,
+				instance.GetNamespace(),
+				labels,
+				containerImage,
+				*upload,
+				objectKey,
+				step.LogsPVC,
+				pod.Annotations[artifacts.LogsDirAnnotation],
+				nodeName,
+				&pod,
+			)
+			if err := controllerutil.SetControllerReference(instance, uploader, r.GetScheme()); err != nil {
+				return err
+			}
+
+			if err := r.Client.Create(ctx, uploader); err != nil && !k8s_errors.IsAlreadyExists(err) {
+				return err
+			}
+
+			Log.Info(fmt.Sprintf(InfoUploadingArtifacts, pod.Name, artifacts.ObjectURL(*upload, objectKey)))
+			uploader.Status.Phase = corev1.PodPending
+		} else if err != nil {
+			return err
+		}
+
+		step.ArtifactsUploadPhase = uploader.Status.Phase
+		switch uploader.Status.Phase {
+		case corev1.PodSucceeded:
+			step.ArtifactsURL = artifacts.ObjectURL(*upload, objectKey)
+			if err := client.IgnoreNotFound(r.Client.Delete(ctx, uploader)); err != nil {
+				return err
+			}
+
+		case corev1.PodFailed:
+			// The failed pod is kept so that its logs can be inspected
+			if step.ArtifactsUploadAttempts < artifacts.UploaderMaxAttempts {
+				Log.Info(fmt.Sprintf(InfoRetryingArtifactsUpload, pod.Name, step.ArtifactsUploadAttempts+1))
+				step.ArtifactsUploadAttempts++
+				step.ArtifactsUploadPhase = corev1.PodPending
+			}
+		}
+	}
+
+	UpdateArtifactsUploadCondition(instance, conditions)
+	return nil
+}
+
+// IsArtifactsUploadFinished returns true when the logs of the workflow step
+// were uploaded or when all attempts to upload them failed
+func IsArtifactsUploadFinished(step testv1beta1.WorkflowStepStatus) bool {
+	return step.ArtifactsUploadPhase == corev1.PodSucceeded ||
+		(step.ArtifactsUploadPhase == corev1.PodFailed &&
+			step.ArtifactsUploadAttempts >= artifacts.UploaderMaxAttempts)
+}
+
+// IsArtifactsUploadInProgress returns true when the logs of a workflow step of
+// the current run are being uploaded
+func IsArtifactsUploadInProgress(instance TestResource) bool {
+	if instance.GetArtifactsUpload() == nil {
+		return false
+	}
+
+	status := instance.GetStatus()
+	for _, step := range status.Steps {
+		if step.RunIndex == status.RunIndex && step.ArtifactsUploadPhase != "" && !IsArtifactsUploadFinished(step) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// UpdateArtifactsUploadCondition sets the ArtifactsUploaded condition from the
+// upload status of the workflow steps of the current run
+func UpdateArtifactsUploadCondition(instance TestResource, conditions *condition.Conditions) {
+	status := instance.GetStatus()
+
+	failed := []string{}
+	for _, step := range status.Steps {
+		if step.RunIndex == status.RunIndex && step.ArtifactsUploadPhase == corev1.PodFailed &&
+			IsArtifactsUploadFinished(step) {
+			failed = append(failed, StringOrPlaceholder(step.Name, step.PodName))
+		}
+	}
+
+	switch {
+	case len(failed) > 0:
+		conditions.Set(condition.FalseCondition(
+			testv1beta1.ArtifactsUploadedCondition,
+			testv1beta1.ArtifactsUploadFailedReason,
+			condition.SeverityWarning,
+			testv1beta1.ArtifactsUploadedErrorMessage,
+			strings.Join(failed, ", ")))
+	case IsArtifactsUploadInProgress(instance):
+		conditions.Set(condition.FalseCondition(
+			testv1beta1.ArtifactsUploadedCondition,
+			condition.RequestedReason,
+			condition.SeverityInfo,
+			testv1beta1.ArtifactsUploadedRunningMessage))
+	case slices.ContainsFunc(status.Steps, func(step testv1beta1.WorkflowStepStatus) bool {
+		return step.RunIndex == status.RunIndex && step.ArtifactsUploadPhase == corev1.PodSucceeded
+	}):
+		conditions.MarkTrue(testv1beta1.ArtifactsUploadedCondition, testv1beta1.ArtifactsUploadedMessage)
+	}
+}
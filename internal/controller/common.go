@@ -1,11 +1,13 @@
 package controller
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -21,31 +23,55 @@ import (
 	nad "github.com/openstack-k8s-operators/lib-common/modules/common/networkattachment"
 	"github.com/openstack-k8s-operators/lib-common/modules/common/pvc"
 	"github.com/openstack-k8s-operators/lib-common/modules/common/util"
+	testv1beta1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
+	testutil "github.com/openstack-k8s-operators/test-operator/internal/util"
 	"gopkg.in/yaml.v3"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	k8sresource "k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 const (
-	podNameStepInfix           = "-s"
-	envVarsConfigMapInfix      = "-env-vars-s"
-	customDataConfigMapInfix   = "-custom-data-s"
-	workflowStepNameInvalid    = "no-name"
-	workflowStepLabel          = "workflowStep"
-	instanceNameLabel          = "instanceName"
-	operatorNameLabel          = "operator"
-	testOperatorLockName       = "test-operator-lock"
-	testOperatorLockOwnerField = "owner"
-	testOperatorBaseDir        = "/etc/test_operator/"
+	podNameStepInfix          = "-s"
+	podNameAttemptInfix       = "-retry-"
+	podNameRunInfix           = "-run-"
+	envVarsConfigMapInfix     = "-env-vars-s"
+	customDataConfigMapInfix  = "-custom-data-s"
+	workflowStepNameInvalid   = "no-name"
+	workflowStepLabel         = "workflowStep"
+	attemptLabel              = "attempt"
+	runIndexLabel             = "runIndex"
+	instanceNameLabel         = "instanceName"
+	operatorNameLabel         = "operator"
+	testOperatorLockName      = "test-operator-lock"
+	testOperatorLockPrefix    = "test-operator-lock-"
+	cloudLockGroupPrefix      = "cloud-"
+	eventReasonLockTakenOver  = "LockTakenOver"
+	eventReasonLockReleased   = "LockReleased"
+	eventReasonCancelled      = "Cancelled"
+	eventReasonRWOLogsPVCs    = "ReadWriteOnceLogsPVCs"
+	artifactsServerLabel      = "artifactsServer"
+	artifactsUploadLabel      = "artifactsUpload"
+	testOperatorBaseDir       = "/etc/test_operator/"
+	podReasonDeadlineExceeded = "DeadlineExceeded"
+	podReasonPendingTimeout   = "PendingTimeout"
+	defaultLogsVolumeSize     = "1Gi"
+	resultsLogsTailLines      = 50000
+	resultsLogsLimitBytes     = 32 * 1024 * 1024
+	maxRunHistory             = 10
 )
 
 const (
@@ -64,32 +90,121 @@ const (
 	InfoCreatingFirstPod = "Creating first test pod (workflow step %d)."
 	// InfoCreatingNextPod is the info message when creating subsequent test pods
 	InfoCreatingNextPod = "Creating next test pod (workflow step %d)."
+	// InfoRetryingPod is the info message when recreating a failed test pod
+	InfoRetryingPod = "Retrying failed test pod (workflow step %d, attempt %d)."
+	// InfoWaitingForRetry is the info message when waiting before a failed test pod is recreated
+	InfoWaitingForRetry = "Waiting before retrying failed test pod."
 	// InfoCanNotAcquireLock is the info message when lock acquisition fails
 	InfoCanNotAcquireLock = "Can not acquire %s lock."
+	// InfoQueuePosition is the info message when the instance waits in the lock queue
+	InfoQueuePosition = "Waiting for %s lock (queue position %d)."
+	// InfoLockTakenOver is the info message when a stale lock is taken over
+	InfoLockTakenOver = "Took over stale %s lock owned by %s %s: %s"
 	// InfoCanNotReleaseLock is the info message when lock release fails
 	InfoCanNotReleaseLock = "Can not release %s lock."
+	// InfoWorkflowStopped is the info message when the workflow failure policy stops the workflow
+	InfoWorkflowStopped = "Workflow stopped by the %s failure policy. Remaining workflow steps are skipped."
+	// InfoWorkflowTimedOut is the info message when the workflow timeout stops the workflow
+	InfoWorkflowTimedOut = "Workflow exceeded its timeout. Remaining workflow steps are skipped."
+	// InfoTerminatingPod is the info message when a test pod exceeded its timeout
+	InfoTerminatingPod = "Test pod %s exceeded its timeout. Terminating the pod."
+	// InfoStuckPod is the info message when a test pod can not leave the Pending phase
+	InfoStuckPod = "Test pod %s can not start: %s"
+	// InfoPendingTimeout is the info message when a test pod exceeded its pendingTimeout
+	InfoPendingTimeout = "Test pod %s exceeded its pendingTimeout. Deleting the pod."
+	// InfoCanNotCollectResults is the info message when the output of a test pod can not be read
+	InfoCanNotCollectResults = "Can not collect test results from pod %s."
+	// InfoStartingRun is the info message when a new run of a finished instance is requested
+	InfoStartingRun = "Starting run %d requested by the %s annotation."
+	// InfoTestingSuspended is the info message when the suspended instance does not spawn new test pods
+	InfoTestingSuspended = "Testing suspended. No new test pods are spawned."
+	// InfoWaitingOnSuspendedPod is the info message when the suspended instance waits for its test pods to finish
+	InfoWaitingOnSuspendedPod = "Testing suspended. Waiting on the running test pods to finish."
+	// InfoDeletingSuspendedPod is the info message when a test pod of the suspended instance is deleted
+	InfoDeletingSuspendedPod = "Testing suspended. Deleting test pod %s."
+	// InfoNoFailedSteps is the info message when a rerun of the failed steps is requested but no step failed
+	InfoNoFailedSteps = "No failed workflow steps to re-run (run ID %s)."
+	// InfoCancellingPod is the info message when a test pod of the deleted instance is terminated
+	InfoCancellingPod = "Testing cancelled. Terminating test pod %s."
+	// InfoWaitingOnCancelledPod is the info message when the deleted instance waits for its test pods to store their logs
+	InfoWaitingOnCancelledPod = "Testing cancelled. Waiting on the terminated test pods to store their logs."
+	// InfoRetainingLogsPVC is the info message when a logs PVC is kept after the instance is deleted
+	InfoRetainingLogsPVC = "Keeping logs PVC %s after the instance is deleted."
+	// InfoArtifactsServerStarted is the info message when the artifacts server of the finished instance is created
+	InfoArtifactsServerStarted = "Serving logs of the finished testing at %s."
+	// InfoArtifactsServerExpired is the info message when the artifacts server exceeded its TTL
+	InfoArtifactsServerExpired = "Artifacts server exceeded its TTL. Deleting the artifacts server."
+	// InfoArtifactsServerNoLogs is the info message when the instance has no logs PVC the artifacts server could serve
+	InfoArtifactsServerNoLogs = "No logs PVCs to serve. The artifacts server is not started."
+	// InfoArtifactsServerRWOLogsPVCs is the info message when the artifacts server mounts several logs PVCs that can be mounted only on a single node
+	InfoArtifactsServerRWOLogsPVCs = "The logs PVCs %s can not be mounted by pods on different nodes. " +
+		"The artifacts server stays Pending when they are bound to volumes on different nodes."
+	// InfoUploadingArtifacts is the info message when the logs of a finished test pod are uploaded to the bucket
+	InfoUploadingArtifacts = "Uploading logs of test pod %s to %s."
+	// InfoRetryingArtifactsUpload is the info message when the upload of the logs of a finished test pod failed and is retried
+	InfoRetryingArtifactsUpload = "Upload of logs of test pod %s failed. Starting attempt %d."
+	// InfoWaitingForArtifactsUpload is the info message when the finished testing waits for the logs upload
+	InfoWaitingForArtifactsUpload = "Waiting for the logs upload to finish."
 )
 
 const (
 	// RequeueAfterValue tells how much time should we wait before calling Reconcile
 	// loop again.
 	RequeueAfterValue = time.Second * 60
+
+	// RequeueAfterCancelValue tells how often the deleted instance checks
+	// whether its terminated test pods are gone.
+	RequeueAfterCancelValue = time.Second * 5
+
+	// LockHeartbeatInterval tells how often the holder of the test-operator-lock
+	// renews its lease.
+	LockHeartbeatInterval = time.Second * 30
+
+	// LockStaleTimeout is the duration of the test-operator-lock lease. The
+	// holder that does not renew the lease in time loses the lock.
+	LockStaleTimeout = time.Minute * 5
 )
 
+// configHashIgnoredFields lists the JSON keys of the spec fields that are not
+// part of the config hash. The fields that only decide when the test pods are
+// scheduled do not change the test pods.
+var configHashIgnoredFields = []string{
+	"priority",
+	"concurrencyGroup",
+	"lockScope",
+	"lockGroup",
+	"suspend",
+	"suspendPolicy",
+	"terminationGracePeriodSeconds",
+	"logsPVCRetentionPolicy",
+	"logsRetention",
+	"artifactsServer",
+	"artifactsServerTTLSeconds",
+	"artifactsUpload",
+}
+
+// configHashDefaultedFields maps the JSON keys of the spec fields added after
+// the config hash was introduced to their encoded default values. The fields
+// are left out of the config hash while they keep their default value so that
+// the test pods created by an older operator are not recreated on upgrade.
+var configHashDefaultedFields = map[string]string{
+	"backoffDelay":          "10",
+	"backoffMaxDelay":       "300",
+	"timeout":               "0",
+	"pendingTimeout":        "0",
+	"workflowFailurePolicy": `"Continue"`,
+	"workflowMaxFailures":   "1",
+	"workflowTimeout":       "0",
+}
+
 // Static error definitions for test operations
 var (
 	// ErrReceivedUnexpectedAction indicates that an unexpected action was received.
 	ErrReceivedUnexpectedAction = errors.New("unexpected action received")
 
-	// ErrFailedToDeleteLock indicates that the test-operator-lock could not be deleted.
-	ErrFailedToDeleteLock = errors.New("failed to delete test-operator-lock")
-
 	// ErrNetworkAttachmentsMismatch indicates that not all pods have interfaces with IPs as configured in NetworkAttachments.
 	ErrNetworkAttachmentsMismatch = errors.New("not all pods have interfaces with ips as configured in NetworkAttachments")
 
-	// ErrLockFieldMissing indicates that a required field is missing in the lock config map.
-	ErrLockFieldMissing = errors.New("field is missing in the config map")
-
 	// ErrFieldExpectedStruct indicates attempting to access a field on a non-struct type.
 	ErrFieldExpectedStruct = errors.New("field cannot be accessed: expected struct")
 
@@ -101,14 +216,22 @@ var (
 
 	// ErrMissingRequiredKey indicates that a required key is missing in a resource.
 	ErrMissingRequiredKey = errors.New("missing required key")
+
+	// ErrLockNamespaceNotSet indicates that the namespace for the locks shared across namespaces is not configured.
+	ErrLockNamespaceNotSet = errors.New("the namespace of the locks shared across namespaces is not set")
 )
 
 // Reconciler provides common functionality for all test framework reconcilers
 type Reconciler struct {
-	Client  client.Client
-	Kclient kubernetes.Interface
-	Log     logr.Logger
-	Scheme  *runtime.Scheme
+	Client   client.Client
+	Kclient  kubernetes.Interface
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// LockNamespace is the namespace that stores the locks shared by
+	// instances from different namespaces
+	LockNamespace string
 }
 
 // NextAction holds an action that should be performed by the Reconcile loop.
@@ -131,11 +254,24 @@ const (
 	// specified in the .Spec.Workflow section (if .Spec.Workflow is defined)
 	CreateNextPod
 
+	// RetryPod indicates that the Reconcile loop should recreate the test pod
+	// of a failed step because the step did not reach its BackoffLimit yet
+	RetryPod
+
+	// WaitForRetry indicates that all pods finished but a failed step will be
+	// retried once its backoff delay passes
+	WaitForRetry
+
 	// EndTesting indicates that all pods have already finished. The Reconcile
 	// loop should end the testing and release resources that are required to
 	// be release (e.g., global lock)
 	EndTesting
 
+	// StopWorkflow indicates that the workflow failure policy or the workflow
+	// timeout does not allow to continue with the next workflow step. The
+	// remaining steps are skipped and the Reconcile loop should end the testing.
+	StopWorkflow
+
 	// Failure indicates that an unexpected error was encountered
 	Failure
 )
@@ -186,7 +322,8 @@ func (r *Reconciler) CreatePod(
 func (r *Reconciler) NextAction(
 	ctx context.Context,
 	instance client.Object,
-	workflowLength int,
+	state *WorkflowState,
+	workflowOptions testv1beta1.WorkflowOptions,
 ) (NextAction, int, error) {
 	// Get the latest pod. The latest pod is pod with the highest value stored
 	// in workflowStep label
@@ -196,55 +333,52 @@ func (r *Reconciler) NextAction(
 		return Failure, workflowStepIdx, err
 	}
 
-	// If there is a pod associated with the current instance.
 	if lastPod != nil {
-		workflowStepIdx, err := strconv.Atoi(lastPod.Labels[workflowStepLabel])
+		workflowStepIdx, err = strconv.Atoi(lastPod.Labels[workflowStepLabel])
 		if err != nil {
 			return Failure, workflowStepIdx, err
 		}
+	}
 
-		switch lastPod.Status.Phase {
-		case corev1.PodPending:
-			// If the last pod is in Pending state -> CheckPending
-			return CheckPending, workflowStepIdx, nil
+	// Do not start any new step once the failure policy says that the
+	// workflow should be stopped or the workflow timeout expired
+	stopWorkflow := ShouldStopWorkflow(workflowOptions, state.FailedSteps()) ||
+		state.IsWorkflowTimedOut(workflowOptions)
+	nextStepIdx := state.NextStep()
 
-		case corev1.PodRunning:
-			// If the last pod is in Running state -> Wait
-			return Wait, workflowStepIdx, nil
+	switch {
+	case !stopWorkflow && nextStepIdx >= 0 && lastPod == nil:
+		// If there is not any pod associated with the instance -> CreateFirstPod
+		return CreateFirstPod, nextStepIdx, nil
 
-		case corev1.PodFailed, corev1.PodSucceeded:
-			// If the last pod is finished and it is NOT the last pod which was
-			// supposed to be created -> CreateNextPod
-			if !isLastPodIndex(workflowStepIdx, workflowLength) {
-				workflowStepIdx++
-				return CreateNextPod, workflowStepIdx, nil
-			}
+	case !stopWorkflow && nextStepIdx >= 0 && state.StepPhases[nextStepIdx] == testv1beta1.WorkflowStepRetrying:
+		// If a failed step should be retried and its backoff delay passed -> RetryPod
+		return RetryPod, nextStepIdx, nil
 
-			// Otherwise if the pod is finished and it IS the last pod -> EndTesting
-			return EndTesting, workflowStepIdx, nil
+	case !stopWorkflow && nextStepIdx >= 0:
+		// If there is a step whose dependencies are finished -> CreateNextPod
+		return CreateNextPod, nextStepIdx, nil
 
-		default:
-			// If the last pod is in Unknown state -> Wait
-			return Wait, workflowStepIdx, nil
-		}
-	}
+	case state.HasPodInPhase(corev1.PodPending):
+		// If any pod is in Pending state -> CheckPending
+		return CheckPending, workflowStepIdx, nil
 
-	// If there is not any pod associated with the instance -> createFirstPod
-	if lastPod == nil {
-		return CreateFirstPod, workflowStepIdx, nil
-	}
+	case state.HasActivePod():
+		// If any pod is in Running or Unknown state -> Wait
+		return Wait, workflowStepIdx, nil
 
-	return Failure, workflowStepIdx, nil
-}
+	case !stopWorkflow && state.HasStepInPhase(testv1beta1.WorkflowStepRetrying):
+		// If all pods finished and a failed step waits for its retry -> WaitForRetry
+		return WaitForRetry, workflowStepIdx, nil
+
+	case stopWorkflow && state.HasPendingSteps():
+		// If all pods finished and the failure policy stopped the workflow
+		// before all steps were executed -> StopWorkflow
+		return StopWorkflow, workflowStepIdx, nil
 
-// isLastPodIndex returns true when podIndex is the index of the last pod that
-// should be executed. Otherwise the return value is false.
-func isLastPodIndex(podIndex int, workflowLength int) bool {
-	switch workflowLength {
-	case 0:
-		return podIndex == workflowLength
 	default:
-		return podIndex == (workflowLength - 1)
+		// If all pods finished and there is no other step to execute -> EndTesting
+		return EndTesting, workflowStepIdx, nil
 	}
 }
 
@@ -267,8 +401,8 @@ func (r *Reconciler) GetLastPod(
 	maxPodWorkflowStep := 0
 
 	for _, pod := range podList.Items {
-		// Skip pods that are being deleted
-		if pod.DeletionTimestamp != nil {
+		// Skip pods that are being deleted or that belong to other runs
+		if pod.DeletionTimestamp != nil || !isCurrentRunPod(instance, &pod) {
 			continue
 		}
 
@@ -287,336 +421,2416 @@ func (r *Reconciler) GetLastPod(
 	return maxPod, nil
 }
 
-// GetContainerImage returns the container image to use for the given instance, either from the provided parameter or from configuration
-func (r *Reconciler) GetContainerImage(
+// GetInstancePods returns all pods associated with the current run of an
+// instance that are not being deleted
+func (r *Reconciler) GetInstancePods(
 	ctx context.Context,
-	instance interface{},
-) (string, error) {
-	v := reflect.ValueOf(instance)
+	instance client.Object,
+) ([]corev1.Pod, error) {
+	labels := map[string]string{instanceNameLabel: instance.GetName()}
+	namespaceListOpt := client.InNamespace(instance.GetNamespace())
+	labelsListOpt := client.MatchingLabels(labels)
+	podList := &corev1.PodList{}
+	err := r.Client.List(ctx, podList, namespaceListOpt, labelsListOpt)
+	if err != nil {
+		return nil, err
+	}
 
-	spec, err := SafetyCheck(v, "Spec")
+	pods := []corev1.Pod{}
+	for _, pod := range podList.Items {
+		if pod.DeletionTimestamp != nil || !isCurrentRunPod(instance, &pod) {
+			continue
+		}
+		pods = append(pods, pod)
+	}
+
+	return pods, nil
+}
+
+// ShouldStopWorkflow returns true when the number of failed workflow steps
+// reached the limit defined by the workflow failure policy
+func ShouldStopWorkflow(workflowOptions testv1beta1.WorkflowOptions, failedSteps int) bool {
+	switch workflowOptions.WorkflowFailurePolicy {
+	case testv1beta1.WorkflowFailurePolicyStopOnFirstFailure:
+		return failedSteps > 0
+	case testv1beta1.WorkflowFailurePolicyStopAfterN:
+		return failedSteps >= int(max(workflowOptions.WorkflowMaxFailures, 1))
+	default:
+		return false
+	}
+}
+
+// WorkflowState describes the state of the workflow steps of an instance
+type WorkflowState struct {
+	// StepPhases holds the phase of each workflow step
+	StepPhases []testv1beta1.WorkflowStepPhase
+
+	// PodPhases holds the phase of the latest test pod spawned for a workflow
+	// step
+	PodPhases map[int]corev1.PodPhase
+
+	// Pods holds the latest test pod spawned for a workflow step
+	Pods map[int]corev1.Pod
+
+	// StartedAt holds the creation time of the first test pod of the workflow
+	StartedAt time.Time
+
+	// Attempts holds the attempt number of the latest test pod spawned for
+	// a workflow step. The first test pod has attempt number 0.
+	Attempts map[int]int
+
+	// RetryAt holds the time when a failed workflow step can be retried
+	RetryAt map[int]time.Time
+
+	// DeletedSteps holds the status of the workflow steps whose latest test
+	// pod was deleted because of its timeout or pendingTimeout
+	DeletedSteps map[int]testv1beta1.WorkflowStepStatus
+
+	// TimeoutAt holds the time when the running test pod of a workflow step
+	// exceeds its timeout
+	TimeoutAt map[int]time.Time
+
+	// Dependencies holds indexes of the steps that have to finish before
+	// a workflow step can be executed
+	Dependencies [][]int
+}
+
+// GetWorkflowState returns the state of all workflow steps based on the test
+// pods spawned for the instance. Test pods deleted because of their timeout or
+// pendingTimeout are represented by the outcome recorded in the status of
+// their steps.
+// Steps that do not have a pod yet are Pending unless their runIf condition
+// can not be met anymore. In that case they are Skipped. Failed steps that did
+// not reach their BackoffLimit are Retrying.
+func (r *Reconciler) GetWorkflowState(
+	ctx context.Context,
+	instance client.Object,
+	workflowLength int,
+) (*WorkflowState, error) {
+	pods, err := r.GetInstancePods(ctx, instance)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	containerImage := GetStringField(spec, "ContainerImage")
-	if containerImage != "" {
-		return containerImage, nil
+	stepsCount := max(workflowLength, 1)
+
+	// The deleted pods come last so that they replace the pods with the same
+	// attempt number that are still present in the cache
+	deletedSteps := getDeletedPodSteps(instance, stepsCount)
+	for _, step := range deletedSteps {
+		pods = append(pods, getDeletedPod(instance, step))
 	}
 
-	namespace := GetStringField(v, "Namespace")
-	kind := GetStringField(v, "Kind")
+	state := &WorkflowState{
+		StepPhases:   make([]testv1beta1.WorkflowStepPhase, stepsCount),
+		PodPhases:    make(map[int]corev1.PodPhase, len(pods)),
+		Pods:         make(map[int]corev1.Pod, len(pods)),
+		Attempts:     make(map[int]int, len(pods)),
+		RetryAt:      make(map[int]time.Time),
+		TimeoutAt:    make(map[int]time.Time),
+		DeletedSteps: make(map[int]testv1beta1.WorkflowStepStatus, len(deletedSteps)),
+		Dependencies: GetWorkflowDependencies(instance, stepsCount),
+	}
 
-	cm := &corev1.ConfigMap{}
-	objectKey := client.ObjectKey{Namespace: namespace, Name: "test-operator-config"}
-	if err := r.Client.Get(ctx, objectKey, cm); err != nil {
-		return "", err
+	for _, pod := range pods {
+		// The pods kept from an earlier run do not count towards the
+		// workflow timeout of the current run
+		createdAt := pod.GetCreationTimestamp().Time
+		if getRunIndexLabel(&pod) == GetRunIndex(instance) && (state.StartedAt.IsZero() || createdAt.Before(state.StartedAt)) {
+			state.StartedAt = createdAt
+		}
+
+		workflowStep, err := strconv.Atoi(pod.Labels[workflowStepLabel])
+		if err != nil {
+			return nil, err
+		}
+
+		attempt, err := GetPodAttempt(pod)
+		if err != nil {
+			return nil, err
+		}
+
+		if latestAttempt, ok := state.Attempts[workflowStep]; ok && latestAttempt > attempt {
+			continue
+		}
+
+		state.Attempts[workflowStep] = attempt
+		state.PodPhases[workflowStep] = pod.Status.Phase
+		state.Pods[workflowStep] = pod
 	}
 
-	imageKey := strings.ToLower(kind) + "-image"
-	if cm.Data != nil {
-		if image, exists := cm.Data[imageKey]; exists && image != "" {
-			return image, nil
+	for _, step := range deletedSteps {
+		if pod, ok := state.Pods[step.Index]; ok && pod.Name == step.PodName {
+			state.DeletedSteps[step.Index] = step
+		}
+	}
+
+	for stepIdx := range state.StepPhases {
+		if pod, ok := state.Pods[stepIdx]; ok {
+			state.StepPhases[stepIdx] = getStepPhase(pod.Status.Phase)
+
+			// Timed out steps are not retried. Pods terminated because of their
+			// pendingTimeout are failed pods and follow the BackoffLimit.
+			if pod.Status.Phase == corev1.PodFailed && pod.Status.Reason == podReasonDeadlineExceeded &&
+				!isPendingTimedOut(&pod) {
+				state.StepPhases[stepIdx] = testv1beta1.WorkflowStepTimedOut
+				continue
+			}
+
+			attempt := state.Attempts[stepIdx]
+			if pod.Status.Phase == corev1.PodFailed && attempt < GetWorkflowStepBackoffLimit(instance, stepIdx) {
+				state.StepPhases[stepIdx] = testv1beta1.WorkflowStepRetrying
+				state.RetryAt[stepIdx] = getPodFinishTime(&pod).Add(GetBackoffDelay(instance, attempt+1))
+			}
+			continue
+		}
+
+		runIfMet, runIfDecided := evaluateRunIf(instance, stepIdx, state.StepPhases)
+		if runIfDecided && !runIfMet {
+			state.StepPhases[stepIdx] = testv1beta1.WorkflowStepSkipped
+			continue
+		}
+
+		state.StepPhases[stepIdx] = testv1beta1.WorkflowStepPending
+	}
+
+	return state, nil
+}
+
+// NextStep returns the index of the first step that was not started yet and
+// whose dependencies already finished or of the first failed step whose
+// backoff delay already passed. When there is no such step -1 is returned.
+func (s *WorkflowState) NextStep() int {
+	for stepIdx := range s.StepPhases {
+		if s.isStepReady(stepIdx) {
+			return stepIdx
+		}
+	}
+
+	return -1
+}
+
+// isStepReady returns true when the step was not started yet and all steps it
+// depends on reached their final phase or when the step should be retried and
+// its backoff delay passed
+func (s *WorkflowState) isStepReady(stepIdx int) bool {
+	if s.StepPhases[stepIdx] == testv1beta1.WorkflowStepRetrying {
+		return !time.Now().Before(s.RetryAt[stepIdx])
+	}
+
+	if _, ok := s.PodPhases[stepIdx]; ok || s.StepPhases[stepIdx] != testv1beta1.WorkflowStepPending {
+		return false
+	}
+
+	for _, dependencyIdx := range s.Dependencies[stepIdx] {
+		if !isFinalStepPhase(s.StepPhases[dependencyIdx]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FailedSteps returns the number of workflow steps that failed or timed out
+func (s *WorkflowState) FailedSteps() int {
+	failedSteps := 0
+	for _, stepPhase := range s.StepPhases {
+		if stepPhase == testv1beta1.WorkflowStepFailed || stepPhase == testv1beta1.WorkflowStepTimedOut {
+			failedSteps++
+		}
+	}
+
+	return failedSteps
+}
+
+// WorkflowDeadline returns the time when the workflow exceeds its timeout.
+// Zero time is returned when the workflow has no timeout or it did not start
+// yet.
+func (s *WorkflowState) WorkflowDeadline(workflowOptions testv1beta1.WorkflowOptions) time.Time {
+	if workflowOptions.WorkflowTimeout <= 0 || s.StartedAt.IsZero() {
+		return time.Time{}
+	}
+
+	return s.StartedAt.Add(time.Duration(workflowOptions.WorkflowTimeout) * time.Second)
+}
+
+// IsWorkflowTimedOut returns true when the workflow exceeded its timeout
+func (s *WorkflowState) IsWorkflowTimedOut(workflowOptions testv1beta1.WorkflowOptions) bool {
+	deadline := s.WorkflowDeadline(workflowOptions)
+	return !deadline.IsZero() && !time.Now().Before(deadline)
+}
+
+// HasPendingSteps returns true when there is a step that was not started yet
+// or a step that waits for its retry
+func (s *WorkflowState) HasPendingSteps() bool {
+	for stepIdx, stepPhase := range s.StepPhases {
+		if _, ok := s.PodPhases[stepIdx]; !ok && stepPhase == testv1beta1.WorkflowStepPending {
+			return true
+		}
+	}
+
+	return s.HasStepInPhase(testv1beta1.WorkflowStepRetrying)
+}
+
+// HasStepInPhase returns true when any workflow step is in the given phase
+func (s *WorkflowState) HasStepInPhase(stepPhase testv1beta1.WorkflowStepPhase) bool {
+	return slices.Contains(s.StepPhases, stepPhase)
+}
+
+// NextAttempt returns the attempt number of the next test pod spawned for
+// the workflow step with the given index
+func (s *WorkflowState) NextAttempt(stepIdx int) int {
+	if attempt, ok := s.Attempts[stepIdx]; ok {
+		return attempt + 1
+	}
+
+	return 0
+}
+
+// RequeueAfter returns how long the Reconcile loop should wait before it
+// checks the workflow again. The default RequeueAfterValue is shortened when
+// a failed step can be retried or a running test pod times out sooner.
+func (s *WorkflowState) RequeueAfter() time.Duration {
+	requeueAfter := RequeueAfterValue
+	for _, retryAt := range s.RetryAt {
+		requeueAfter = min(requeueAfter, max(time.Until(retryAt), time.Second))
+	}
+
+	for _, timeoutAt := range s.TimeoutAt {
+		requeueAfter = min(requeueAfter, max(time.Until(timeoutAt), time.Second))
+	}
+
+	return requeueAfter
+}
+
+// GetPodTimeout returns the number of seconds the next test pod of the given
+// workflow step is allowed to run. It is the timeout of the step shortened to
+// the time remaining until the workflow timeout. Zero means no timeout.
+func (s *WorkflowState) GetPodTimeout(
+	instance interface{},
+	stepIdx int,
+	workflowOptions testv1beta1.WorkflowOptions,
+) int64 {
+	timeout := GetWorkflowStepTimeout(instance, stepIdx)
+
+	if workflowOptions.WorkflowTimeout > 0 {
+		remaining := workflowOptions.WorkflowTimeout
+		if deadline := s.WorkflowDeadline(workflowOptions); !deadline.IsZero() {
+			remaining = max(int64(time.Until(deadline).Seconds()), 1)
+		}
+
+		if timeout <= 0 || remaining < timeout {
+			timeout = remaining
+		}
+	}
+
+	return timeout
+}
+
+// TerminateTimedOutPods terminates the running test pods that exceeded either
+// their own timeout or the workflow timeout. Pods that already started on
+// a node get their activeDeadlineSeconds shortened so that the kubelet
+// terminates them. Pods that did not start yet are deleted and their steps
+// are recorded as TimedOut in the status. The function returns true when any
+// pod was terminated.
+func (r *Reconciler) TerminateTimedOutPods(
+	ctx context.Context,
+	instance TestResource,
+	state *WorkflowState,
+	workflowOptions testv1beta1.WorkflowOptions,
+) (bool, error) {
+	Log := r.GetLogger(ctx)
+
+	terminated := false
+	workflowDeadline := state.WorkflowDeadline(workflowOptions)
+	for stepIdx, pod := range state.Pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+
+		deadline := getPodDeadline(&pod)
+		if !workflowDeadline.IsZero() && (deadline.IsZero() || workflowDeadline.Before(deadline)) {
+			deadline = workflowDeadline
+		}
+
+		if deadline.IsZero() {
+			continue
+		}
+
+		if time.Now().Before(deadline) {
+			state.TimeoutAt[stepIdx] = deadline
+			continue
+		}
+
+		if pod.Status.StartTime != nil {
+			// The kubelet terminates the pod once activeDeadlineSeconds expires
+			activeDeadline := max(int64(time.Since(pod.Status.StartTime.Time).Seconds()), 1)
+			if pod.Spec.ActiveDeadlineSeconds != nil && *pod.Spec.ActiveDeadlineSeconds <= activeDeadline {
+				continue
+			}
+
+			Log.Info(fmt.Sprintf(InfoTerminatingPod, pod.Name))
+			patch := client.MergeFrom(pod.DeepCopy())
+			pod.Spec.ActiveDeadlineSeconds = &activeDeadline
+			if err := r.Client.Patch(ctx, &pod, patch); err != nil && !k8s_errors.IsNotFound(err) {
+				return terminated, err
+			}
+
+			terminated = true
+			continue
+		}
+
+		Log.Info(fmt.Sprintf(InfoTerminatingPod, pod.Name))
+		if err := r.DeletePodGracefully(ctx, instance, &pod); err != nil {
+			return terminated, err
+		}
+
+		recordDeletedPod(
+			instance,
+			stepIdx,
+			state.Attempts[stepIdx],
+			&pod,
+			testv1beta1.WorkflowStepTimedOut,
+			podReasonDeadlineExceeded,
+			"Test pod did not start before its timeout expired.",
+		)
+		terminated = true
+	}
+
+	return terminated, nil
+}
+
+// recordDeletedPod stores the outcome of a test pod deleted because of its
+// timeout or pendingTimeout in the status of its workflow step. The status is
+// the only record of the pod once it is gone.
+func recordDeletedPod(
+	instance TestResource,
+	stepIdx int,
+	attempt int,
+	pod *corev1.Pod,
+	stepPhase testv1beta1.WorkflowStepPhase,
+	reason string,
+	message string,
+) {
+	stepName := GetWorkflowStepName(instance, stepIdx)
+	if stepName == "" {
+		stepName = instance.GetName()
+	}
+
+	creationTime := pod.GetCreationTimestamp()
+	finishTime := metav1.Now()
+	deletedStep := testv1beta1.WorkflowStepStatus{
+		Name:         stepName,
+		Index:        stepIdx,
+		Phase:        stepPhase,
+		Attempts:     attempt + 1,
+		DependsOn:    GetWorkflowStepDependsOn(instance, stepIdx),
+		PodName:      pod.Name,
+		CreationTime: &creationTime,
+		FinishTime:   &finishTime,
+		Reason:       reason,
+		Message:      message,
+		RunIndex:     getRunIndexLabel(pod),
+		PodDeleted:   true,
+	}
+
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Name == testutil.TestOperatorLogsVolumeName && volume.PersistentVolumeClaim != nil {
+			deletedStep.LogsPVC = volume.PersistentVolumeClaim.ClaimName
+		}
+	}
+
+	status := instance.GetStatus()
+	for idx, step := range status.Steps {
+		if step.Index == stepIdx && step.RunIndex == deletedStep.RunIndex {
+			status.Steps[idx] = deletedStep
+			return
+		}
+	}
+
+	status.Steps = append(status.Steps, deletedStep)
+}
+
+// getDeletedPodSteps returns the status of the workflow steps of the current
+// run whose latest test pod was deleted because of its timeout or
+// pendingTimeout
+func getDeletedPodSteps(instance client.Object, stepsCount int) []testv1beta1.WorkflowStepStatus {
+	testResource, ok := instance.(TestResource)
+	if !ok {
+		return nil
+	}
+
+	steps := []testv1beta1.WorkflowStepStatus{}
+	for _, step := range testResource.GetStatus().Steps {
+		if step.PodDeleted && step.Index < stepsCount && step.RunIndex == GetStepRunIndex(instance, step.Index) {
+			steps = append(steps, step)
+		}
+	}
+
+	return steps
+}
+
+// getDeletedPod returns the failed test pod described by the status of
+// a workflow step whose pod was deleted because of its timeout or
+// pendingTimeout
+func getDeletedPod(instance client.Object, step testv1beta1.WorkflowStepStatus) corev1.Pod {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      step.PodName,
+			Namespace: instance.GetNamespace(),
+			Labels: map[string]string{
+				instanceNameLabel: instance.GetName(),
+				workflowStepLabel: strconv.Itoa(step.Index),
+				attemptLabel:      strconv.Itoa(max(step.Attempts-1, 0)),
+				runIndexLabel:     strconv.Itoa(step.RunIndex),
+			},
+		},
+		Status: corev1.PodStatus{
+			Phase:   corev1.PodFailed,
+			Reason:  step.Reason,
+			Message: step.Message,
+		},
+	}
+
+	if step.CreationTime != nil {
+		pod.CreationTimestamp = *step.CreationTime
+	}
+
+	return pod
+}
+
+// getPodDeadline returns the time when the activeDeadlineSeconds of the pod
+// expires. Zero time is returned when the pod has no deadline.
+func getPodDeadline(pod *corev1.Pod) time.Time {
+	if pod.Spec.ActiveDeadlineSeconds == nil {
+		return time.Time{}
+	}
+
+	startTime := pod.GetCreationTimestamp().Time
+	if pod.Status.StartTime != nil {
+		startTime = pod.Status.StartTime.Time
+	}
+
+	return startTime.Add(time.Duration(*pod.Spec.ActiveDeadlineSeconds) * time.Second)
+}
+
+// HasPodInPhase returns true when any test pod is in the given phase
+func (s *WorkflowState) HasPodInPhase(podPhase corev1.PodPhase) bool {
+	for _, phase := range s.PodPhases {
+		if phase == podPhase {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasActivePod returns true when any test pod has not finished yet
+func (s *WorkflowState) HasActivePod() bool {
+	for _, phase := range s.PodPhases {
+		if phase != corev1.PodSucceeded && phase != corev1.PodFailed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isFinalStepPhase returns true when the step phase can not change anymore
+func isFinalStepPhase(stepPhase testv1beta1.WorkflowStepPhase) bool {
+	switch stepPhase {
+	case testv1beta1.WorkflowStepSucceeded,
+		testv1beta1.WorkflowStepFailed,
+		testv1beta1.WorkflowStepTimedOut,
+		testv1beta1.WorkflowStepSkipped:
+		return true
+	default:
+		return false
+	}
+}
+
+// GetWorkflowDependencies returns indexes of the steps each workflow step
+// depends on. When none of the steps uses dependsOn the workflow is executed
+// sequentially and each step depends on the previous one. Otherwise the steps
+// form a DAG and a step depends only on the steps listed in its dependsOn
+// field and on the step referenced by its runIf condition.
+func GetWorkflowDependencies(instance interface{}, stepsCount int) [][]int {
+	dependencies := make([][]int, stepsCount)
+
+	if !IsWorkflowDAG(instance) {
+		for stepIdx := 1; stepIdx < stepsCount; stepIdx++ {
+			dependencies[stepIdx] = []int{stepIdx - 1}
+		}
+		return dependencies
+	}
+
+	stepIndexes := make(map[string]int, stepsCount)
+	for stepIdx := 0; stepIdx < stepsCount; stepIdx++ {
+		stepIndexes[GetWorkflowStepName(instance, stepIdx)] = stepIdx
+	}
+
+	for stepIdx := 0; stepIdx < stepsCount; stepIdx++ {
+		stepNames := append([]string{}, GetWorkflowStepDependsOn(instance, stepIdx)...)
+		if runIf := GetWorkflowStepRunIf(instance, stepIdx); runIf != nil {
+			stepNames = append(stepNames, runIf.StepName)
+		}
+
+		for _, stepName := range stepNames {
+			if dependencyIdx, ok := stepIndexes[stepName]; ok && dependencyIdx != stepIdx {
+				dependencies[stepIdx] = append(dependencies[stepIdx], dependencyIdx)
+			}
+		}
+	}
+
+	return dependencies
+}
+
+// IsLogsVolumePerStep returns true when a workflow step of the instance sets
+// its own logs volume options. Each workflow step gets its own logs PVC then.
+func IsLogsVolumePerStep(instance interface{}) bool {
+	spec, err := SafetyCheck(reflect.ValueOf(instance), "Spec")
+	if err != nil {
+		return false
+	}
+
+	workflow, err := SafetyCheck(spec, "Workflow")
+	if err != nil {
+		return false
+	}
+
+	for i := 0; i < workflow.Len(); i++ {
+		logsVolume, err := SafetyCheck(workflow.Index(i), "LogsVolume")
+		if err == nil && !logsVolume.IsNil() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsWorkflowDAG returns true when at least one workflow step uses dependsOn
+func IsWorkflowDAG(instance interface{}) bool {
+	v := reflect.ValueOf(instance)
+
+	spec, err := SafetyCheck(v, "Spec")
+	if err != nil {
+		return false
+	}
+
+	workflow, err := SafetyCheck(spec, "Workflow")
+	if err != nil {
+		return false
+	}
+
+	for stepIdx := 0; stepIdx < workflow.Len(); stepIdx++ {
+		if len(GetWorkflowStepDependsOn(instance, stepIdx)) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// evaluateRunIf checks the runIf condition of the workflow step with the given
+// index against the phases of the earlier steps. The first return value tells
+// whether the condition is met, the second one whether the referenced step
+// already reached its final phase. A step without runIf is always runnable.
+func evaluateRunIf(
+	instance client.Object,
+	stepIdx int,
+	stepPhases []testv1beta1.WorkflowStepPhase,
+) (bool, bool) {
+	runIf := GetWorkflowStepRunIf(instance, stepIdx)
+	if runIf == nil {
+		return true, true
+	}
+
+	for i := 0; i < stepIdx; i++ {
+		if GetWorkflowStepName(instance, i) != runIf.StepName {
+			continue
+		}
+
+		switch stepPhases[i] {
+		case testv1beta1.WorkflowStepSucceeded:
+			return runIf.Outcome != testv1beta1.WorkflowStepOutcomeFailed, true
+		case testv1beta1.WorkflowStepFailed, testv1beta1.WorkflowStepTimedOut:
+			return runIf.Outcome != testv1beta1.WorkflowStepOutcomeSucceeded, true
+		case testv1beta1.WorkflowStepSkipped:
+			return false, true
+		default:
+			return false, false
+		}
+	}
+
+	// The referenced step does not exist or it is not an earlier step
+	return false, true
+}
+
+// UpdateStepsStatus refreshes the status of the workflow steps and their
+// summary based on the test pods spawned for the instance. Steps that were not
+// started are reported as Skipped and steps waiting for a retry are reported
+// as Failed when the workflow was stopped. The status of the steps from the
+// earlier runs is kept in front of the status of the steps executed again by
+// a run in the failed rerun mode.
+func UpdateStepsStatus(
+	instance client.Object,
+	status *testv1beta1.CommonTestStatus,
+	state *WorkflowState,
+	workflowStopped bool,
+) {
+	steps := make([]testv1beta1.WorkflowStepStatus, 0, len(state.StepPhases))
+	summary := &testv1beta1.WorkflowStepsSummary{Total: len(state.StepPhases)}
+	for stepIdx, stepPhase := range state.StepPhases {
+		stepName := GetWorkflowStepName(instance, stepIdx)
+		if stepName == "" {
+			stepName = instance.GetName()
+		}
+
+		stepRunIndex := GetStepRunIndex(instance, stepIdx)
+		for _, previousStep := range status.Steps {
+			if previousStep.Index == stepIdx && previousStep.RunIndex < stepRunIndex {
+				steps = append(steps, previousStep)
+			}
+		}
+
+		_, podExists := state.PodPhases[stepIdx]
+		if workflowStopped && !podExists && stepPhase == testv1beta1.WorkflowStepPending {
+			stepPhase = testv1beta1.WorkflowStepSkipped
+		}
+
+		if workflowStopped && stepPhase == testv1beta1.WorkflowStepRetrying {
+			stepPhase = testv1beta1.WorkflowStepFailed
+		}
+
+		switch stepPhase {
+		case testv1beta1.WorkflowStepSucceeded:
+			summary.Succeeded++
+		case testv1beta1.WorkflowStepFailed, testv1beta1.WorkflowStepTimedOut:
+			summary.Failed++
+		}
+
+		stepStatus := testv1beta1.WorkflowStepStatus{
+			Name:      stepName,
+			Index:     stepIdx,
+			Phase:     stepPhase,
+			Attempts:  state.NextAttempt(stepIdx),
+			DependsOn: GetWorkflowStepDependsOn(instance, stepIdx),
+			RunIndex:  stepRunIndex,
+		}
+
+		if pod, ok := state.Pods[stepIdx]; ok {
+			setStepPodStatus(&stepStatus, &pod)
+		}
+
+		if deletedStep, ok := state.DeletedSteps[stepIdx]; ok {
+			stepStatus.FinishTime = deletedStep.FinishTime
+			stepStatus.LogsPVC = deletedStep.LogsPVC
+			stepStatus.PodDeleted = true
+		}
+
+		// The output of the test pod is not parsed for test results again and
+		// the upload of its logs continues where it stopped
+		for _, previousStep := range status.Steps {
+			if previousStep.Index == stepIdx && previousStep.RunIndex == stepRunIndex &&
+				stepStatus.PodName != "" && previousStep.PodName == stepStatus.PodName {
+				stepStatus.ResultsCollected = previousStep.ResultsCollected
+				stepStatus.ArtifactsUploadPhase = previousStep.ArtifactsUploadPhase
+				stepStatus.ArtifactsUploadAttempts = previousStep.ArtifactsUploadAttempts
+				stepStatus.ArtifactsURL = previousStep.ArtifactsURL
+			}
+		}
+
+		steps = append(steps, stepStatus)
+	}
+
+	status.Steps = steps
+	status.StepsSummary = summary
+}
+
+// setStepPodStatus fills in the details about the test pod of a workflow step
+func setStepPodStatus(stepStatus *testv1beta1.WorkflowStepStatus, pod *corev1.Pod) {
+	creationTime := pod.GetCreationTimestamp()
+	stepStatus.PodName = pod.Name
+	stepStatus.CreationTime = &creationTime
+	stepStatus.StartTime = pod.Status.StartTime
+
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Name == testutil.TestOperatorLogsVolumeName && volume.PersistentVolumeClaim != nil {
+			stepStatus.LogsPVC = volume.PersistentVolumeClaim.ClaimName
+		}
+	}
+
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if terminated := containerStatus.State.Terminated; terminated != nil {
+			exitCode := terminated.ExitCode
+			finishTime := terminated.FinishedAt
+			stepStatus.ExitCode = &exitCode
+			stepStatus.FinishTime = &finishTime
+			stepStatus.Reason = terminated.Reason
+		}
+	}
+
+	// Reasons reported for the whole pod (e.g. DeadlineExceeded, Evicted)
+	// take precedence over the reason reported by the container
+	if pod.Status.Reason != "" {
+		stepStatus.Reason = pod.Status.Reason
+		stepStatus.Message = pod.Status.Message
+	}
+}
+
+// UpdateTestResults collects the test results of the finished workflow steps
+// from the output of their latest test pods. The output of each test pod is
+// parsed only once, even when it contains no results. The results collected
+// by earlier reconciles are kept. The results of the steps executed again by
+// a run in the failed rerun mode are added next to the results from the
+// earlier runs.
+func (r *Reconciler) UpdateTestResults(
+	ctx context.Context,
+	instance client.Object,
+	status *testv1beta1.CommonTestStatus,
+	state *WorkflowState,
+	parseTestResults func(logs string) *testv1beta1.TestResults,
+) {
+	Log := r.GetLogger(ctx)
+
+	collectedResults := make(map[string]testv1beta1.TestResults, len(status.Results))
+	for _, stepResults := range status.Results {
+		collectedResults[stepResults.PodName] = stepResults
+	}
+
+	results := []testv1beta1.TestResults{}
+	for stepIdx := range state.StepPhases {
+		for _, step := range status.Steps {
+			if step.Index != stepIdx || step.RunIndex >= GetStepRunIndex(instance, stepIdx) {
+				continue
+			}
+
+			if stepResults, ok := collectedResults[step.PodName]; ok {
+				results = append(results, stepResults)
+			}
+		}
+
+		// The pods deleted because of their timeout or pendingTimeout have no output
+		pod, ok := state.Pods[stepIdx]
+		if _, deleted := state.DeletedSteps[stepIdx]; !ok || deleted ||
+			(pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed) {
+			continue
+		}
+
+		if stepResults, ok := collectedResults[pod.Name]; ok {
+			results = append(results, stepResults)
+			continue
+		}
+
+		stepStatus := getStepStatus(status, stepIdx, GetStepRunIndex(instance, stepIdx))
+		if stepStatus == nil || stepStatus.PodName != pod.Name || stepStatus.ResultsCollected {
+			continue
+		}
+
+		logs, err := r.GetPodLogs(ctx, &pod)
+		if err != nil {
+			Log.Info(fmt.Sprintf(InfoCanNotCollectResults, pod.Name), "error", err.Error())
+			continue
+		}
+
+		stepStatus.ResultsCollected = true
+		stepResults := parseTestResults(logs)
+		if stepResults == nil {
+			continue
+		}
+
+		stepResults.StepName = GetWorkflowStepName(instance, stepIdx)
+		if stepResults.StepName == "" {
+			stepResults.StepName = instance.GetName()
+		}
+		stepResults.Index = stepIdx
+		stepResults.PodName = pod.Name
+		results = append(results, *stepResults)
+	}
+
+	status.Results = results
+}
+
+// GetPodLogs returns the end of the output of the test container of the given
+// pod. The summaries the test results are parsed from are printed at the end of
+// the output, the rest of a long output is not downloaded. The output is read
+// from the kubelet, so it is not available once the kubelet rotated the log of
+// the container or the pod was deleted.
+func (r *Reconciler) GetPodLogs(ctx context.Context, pod *corev1.Pod) (string, error) {
+	tailLines := int64(resultsLogsTailLines)
+	limitBytes := int64(resultsLogsLimitBytes)
+	logOptions := &corev1.PodLogOptions{
+		TailLines:  &tailLines,
+		LimitBytes: &limitBytes,
+	}
+
+	if len(pod.Spec.Containers) > 0 {
+		logOptions.Container = pod.Spec.Containers[0].Name
+	}
+
+	logs, err := r.Kclient.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, logOptions).DoRaw(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return string(logs), nil
+}
+
+// getStepStatus returns the status of the workflow step with the given index
+// executed by the given run. Nil is returned when the status does not exist.
+func getStepStatus(status *testv1beta1.CommonTestStatus, stepIdx int, runIndex int) *testv1beta1.WorkflowStepStatus {
+	for idx := range status.Steps {
+		if status.Steps[idx].Index == stepIdx && status.Steps[idx].RunIndex == runIndex {
+			return &status.Steps[idx]
+		}
+	}
+
+	return nil
+}
+
+// infrastructureWaitingReasons lists the reasons of a waiting test container
+// that indicate that the tests can not be started because of a problem with
+// the cluster
+var infrastructureWaitingReasons = []string{
+	"ErrImagePull",
+	"ImagePullBackOff",
+	"InvalidImageName",
+	"CreateContainerConfigError",
+	"CreateContainerError",
+}
+
+// infrastructureTerminatedReasons lists the reasons of a terminated test
+// container that indicate that the tests were interrupted by the cluster
+var infrastructureTerminatedReasons = []string{
+	"OOMKilled",
+	"ContainerCannotRun",
+	"StartError",
+}
+
+// GetPodInfrastructureFailure returns the reason why the pod can not execute
+// the tests because of a problem with the cluster (e.g. ImagePullBackOff,
+// OOMKilled, evicted or unschedulable pod). An empty string is returned when
+// no such problem is detected.
+func GetPodInfrastructureFailure(pod *corev1.Pod) string {
+	if isPendingTimedOut(pod) {
+		return podReasonPendingTimeout
+	}
+
+	if pod.Status.Reason == "Evicted" {
+		return pod.Status.Reason
+	}
+
+	for _, podCondition := range pod.Status.Conditions {
+		if podCondition.Type == corev1.PodScheduled &&
+			podCondition.Status == corev1.ConditionFalse &&
+			podCondition.Reason == corev1.PodReasonUnschedulable {
+			return podCondition.Reason
+		}
+	}
+
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		waiting := containerStatus.State.Waiting
+		if waiting != nil && slices.Contains(infrastructureWaitingReasons, waiting.Reason) {
+			return waiting.Reason
+		}
+
+		terminated := containerStatus.State.Terminated
+		if terminated != nil && slices.Contains(infrastructureTerminatedReasons, terminated.Reason) {
+			return terminated.Reason
+		}
+	}
+
+	return ""
+}
+
+// UpdateTestConditions sets the ExecutionCompleted and TestsPassed conditions
+// based on the state of the workflow steps. Steps whose test pods failed
+// because of a problem with the cluster are reported separately from steps
+// whose tests failed.
+func UpdateTestConditions(
+	instance client.Object,
+	conditions *condition.Conditions,
+	state *WorkflowState,
+	testingFinished bool,
+) {
+	testsFailed := []string{}
+	infrastructureFailed := []string{}
+	timedOut := []string{}
+	for stepIdx, stepPhase := range state.StepPhases {
+		pod, ok := state.Pods[stepIdx]
+		if !ok {
+			continue
+		}
+
+		stepName := GetWorkflowStepName(instance, stepIdx)
+		if stepName == "" {
+			stepName = instance.GetName()
+		}
+
+		if reason := GetPodInfrastructureFailure(&pod); reason != "" {
+			infrastructureFailed = append(infrastructureFailed, fmt.Sprintf("%s (%s)", stepName, reason))
+			continue
+		}
+
+		switch stepPhase {
+		case testv1beta1.WorkflowStepTimedOut:
+			timedOut = append(timedOut, stepName)
+		case testv1beta1.WorkflowStepFailed:
+			testsFailed = append(testsFailed, stepName)
+		}
+	}
+
+	switch {
+	case len(infrastructureFailed) > 0:
+		conditions.Set(condition.FalseCondition(
+			testv1beta1.ExecutionCompletedCondition,
+			testv1beta1.InfrastructureFailureReason,
+			condition.SeverityError,
+			testv1beta1.ExecutionCompletedInfrastructureErrorMessage,
+			strings.Join(infrastructureFailed, ", ")))
+	case len(timedOut) > 0:
+		conditions.Set(condition.FalseCondition(
+			testv1beta1.ExecutionCompletedCondition,
+			testv1beta1.TimedOutReason,
+			condition.SeverityError,
+			testv1beta1.ExecutionCompletedTimedOutMessage,
+			strings.Join(timedOut, ", ")))
+	case testingFinished:
+		conditions.MarkTrue(testv1beta1.ExecutionCompletedCondition, testv1beta1.ExecutionCompletedMessage)
+	case len(state.Pods) == 0:
+		conditions.MarkUnknown(testv1beta1.ExecutionCompletedCondition, condition.InitReason, testv1beta1.ExecutionCompletedInitMessage)
+	default:
+		conditions.Set(condition.FalseCondition(
+			testv1beta1.ExecutionCompletedCondition,
+			condition.RequestedReason,
+			condition.SeverityInfo,
+			testv1beta1.ExecutionCompletedRunningMessage))
+	}
+
+	switch {
+	case len(testsFailed) > 0:
+		conditions.Set(condition.FalseCondition(
+			testv1beta1.TestsPassedCondition,
+			testv1beta1.TestsFailedReason,
+			condition.SeverityError,
+			testv1beta1.TestsPassedErrorMessage,
+			strings.Join(testsFailed, ", ")))
+	case testingFinished && len(infrastructureFailed) > 0:
+		conditions.Set(condition.FalseCondition(
+			testv1beta1.TestsPassedCondition,
+			testv1beta1.InfrastructureFailureReason,
+			condition.SeverityWarning,
+			testv1beta1.TestsPassedUnavailableMessage))
+	case testingFinished && len(timedOut) > 0:
+		conditions.Set(condition.FalseCondition(
+			testv1beta1.TestsPassedCondition,
+			testv1beta1.TimedOutReason,
+			condition.SeverityWarning,
+			testv1beta1.TestsPassedUnavailableMessage))
+	case testingFinished:
+		conditions.MarkTrue(testv1beta1.TestsPassedCondition, testv1beta1.TestsPassedMessage)
+	default:
+		conditions.MarkUnknown(testv1beta1.TestsPassedCondition, condition.InitReason, testv1beta1.TestsPassedInitMessage)
+	}
+}
+
+// IsTestingFinished returns true when the instance finished its testing. The
+// ExecutionCompleted condition is not true when a test pod failed because of
+// an infrastructure problem or a timeout, so the phases of the workflow steps
+// are checked as well.
+func IsTestingFinished(instance TestResource) bool {
+	if instance.GetConditions().IsTrue(testv1beta1.ExecutionCompletedCondition) {
+		return true
+	}
+
+	steps := instance.GetStatus().Steps
+	if len(steps) == 0 {
+		return false
+	}
+
+	for _, step := range steps {
+		switch step.Phase {
+		case testv1beta1.WorkflowStepSucceeded,
+			testv1beta1.WorkflowStepFailed,
+			testv1beta1.WorkflowStepTimedOut,
+			testv1beta1.WorkflowStepSkipped:
+			continue
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// AllSubConditionIsTrueExcept returns true when all conditions except the
+// Ready condition and the given conditions are true. It is used to mark the
+// instance as Ready once the testing finished no matter whether the tests
+// passed or not.
+func AllSubConditionIsTrueExcept(conditions *condition.Conditions, ignored ...condition.Type) bool {
+	for _, c := range *conditions {
+		if c.Type == condition.ReadyCondition || slices.Contains(ignored, c.Type) {
+			continue
+		}
+
+		if c.Status != corev1.ConditionTrue {
+			return false
+		}
+	}
+
+	return true
+}
+
+// GetPodAttempt returns the attempt number stored in the attempt label of
+// a test pod. Pods without the label are the first attempt.
+func GetPodAttempt(pod corev1.Pod) (int, error) {
+	attempt, ok := pod.Labels[attemptLabel]
+	if !ok {
+		return 0, nil
+	}
+
+	return strconv.Atoi(attempt)
+}
+
+// getPodFinishTime returns the time when the last container of the pod
+// terminated. The creation time of the pod is used when no container
+// reported its termination.
+func getPodFinishTime(pod *corev1.Pod) time.Time {
+	finishTime := pod.GetCreationTimestamp().Time
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		terminated := containerStatus.State.Terminated
+		if terminated != nil && terminated.FinishedAt.After(finishTime) {
+			finishTime = terminated.FinishedAt.Time
+		}
+	}
+
+	return finishTime
+}
+
+// GetBackoffDelay returns how long the test-operator waits before it spawns
+// the given attempt of a failed test pod. The delay starts at BackoffDelay
+// and doubles with each attempt until it reaches BackoffMaxDelay.
+func GetBackoffDelay(instance interface{}, attempt int) time.Duration {
+	spec, err := SafetyCheck(reflect.ValueOf(instance), "Spec")
+	if err != nil {
+		return 0
+	}
+
+	backoffDelay, err := SafetyCheck(spec, "BackoffDelay")
+	if err != nil || backoffDelay.Kind() != reflect.Int32 {
+		return 0
+	}
+
+	backoffMaxDelay, err := SafetyCheck(spec, "BackoffMaxDelay")
+	if err != nil || backoffMaxDelay.Kind() != reflect.Int32 {
+		return 0
+	}
+
+	delay := time.Duration(backoffDelay.Int()) * time.Second
+	maxDelay := time.Duration(backoffMaxDelay.Int()) * time.Second
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxDelay)
+}
+
+// GetWorkflowStepBackoffLimit returns how many times the test pod of the
+// workflow step with the given index can be retried. The value defined in
+// the workflow step takes precedence over the value defined in the spec.
+func GetWorkflowStepBackoffLimit(instance interface{}, stepNum int) int {
+	backoffLimit, err := getWorkflowStepOption(instance, stepNum, "BackoffLimit")
+	if err != nil {
+		return 0
+	}
+
+	if limit, ok := backoffLimit.Interface().(*int32); ok && limit != nil {
+		return int(*limit)
+	}
+
+	return 0
+}
+
+// GetWorkflowStepTimeout returns the timeout in seconds of the test pod of the
+// workflow step with the given index. The value defined in the workflow step
+// takes precedence over the value defined in the spec.
+func GetWorkflowStepTimeout(instance interface{}, stepNum int) int64 {
+	return getWorkflowStepInt64Option(instance, stepNum, "Timeout")
+}
+
+// GetWorkflowStepPendingTimeout returns the number of seconds the test pod of
+// the workflow step with the given index is allowed to stay in the Pending
+// phase. The value defined in the workflow step takes precedence over the
+// value defined in the spec.
+func GetWorkflowStepPendingTimeout(instance interface{}, stepNum int) int64 {
+	return getWorkflowStepInt64Option(instance, stepNum, "PendingTimeout")
+}
+
+// getWorkflowStepInt64Option returns the value of an int64 (or *int64) option
+// of the workflow step with the given index. Zero is returned when the option
+// is not set.
+func getWorkflowStepInt64Option(instance interface{}, stepNum int, fieldName string) int64 {
+	option, err := getWorkflowStepOption(instance, stepNum, fieldName)
+	if err != nil {
+		return 0
+	}
+
+	if option.Kind() == reflect.Pointer {
+		if option.IsNil() {
+			return 0
+		}
+		option = option.Elem()
+	}
+
+	if option.Kind() != reflect.Int64 {
+		return 0
+	}
+
+	return option.Int()
+}
+
+// getWorkflowStepOption returns the value of the given field of the workflow
+// step with the given index. When the step does not set the field the value
+// from the spec is returned.
+func getWorkflowStepOption(instance interface{}, stepNum int, fieldName string) (reflect.Value, error) {
+	spec, err := SafetyCheck(reflect.ValueOf(instance), "Spec")
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	option, err := SafetyCheck(spec, fieldName)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	workflow, err := SafetyCheck(spec, "Workflow")
+	if err != nil || stepNum < 0 || stepNum >= workflow.Len() {
+		return option, nil
+	}
+
+	stepOption, err := SafetyCheck(workflow.Index(stepNum), fieldName)
+	if err == nil && !IsEmpty(stepOption) {
+		return stepOption, nil
+	}
+
+	return option, nil
+}
+
+// getStepPhase translates the phase of a test pod to the phase of a workflow step
+func getStepPhase(podPhase corev1.PodPhase) testv1beta1.WorkflowStepPhase {
+	switch podPhase {
+	case corev1.PodRunning:
+		return testv1beta1.WorkflowStepRunning
+	case corev1.PodSucceeded:
+		return testv1beta1.WorkflowStepSucceeded
+	case corev1.PodFailed:
+		return testv1beta1.WorkflowStepFailed
+	default:
+		return testv1beta1.WorkflowStepPending
+	}
+}
+
+// GetContainerImage returns the container image to use for the given instance, either from the provided parameter or from configuration
+func (r *Reconciler) GetContainerImage(
+	ctx context.Context,
+	instance interface{},
+) (string, error) {
+	v := reflect.ValueOf(instance)
+
+	spec, err := SafetyCheck(v, "Spec")
+	if err != nil {
+		return "", err
+	}
+
+	containerImage := GetStringField(spec, "ContainerImage")
+	if containerImage != "" {
+		return containerImage, nil
+	}
+
+	namespace := GetStringField(v, "Namespace")
+	kind := GetStringField(v, "Kind")
+
+	cm := &corev1.ConfigMap{}
+	objectKey := client.ObjectKey{Namespace: namespace, Name: testOperatorConfigName}
+	if err := r.Client.Get(ctx, objectKey, cm); err != nil {
+		return "", err
+	}
+
+	imageKey := strings.ToLower(kind) + "-image"
+	if cm.Data != nil {
+		if image, exists := cm.Data[imageKey]; exists && image != "" {
+			return image, nil
+		}
+	}
+
+	relatedImage := "RELATED_IMAGE_TEST_" + strings.ToUpper(kind) + "_IMAGE_URL_DEFAULT"
+	return util.GetEnvVar(relatedImage, ""), nil
+}
+
+// GetPodName returns the name of the pod for the given instance, workflow step
+// and attempt. Retries of a failed test pod get the attempt number appended.
+// Pods of the runs started by the rerun annotation get the run index appended.
+func (r *Reconciler) GetPodName(instance interface{}, stepNum int, attempt int) string {
+	name := GetStringField(reflect.ValueOf(instance), "Name")
+
+	stepName := GetWorkflowStepName(instance, stepNum)
+	if stepName != "" {
+		name += podNameStepInfix + fmt.Sprintf("%02d", stepNum) + "-" + stepName
+	}
+
+	return name + GetRunSuffix(GetRunIndex(instance)) + GetAttemptSuffix(attempt)
+}
+
+// GetAttemptSuffix returns the suffix that distinguishes retries of a failed
+// test pod. The first attempt does not have any suffix.
+func GetAttemptSuffix(attempt int) string {
+	if attempt == 0 {
+		return ""
+	}
+
+	return podNameAttemptInfix + strconv.Itoa(attempt)
+}
+
+// GetWorkflowStepName returns the name of the workflow step with the given
+// index. An empty string is returned when the instance has no workflow.
+func GetWorkflowStepName(instance interface{}, stepNum int) string {
+	v := reflect.ValueOf(instance)
+
+	spec, err := SafetyCheck(v, "Spec")
+	if err != nil {
+		return ""
+	}
+
+	workflow, err := SafetyCheck(spec, "Workflow")
+	if err != nil || workflow.Len() == 0 {
+		return ""
+	}
+
+	stepName := workflowStepNameInvalid
+	if stepNum >= 0 && stepNum < workflow.Len() {
+		stepName = GetStringField(workflow.Index(stepNum), "StepName")
+		if stepName == "" {
+			stepName = workflowStepNameInvalid
+		}
+	}
+
+	return stepName
+}
+
+// GetWorkflowStepRunIf returns the runIf condition of the workflow step with
+// the given index or nil when the step does not define any condition.
+func GetWorkflowStepRunIf(instance interface{}, stepNum int) *testv1beta1.WorkflowStepRunIf {
+	v := reflect.ValueOf(instance)
+
+	spec, err := SafetyCheck(v, "Spec")
+	if err != nil {
+		return nil
+	}
+
+	workflow, err := SafetyCheck(spec, "Workflow")
+	if err != nil || stepNum < 0 || stepNum >= workflow.Len() {
+		return nil
+	}
+
+	runIf, err := SafetyCheck(workflow.Index(stepNum), "RunIf")
+	if err != nil {
+		return nil
+	}
+
+	if stepRunIf, ok := runIf.Interface().(*testv1beta1.WorkflowStepRunIf); ok {
+		return stepRunIf
+	}
+
+	return nil
+}
+
+// GetWorkflowStepDependsOn returns names of the steps the workflow step with
+// the given index depends on
+func GetWorkflowStepDependsOn(instance interface{}, stepNum int) []string {
+	v := reflect.ValueOf(instance)
+
+	spec, err := SafetyCheck(v, "Spec")
+	if err != nil {
+		return nil
+	}
+
+	workflow, err := SafetyCheck(spec, "Workflow")
+	if err != nil || stepNum < 0 || stepNum >= workflow.Len() {
+		return nil
+	}
+
+	dependsOn, err := SafetyCheck(workflow.Index(stepNum), "DependsOn")
+	if err != nil {
+		return nil
+	}
+
+	if stepDependsOn, ok := dependsOn.Interface().([]string); ok {
+		return stepDependsOn
+	}
+
+	return nil
+}
+
+// GetPVCLogsName returns the name of the PVC for logs for the given instance and workflow step.
+// Each run started by the rerun annotation gets its own PVC.
+func (r *Reconciler) GetPVCLogsName(instance client.Object, pvcIndex int) string {
+	instanceName := instance.GetName()
+	instanceCreationTimestamp := instance.GetCreationTimestamp().Format(time.UnixDate)
+	suffixLength := 5
+	nameSuffix := GetStringHash(instanceName+instanceCreationTimestamp, suffixLength)
+	workflowStep := strconv.Itoa(pvcIndex)
+	return instanceName + "-" + workflowStep + "-" + nameSuffix + GetRunSuffix(GetRunIndex(instance))
+}
+
+// CheckSecretExists checks if a secret with the given name exists in the same namespace as the instance
+func (r *Reconciler) CheckSecretExists(ctx context.Context, instance client.Object, secretName string) bool {
+	secret := &corev1.Secret{}
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: instance.GetNamespace(), Name: secretName}, secret)
+	if err != nil && k8s_errors.IsNotFound(err) {
+		return false
+	}
+
+	return true
+}
+
+// ValidateSecretWithKeys validates that a Secret exists and contains required keys
+func (r *Reconciler) ValidateSecretWithKeys(
+	ctx context.Context,
+	instance client.Object,
+	secretName string,
+	requiredKeys []string,
+) error {
+	// When secret is not specified, skip it
+	if secretName == "" {
+		return nil
+	}
+
+	secret := &corev1.Secret{}
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: instance.GetNamespace(), Name: secretName}, secret)
+	if err != nil {
+		return err
+	}
+
+	// Validate required keys
+	for _, key := range requiredKeys {
+		if _, ok := secret.Data[key]; !ok {
+			return fmt.Errorf("%w '%s' in secret %s", ErrMissingRequiredKey, key, secretName)
+		}
+	}
+
+	return nil
+}
+
+// ValidateConfigMapWithKeys validates that a ConfigMap exists and contains required keys
+func (r *Reconciler) ValidateConfigMapWithKeys(
+	ctx context.Context,
+	instance client.Object,
+	configMapName string,
+	requiredKeys []string,
+) error {
+	// When config map is not specified, skip it
+	if configMapName == "" {
+		return nil
+	}
+
+	cm := &corev1.ConfigMap{}
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: instance.GetNamespace(), Name: configMapName}, cm)
+	if err != nil {
+		return err
+	}
+
+	// Validate required keys
+	for _, key := range requiredKeys {
+		if _, ok := cm.Data[key]; !ok {
+			return fmt.Errorf("%w '%s' in config map %s", ErrMissingRequiredKey, key, configMapName)
+		}
+	}
+
+	return nil
+}
+
+// ValidateOpenstackInputs validates OpenStack configuration inputs
+func (r *Reconciler) ValidateOpenstackInputs(
+	ctx context.Context,
+	instance client.Object,
+	openstackConfigMapName string,
+	openstackConfigSecretName string,
+) error {
+	err := r.ValidateConfigMapWithKeys(ctx, instance, openstackConfigMapName, []string{"clouds.yaml"})
+	if err != nil {
+		return err
+	}
+
+	err = r.ValidateSecretWithKeys(ctx, instance, openstackConfigSecretName, []string{"secure.yaml"})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetStringHash returns a hash of the given string with the specified length
+func GetStringHash(str string, hashLength int) string {
+	hash := sha256.New()
+	hash.Write([]byte(str))
+	byteSlice := hash.Sum(nil)
+	hashString := fmt.Sprintf("%x", byteSlice)
+
+	return hashString[:hashLength]
+}
+
+// EnsureLogsPVCExists ensures that a PVC for logs exists for the given instance and workflow step
+func (r *Reconciler) EnsureLogsPVCExists(
+	ctx context.Context,
+	instance client.Object,
+	helper *helper.Helper,
+	labels map[string]string,
+	StorageClassName string,
+	logsVolume testv1beta1.LogsVolume,
+	pvcIndex int,
+) (ctrl.Result, error) {
+	instanceNamespace := instance.GetNamespace()
+	pvcName := r.GetPVCLogsName(instance, pvcIndex)
+
+	pvvc := &corev1.PersistentVolumeClaim{}
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: instanceNamespace, Name: pvcName}, pvvc)
+	if err == nil {
+		return ctrl.Result{}, nil
+	}
+
+	testOperatorPvcDef := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pvcName,
+			Namespace: instanceNamespace,
+			Labels:    labels,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: logsVolume.AccessModes,
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: *logsVolume.Size,
+				},
+			},
+			StorageClassName: &StorageClassName,
+			VolumeMode:       logsVolume.VolumeMode,
+			Selector:         logsVolume.Selector,
+			DataSource:       logsVolume.DataSource,
+		},
+	}
+
+	timeDuration, _ := time.ParseDuration("2m")
+	testOperatorPvc := pvc.NewPvc(testOperatorPvcDef, timeDuration)
+	ctrlResult, err := testOperatorPvc.CreateOrPatch(ctx, helper)
+	if err != nil {
+		return ctrlResult, err
+	} else if (ctrlResult != ctrl.Result{}) {
+		return ctrlResult, nil
+	}
+
+	return ctrlResult, nil
+}
+
+// GetLogsVolume returns the options of the volume that stores the logs of
+// the test pods of the instance with the defaults filled in. The logs are
+// stored in a 1Gi ReadWriteOnce PVC by default.
+func GetLogsVolume(instance TestResource) testv1beta1.LogsVolume {
+	logsVolume := testv1beta1.LogsVolume{}
+	if instance.GetLogsVolume() != nil {
+		logsVolume = *instance.GetLogsVolume().DeepCopy()
+	}
+
+	if logsVolume.Type == "" {
+		logsVolume.Type = testv1beta1.LogsVolumeTypePersistentVolumeClaim
+	}
+
+	if logsVolume.Size == nil {
+		size := k8sresource.MustParse(defaultLogsVolumeSize)
+		logsVolume.Size = &size
+	}
+
+	if len(logsVolume.AccessModes) == 0 {
+		logsVolume.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+	}
+
+	return logsVolume
+}
+
+// SetEmptyDirLogsVolume replaces the logs PVC of the test pod with an emptyDir
+// volume limited to the size of the logs volume
+func SetEmptyDirLogsVolume(pod *corev1.Pod, logsVolume testv1beta1.LogsVolume) {
+	for idx := range pod.Spec.Volumes {
+		if pod.Spec.Volumes[idx].Name != testutil.TestOperatorLogsVolumeName {
+			continue
+		}
+
+		pod.Spec.Volumes[idx].VolumeSource = corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{
+				SizeLimit: logsVolume.Size,
+			},
+		}
+	}
+}
+
+// GetLogger returns the logger instance
+func (r *Reconciler) GetLogger(ctx context.Context) logr.Logger {
+	return log.FromContext(ctx)
+}
+
+// GetScheme returns the runtime scheme
+func (r *Reconciler) GetScheme() *runtime.Scheme {
+	return r.Scheme
+}
+
+// GetTestLock returns the lock the given instance waits for or holds. The
+// lock shared across namespaces is stored in the LockNamespace. Otherwise the
+// lock is stored in the namespace of the instance. The name of the shared lock
+// is read from the status of the instance where AcquireLock stored it, so the
+// instance keeps using the same lock when the inputs of the name change.
+func (r *Reconciler) GetTestLock(instance client.Object) (TestLock, error) {
+	lockName := GetSharedLockNameFromStatus(instance)
+	if lockName == "" {
+		return NewLeaseLock(r.Client, r.Scheme, instance.GetNamespace(), testOperatorLockName), nil
+	}
+
+	if r.LockNamespace == "" {
+		return nil, ErrLockNamespaceNotSet
+	}
+
+	return NewLeaseLock(r.Client, r.Scheme, r.LockNamespace, lockName), nil
+}
+
+// GetSharedLockName returns the name of the lock the instance shares with
+// instances from other namespaces. The name is derived from the lock group
+// of the instance or, when the lock group is not set, from the auth URL of
+// the cloud the instance tests. An empty string is returned when the
+// instance shares the lock only with the instances from its namespace.
+func (r *Reconciler) GetSharedLockName(ctx context.Context, instance client.Object) (string, error) {
+	testResource, ok := instance.(TestResource)
+	if !ok || testResource.GetLockScope() != testv1beta1.LockScopeCluster || testResource.GetConcurrencyGroup() != "" {
+		return "", nil
+	}
+
+	if lockGroup := testResource.GetLockGroup(); lockGroup != "" {
+		return testOperatorLockPrefix + lockGroup, nil
+	}
+
+	authURL, err := r.GetCloudAuthURL(ctx, instance.GetNamespace(), testResource.GetOpenStackConfigMap())
+	if err != nil {
+		return "", err
+	}
+
+	return testOperatorLockPrefix + cloudLockGroupPrefix + GetStringHash(authURL, 10), nil
+}
+
+// GetCloudAuthURL returns the auth URL of the default cloud from the
+// clouds.yaml stored in the given ConfigMap
+func (r *Reconciler) GetCloudAuthURL(ctx context.Context, namespace string, configMapName string) (string, error) {
+	cm := &corev1.ConfigMap{}
+	objectKey := client.ObjectKey{Namespace: namespace, Name: configMapName}
+	if err := r.Client.Get(ctx, objectKey, cm); err != nil {
+		return "", err
+	}
+
+	clouds := struct {
+		Clouds map[string]struct {
+			Auth struct {
+				AuthURL string `yaml:"auth_url"`
+			} `yaml:"auth"`
+		} `yaml:"clouds"`
+	}{}
+
+	if err := yaml.Unmarshal([]byte(cm.Data["clouds.yaml"]), &clouds); err != nil {
+		return "", err
+	}
+
+	authURL := strings.TrimSuffix(clouds.Clouds["default"].Auth.AuthURL, "/")
+	if authURL == "" {
+		return "", fmt.Errorf("%w 'clouds.default.auth.auth_url' in config map %s", ErrMissingRequiredKey, configMapName)
+	}
+
+	return authURL, nil
+}
+
+// AcquireLock attempts to acquire a lock for the given instance to prevent
+// concurrent operations. Instances waiting for the lock form a queue ordered
+// by their priority and creation time. When the lock is free only the first
+// instance in the queue can acquire it. The lock is created only when it
+// does not exist yet, so two instances can never both own it. The position
+// of the instance in the queue is stored in its status. Instances that belong
+// to a concurrency group acquire a slot of the group for the test pod with the
+// given name instead.
+func (r *Reconciler) AcquireLock(
+	ctx context.Context,
+	instance client.Object,
+	parallel bool,
+	podName string,
+) (bool, error) {
+	// The name of the lock is computed only when the instance does not hold
+	// a lock. The holder keeps its lock even when the lock group or the auth
+	// URL of the cloud changed in the meantime.
+	lockHeld, err := r.HoldsLock(ctx, instance, false, "")
+	if err != nil {
+		return false, err
+	}
+
+	if !lockHeld {
+		lockName, err := r.GetSharedLockName(ctx, instance)
+		if err != nil {
+			return false, err
+		}
+		setLockName(instance, lockName)
+	}
+
+	// The ConfigMap lock of the earlier releases is held until its holder
+	// migrates it to the Lease lock. The tests of the instances started
+	// before the upgrade do not run at the same time as the new ones.
+	group := GetConcurrencyGroup(instance)
+	if !parallel || group != "" {
+		legacyLock, err := r.GetLegacyLock(ctx, instance)
+		if err != nil {
+			return false, err
+		}
+
+		if legacyLock != nil {
+			if legacyLock.Data[legacyLockOwnerField] != string(instance.GetUID()) {
+				return false, nil
+			}
+
+			if group == "" {
+				return r.MigrateLegacyLock(ctx, instance, legacyLock)
+			}
+
+			if err := r.ReleaseLegacyLock(ctx, instance); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	// Instances that belong to a concurrency group share the slots of the
+	// group instead of the lock
+	if group != "" {
+		return r.AcquireSemaphore(ctx, instance, group, podName)
+	}
+
+	// Do not wait for the lock if the user wants the tests to be
+	// executed parallely
+	if parallel {
+		setQueuePosition(instance, 0)
+		return true, nil
+	}
+
+	lock, err := r.GetTestLock(instance)
+	if err != nil {
+		return false, err
+	}
+
+	record, err := lock.Get(ctx)
+	if err != nil && k8s_errors.IsNotFound(err) {
+		position, err := r.GetQueuePosition(ctx, instance, "")
+		if err != nil {
+			return false, err
+		}
+
+		if position > 1 {
+			setQueuePosition(instance, position)
+			return false, nil
+		}
+
+		// The creation fails when another instance created the lock in the
+		// meantime
+		if err := lock.Create(ctx, instance, r.newLockRecord(instance, nil)); err != nil {
+			if k8s_errors.IsAlreadyExists(err) {
+				setQueuePosition(instance, position)
+				return false, nil
+			}
+			return false, err
+		}
+
+		setQueuePosition(instance, 0)
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if record.IsHeldBy(instance) && !record.IsExpired() {
+		setQueuePosition(instance, 0)
+		return true, nil
+	}
+
+	// The instance whose lease expired lost the lock. It waits in the queue
+	// like any other instance.
+	lockHolder := record.HolderIdentity
+	if record.IsHeldBy(instance) {
+		lockHolder = ""
+	}
+
+	position, err := r.GetQueuePosition(ctx, instance, lockHolder)
+	if err != nil {
+		return false, err
+	}
+
+	// The first instance in the queue takes over the lock when its holder is
+	// gone or did not renew the lease in time
+	if position == 1 {
+		staleReason, err := r.GetStaleLockReason(ctx, record)
+		if err != nil {
+			return false, err
+		}
+
+		if staleReason != "" {
+			newRecord := r.newLockRecord(instance, record)
+			lockAcquired, err := r.takeOverLock(ctx, instance, lock, record, newRecord, staleReason)
+			if lockAcquired {
+				setQueuePosition(instance, 0)
+				return true, nil
+			}
+
+			if err != nil {
+				return false, err
+			}
+		}
+	}
+
+	setQueuePosition(instance, position)
+	return false, nil
+}
+
+// HoldsLock returns true when the given instance holds the test-operator
+// lock or a slot of its concurrency group for the test pod with the given
+// name. The instance does not hold the lock anymore when it did not renew the
+// lease in time.
+func (r *Reconciler) HoldsLock(
+	ctx context.Context,
+	instance client.Object,
+	parallel bool,
+	podName string,
+) (bool, error) {
+	if group := GetConcurrencyGroup(instance); group != "" {
+		return r.HoldsSemaphore(ctx, instance, group, podName)
+	}
+
+	if parallel {
+		return true, nil
+	}
+
+	lock, err := r.GetTestLock(instance)
+	if err != nil {
+		return false, err
+	}
+
+	record, err := lock.Get(ctx)
+	if err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+
+	return record.IsHeldBy(instance) && !record.IsExpired(), nil
+}
+
+// newLockRecord returns the record of the test-operator lock held by the
+// given instance. The record continues the given previous record of the lock.
+func (r *Reconciler) newLockRecord(instance client.Object, previous *LockRecord) LockRecord {
+	now := time.Now()
+	record := LockRecord{
+		HolderIdentity:  string(instance.GetUID()),
+		HolderKind:      r.GetKind(instance),
+		HolderName:      instance.GetName(),
+		HolderNamespace: instance.GetNamespace(),
+		LeaseDuration:   LockStaleTimeout,
+		AcquireTime:     now,
+		RenewTime:       now,
+	}
+
+	if previous != nil {
+		record.Transitions = previous.Transitions
+		if !previous.IsHeldBy(instance) {
+			record.Transitions++
+		}
+	}
+
+	return record
+}
+
+// GetStaleLockReason returns why the test-operator lock is stale. The lock is
+// stale when its holder does not exist anymore or when the holder did not
+// renew the lease in time. An empty string is returned when the lock is not
+// stale.
+func (r *Reconciler) GetStaleLockReason(ctx context.Context, record *LockRecord) (string, error) {
+	if record.HolderIdentity == "" {
+		return "the lock has no holder", nil
+	}
+
+	return r.getStaleOwnerReason(
+		ctx,
+		record.HolderNamespace,
+		record.HolderIdentity,
+		record.HolderKind,
+		record.HolderName,
+		record.RenewTime,
+		record.LeaseDuration,
+	)
+}
+
+// getStaleOwnerReason returns why the owner of a lock or of a semaphore slot
+// is stale. The owner is stale when it does not exist anymore or when it did
+// not renew its ownership for longer than the given lease duration. An empty
+// string is returned when the owner is not stale.
+func (r *Reconciler) getStaleOwnerReason(
+	ctx context.Context,
+	namespace string,
+	ownerUID string,
+	ownerKind string,
+	ownerName string,
+	renewTime time.Time,
+	leaseDuration time.Duration,
+) (string, error) {
+	if ownerKind != "" && ownerName != "" && namespace != "" {
+		obj, err := r.Scheme.New(testv1beta1.GroupVersion.WithKind(ownerKind))
+		if owner, ok := obj.(client.Object); err == nil && ok {
+			err := r.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ownerName}, owner)
+			if err != nil && !k8s_errors.IsNotFound(err) {
+				return "", err
+			}
+
+			if k8s_errors.IsNotFound(err) || string(owner.GetUID()) != ownerUID {
+				return fmt.Sprintf("%s %s does not exist anymore", ownerKind, ownerName), nil
+			}
+		}
+	}
+
+	if time.Since(renewTime) > leaseDuration {
+		return fmt.Sprintf("the lock was not renewed for more than %s", leaseDuration), nil
+	}
+
+	return "", nil
+}
+
+// takeOverLock transfers the stale test-operator lock or semaphore slot to the
+// given instance by replacing its record with the new one. The lock is
+// updated only when it did not change since it was read so only one instance
+// can take the lock over. The takeover is recorded in an event.
+func (r *Reconciler) takeOverLock(
+	ctx context.Context,
+	instance client.Object,
+	lock TestLock,
+	record *LockRecord,
+	newRecord LockRecord,
+	staleReason string,
+) (bool, error) {
+	Log := r.GetLogger(ctx)
+
+	previousKind := StringOrPlaceholder(record.HolderKind, "unknown")
+	previousName := StringOrPlaceholder(record.HolderName, record.HolderIdentity)
+
+	if err := lock.Update(ctx, instance, newRecord); err != nil {
+		if k8s_errors.IsConflict(err) || k8s_errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	Log.Info(fmt.Sprintf(InfoLockTakenOver, lock.Describe(), previousKind, previousName, staleReason))
+	if r.Recorder != nil {
+		r.Recorder.Eventf(
+			instance,
+			corev1.EventTypeWarning,
+			eventReasonLockTakenOver,
+			"Took over the stale %s lock owned by %s %s: %s.",
+			lock.Describe(),
+			previousKind,
+			previousName,
+			staleReason,
+		)
+	}
+
+	return true, nil
+}
+
+// RefreshLockHeartbeat renews the lease of the test-operator lock when the
+// lock is held by the given instance and the lease was renewed more than
+// LockHeartbeatInterval ago. An expired lease is not renewed as its holder
+// already lost the lock. The slots of a concurrency group held for the test
+// pods that finished are released.
+func (r *Reconciler) RefreshLockHeartbeat(
+	ctx context.Context,
+	instance client.Object,
+	state *WorkflowState,
+) error {
+	if group := GetConcurrencyGroup(instance); group != "" {
+		return r.RefreshSemaphore(ctx, instance, group, state)
+	}
+
+	lock, err := r.GetTestLock(instance)
+	if err != nil {
+		return err
+	}
+
+	record, err := lock.Get(ctx)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	if !record.IsHeldBy(instance) || record.IsExpired() {
+		return nil
+	}
+
+	if time.Since(record.RenewTime) < LockHeartbeatInterval {
+		return nil
+	}
+
+	record.RenewTime = time.Now()
+	record.LeaseDuration = LockStaleTimeout
+
+	// The update fails when the lock was taken over in the meantime
+	return client.IgnoreNotFound(ignoreConflict(lock.Update(ctx, instance, *record)))
+}
+
+// GetKind returns the kind of the given instance
+func (r *Reconciler) GetKind(instance client.Object) string {
+	if gvk, err := apiutil.GVKForObject(instance, r.Scheme); err == nil {
+		return gvk.Kind
+	}
+
+	return instance.GetObjectKind().GroupVersionKind().Kind
+}
+
+// GetLockQueue returns the instances that wait for the same lock as the given
+// instance ordered by their priority and creation time. These are the
+// instances from the namespace of the given instance that wait for the
+// test-operator lock or the instances from all namespaces that wait for the
+// same shared lock or for a slot of the same concurrency group. An
+// instance waits for the lock when its status contains a queue position. The
+// given instance is always part of the queue unless it is the owner of the
+// lock.
+func (r *Reconciler) GetLockQueue(
+	ctx context.Context,
+	instance client.Object,
+	lockOwner string,
+) ([]TestResource, error) {
+	lists := []client.ObjectList{
+		&testv1beta1.TempestList{},
+		&testv1beta1.TobikoList{},
+		&testv1beta1.AnsibleTestList{},
+		&testv1beta1.HorizonTestList{},
+	}
+
+	lockName := GetSharedLockNameFromStatus(instance)
+	listOpts := []client.ListOption{}
+	if lockName == "" && GetConcurrencyGroup(instance) == "" {
+		listOpts = append(listOpts, client.InNamespace(instance.GetNamespace()))
+	}
+
+	queue := []TestResource{}
+	for _, list := range lists {
+		if err := r.Client.List(ctx, list, listOpts...); err != nil {
+			return nil, err
+		}
+
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range items {
+			waiter, ok := item.(TestResource)
+			if !ok || string(waiter.GetUID()) == lockOwner || !waiter.GetDeletionTimestamp().IsZero() {
+				continue
+			}
+
+			if waiter.GetConcurrencyGroup() != GetConcurrencyGroup(instance) ||
+				waiter.GetStatus().LockName != lockName {
+				continue
+			}
+
+			if waiter.GetUID() == instance.GetUID() || waiter.GetStatus().QueuePosition > 0 {
+				queue = append(queue, waiter)
+			}
+		}
+	}
+
+	slices.SortStableFunc(queue, compareQueuedInstances)
+	return queue, nil
+}
+
+// GetQueuePosition returns the position of the instance in the queue of
+// instances waiting for the test-operator lock. The first position is one.
+func (r *Reconciler) GetQueuePosition(
+	ctx context.Context,
+	instance client.Object,
+	lockOwner string,
+) (int32, error) {
+	queue, err := r.GetLockQueue(ctx, instance, lockOwner)
+	if err != nil {
+		return 0, err
+	}
+
+	for idx, waiter := range queue {
+		if waiter.GetUID() == instance.GetUID() {
+			return int32(idx + 1), nil // #nosec G115
 		}
 	}
 
-	relatedImage := "RELATED_IMAGE_TEST_" + strings.ToUpper(kind) + "_IMAGE_URL_DEFAULT"
-	return util.GetEnvVar(relatedImage, ""), nil
+	return 1, nil
 }
 
-// GetPodName returns the name of the pod for the given instance and workflow step
-func (r *Reconciler) GetPodName(instance interface{}, stepNum int) string {
-	v := reflect.ValueOf(instance)
-
-	name := GetStringField(v, "Name")
-	spec, err := SafetyCheck(v, "Spec")
-	if err != nil {
-		return name
-	}
-
-	workflow, err := SafetyCheck(spec, "Workflow")
-	if err != nil || workflow.Len() == 0 {
-		return name
+// compareQueuedInstances orders the instances waiting for the lock. Instances
+// with a higher priority go first. Instances with the same priority are
+// ordered by their creation time.
+func compareQueuedInstances(a, b TestResource) int {
+	if a.GetPriority() != b.GetPriority() {
+		return int(b.GetPriority()) - int(a.GetPriority())
 	}
 
-	// Get workflow step name
-	stepName := workflowStepNameInvalid
-	if stepNum >= 0 && stepNum < workflow.Len() {
-		stepName = GetStringField(workflow.Index(stepNum), "StepName")
-		if stepName == "" {
-			stepName = workflowStepNameInvalid
-		}
+	if c := a.GetCreationTimestamp().Compare(b.GetCreationTimestamp().Time); c != 0 {
+		return c
 	}
 
-	return name + podNameStepInfix + fmt.Sprintf("%02d", stepNum) + "-" + stepName
+	return strings.Compare(string(a.GetUID()), string(b.GetUID()))
 }
 
-// GetPVCLogsName returns the name of the PVC for logs for the given instance and workflow step
-func (r *Reconciler) GetPVCLogsName(instance client.Object, pvcIndex int) string {
-	instanceName := instance.GetName()
-	instanceCreationTimestamp := instance.GetCreationTimestamp().Format(time.UnixDate)
-	suffixLength := 5
-	nameSuffix := GetStringHash(instanceName+instanceCreationTimestamp, suffixLength)
-	workflowStep := strconv.Itoa(pvcIndex)
-	return instanceName + "-" + workflowStep + "-" + nameSuffix
+// setLockName stores the name of the lock shared across namespaces in the
+// status of the instance
+func setLockName(instance client.Object, lockName string) {
+	if testResource, ok := instance.(TestResource); ok {
+		testResource.GetStatus().LockName = lockName
+	}
 }
 
-// CheckSecretExists checks if a secret with the given name exists in the same namespace as the instance
-func (r *Reconciler) CheckSecretExists(ctx context.Context, instance client.Object, secretName string) bool {
-	secret := &corev1.Secret{}
-	err := r.Client.Get(ctx, client.ObjectKey{Namespace: instance.GetNamespace(), Name: secretName}, secret)
-	if err != nil && k8s_errors.IsNotFound(err) {
-		return false
+// GetSharedLockNameFromStatus returns the name of the lock shared across
+// namespaces stored in the status of the instance
+func GetSharedLockNameFromStatus(instance client.Object) string {
+	if testResource, ok := instance.(TestResource); ok {
+		return testResource.GetStatus().LockName
 	}
 
-	return true
+	return ""
 }
 
-// ValidateSecretWithKeys validates that a Secret exists and contains required keys
-func (r *Reconciler) ValidateSecretWithKeys(
-	ctx context.Context,
-	instance client.Object,
-	secretName string,
-	requiredKeys []string,
-) error {
-	// When secret is not specified, skip it
-	if secretName == "" {
+// setQueuePosition stores the position of the instance in the lock queue in
+// its status
+func setQueuePosition(instance client.Object, position int32) {
+	if testResource, ok := instance.(TestResource); ok {
+		testResource.GetStatus().QueuePosition = position
+	}
+}
+
+// NotifyLockWaiters sends an event to all instances waiting for the lock
+// after the given instance released it
+func (r *Reconciler) NotifyLockWaiters(ctx context.Context, instance client.Object) error {
+	if r.Recorder == nil {
 		return nil
 	}
 
-	secret := &corev1.Secret{}
-	err := r.Client.Get(ctx, client.ObjectKey{Namespace: instance.GetNamespace(), Name: secretName}, secret)
+	queue, err := r.GetLockQueue(ctx, instance, string(instance.GetUID()))
 	if err != nil {
 		return err
 	}
 
-	// Validate required keys
-	for _, key := range requiredKeys {
-		if _, ok := secret.Data[key]; !ok {
-			return fmt.Errorf("%w '%s' in secret %s", ErrMissingRequiredKey, key, secretName)
-		}
+	kind := r.GetKind(instance)
+	for idx, waiter := range queue {
+		r.Recorder.Eventf(
+			waiter,
+			corev1.EventTypeNormal,
+			eventReasonLockReleased,
+			"The %s lock was released by %s %s. Queue position: %d.",
+			GetLockName(instance),
+			kind,
+			instance.GetName(),
+			idx+1,
+		)
 	}
 
 	return nil
 }
 
-// ValidateConfigMapWithKeys validates that a ConfigMap exists and contains required keys
-func (r *Reconciler) ValidateConfigMapWithKeys(
-	ctx context.Context,
-	instance client.Object,
-	configMapName string,
-	requiredKeys []string,
-) error {
-	// When config map is not specified, skip it
-	if configMapName == "" {
-		return nil
-	}
+// LockQueueRequests returns a function that maps a change of the
+// test-operator lock, of a lock shared across namespaces or of a slot of a
+// concurrency group to reconcile requests for the instances of the given kind
+// that wait for it. It lets the waiting instances react to the release of the
+// lock right away.
+func (r *Reconciler) LockQueueRequests(newList func() client.ObjectList) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		inLockNamespace := r.LockNamespace != "" && obj.GetNamespace() == r.LockNamespace
+		group, _, isSemaphore := parseSemaphoreSlotName(obj.GetName())
+		isSemaphore = isSemaphore && inLockNamespace
+		isSharedLock := inLockNamespace && strings.HasPrefix(obj.GetName(), testOperatorLockPrefix)
+		if !isSemaphore && !isSharedLock && obj.GetName() != testOperatorLockName {
+			return nil
+		}
 
-	cm := &corev1.ConfigMap{}
-	err := r.Client.Get(ctx, client.ObjectKey{Namespace: instance.GetNamespace(), Name: configMapName}, cm)
-	if err != nil {
-		return err
-	}
+		lockName := ""
+		listOpts := []client.ListOption{client.InNamespace(obj.GetNamespace())}
+		if isSharedLock {
+			lockName = obj.GetName()
+			listOpts = []client.ListOption{}
+		} else if isSemaphore {
+			listOpts = []client.ListOption{}
+		} else {
+			group = ""
+		}
 
-	// Validate required keys
-	for _, key := range requiredKeys {
-		if _, ok := cm.Data[key]; !ok {
-			return fmt.Errorf("%w '%s' in config map %s", ErrMissingRequiredKey, key, configMapName)
+		list := newList()
+		if err := r.Client.List(ctx, list, listOpts...); err != nil {
+			return nil
 		}
-	}
 
-	return nil
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil
+		}
+
+		requests := []reconcile.Request{}
+		for _, item := range items {
+			waiter, ok := item.(TestResource)
+			if !ok || waiter.GetStatus().QueuePosition == 0 || waiter.GetStatus().LockName != lockName ||
+				waiter.GetConcurrencyGroup() != group {
+				continue
+			}
+
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(waiter)})
+		}
+
+		return requests
+	}
 }
 
-// ValidateOpenstackInputs validates OpenStack configuration inputs
-func (r *Reconciler) ValidateOpenstackInputs(
-	ctx context.Context,
-	instance client.Object,
-	openstackConfigMapName string,
-	openstackConfigSecretName string,
-) error {
-	err := r.ValidateConfigMapWithKeys(ctx, instance, openstackConfigMapName, []string{"clouds.yaml"})
-	if err != nil {
-		return err
+// ReleaseLock releases the lock held by the given instance. The lock is
+// deleted only when it is still owned by the instance and did not change
+// since it was read. The function does not block. It returns false when the
+// lock changed in the meantime and the release has to be retried. An
+// instance that does not own the lock has nothing to release.
+func (r *Reconciler) ReleaseLock(ctx context.Context, instance client.Object) (bool, error) {
+	Log := r.GetLogger(ctx)
+
+	if err := r.ReleaseLegacyLock(ctx, instance); err != nil {
+		return false, err
 	}
 
-	err = r.ValidateSecretWithKeys(ctx, instance, openstackConfigSecretName, []string{"secure.yaml"})
+	if group := GetConcurrencyGroup(instance); group != "" {
+		return r.ReleaseSemaphore(ctx, instance, group)
+	}
+
+	lock, err := r.GetTestLock(instance)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	return nil
-}
+	record, err := lock.Get(ctx)
+	if err != nil && k8s_errors.IsNotFound(err) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
 
-// GetStringHash returns a hash of the given string with the specified length
-func GetStringHash(str string, hashLength int) string {
-	hash := sha256.New()
-	hash.Write([]byte(str))
-	byteSlice := hash.Sum(nil)
-	hashString := fmt.Sprintf("%x", byteSlice)
+	// Lock can be only released by the instance that holds it
+	if !record.IsHeldBy(instance) {
+		return true, nil
+	}
 
-	return hashString[:hashLength]
+	err = lock.Delete(ctx)
+	if k8s_errors.IsConflict(err) {
+		return false, nil
+	} else if err != nil && !k8s_errors.IsNotFound(err) {
+		return false, err
+	}
+
+	if err := r.NotifyLockWaiters(ctx, instance); err != nil {
+		Log.Error(err, "Can not notify instances waiting for the lock")
+	}
+
+	return true, nil
 }
 
-// EnsureLogsPVCExists ensures that a PVC for logs exists for the given instance and workflow step
-func (r *Reconciler) EnsureLogsPVCExists(
+// SuspendTesting keeps the suspended instance from spawning new test pods. The
+// running test pods are deleted when the SuspendPolicy is Terminate, otherwise
+// they are allowed to finish. The lock is released once no test pod of the
+// instance is active.
+func (r *Reconciler) SuspendTesting(
 	ctx context.Context,
-	instance client.Object,
-	helper *helper.Helper,
-	labels map[string]string,
-	StorageClassName string,
-	pvcIndex int,
+	instance TestResource,
+	state *WorkflowState,
 ) (ctrl.Result, error) {
-	instanceNamespace := instance.GetNamespace()
-	pvcName := r.GetPVCLogsName(instance, pvcIndex)
+	Log := r.GetLogger(ctx)
 
-	pvvc := &corev1.PersistentVolumeClaim{}
-	err := r.Client.Get(ctx, client.ObjectKey{Namespace: instanceNamespace, Name: pvcName}, pvvc)
-	if err == nil {
-		return ctrl.Result{}, nil
-	}
+	instance.GetConditions().Set(condition.FalseCondition(
+		testv1beta1.ExecutionCompletedCondition,
+		testv1beta1.SuspendedReason,
+		condition.SeverityInfo,
+		testv1beta1.ExecutionCompletedSuspendedMessage))
 
-	pvcAccessMode := []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+	// The suspended instance does not wait for the lock
+	setQueuePosition(instance, 0)
 
-	testOperatorPvcDef := &corev1.PersistentVolumeClaim{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      pvcName,
-			Namespace: instanceNamespace,
-			Labels:    labels,
-		},
-		Spec: corev1.PersistentVolumeClaimSpec{
-			AccessModes: pvcAccessMode,
-			Resources: corev1.VolumeResourceRequirements{
-				Requests: corev1.ResourceList{
-					corev1.ResourceStorage: k8sresource.MustParse("1Gi"),
-				},
-			},
-			StorageClassName: &StorageClassName,
-		},
+	if instance.GetSuspendPolicy() == testv1beta1.SuspendPolicyTerminate {
+		for _, pod := range state.Pods {
+			if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+				continue
+			}
+
+			Log.Info(fmt.Sprintf(InfoDeletingSuspendedPod, pod.Name))
+			if err := r.DeletePodGracefully(ctx, instance, &pod); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	} else if state.HasActivePod() {
+		Log.Info(InfoWaitingOnSuspendedPod)
+		return ctrl.Result{RequeueAfter: state.RequeueAfter()}, nil
 	}
 
-	timeDuration, _ := time.ParseDuration("2m")
-	testOperatorPvc := pvc.NewPvc(testOperatorPvcDef, timeDuration)
-	ctrlResult, err := testOperatorPvc.CreateOrPatch(ctx, helper)
-	if err != nil {
-		return ctrlResult, err
-	} else if (ctrlResult != ctrl.Result{}) {
-		return ctrlResult, nil
+	if lockReleased, err := r.ReleaseLock(ctx, instance); !lockReleased {
+		Log.Info(fmt.Sprintf(InfoCanNotReleaseLock, GetLockName(instance)))
+		return ctrl.Result{Requeue: true}, err
 	}
 
-	return ctrlResult, nil
+	Log.Info(InfoTestingSuspended)
+	return ctrl.Result{}, nil
 }
 
-// GetLogger returns the logger instance
-func (r *Reconciler) GetLogger(ctx context.Context) logr.Logger {
-	return log.FromContext(ctx)
+// DeletePodGracefully deletes the test pod. The test pod receives SIGTERM and
+// gets the termination grace period of the instance to store its logs in the
+// logs PVC before it is killed.
+func (r *Reconciler) DeletePodGracefully(
+	ctx context.Context,
+	instance TestResource,
+	pod *corev1.Pod,
+) error {
+	gracePeriod := client.GracePeriodSeconds(instance.GetTerminationGracePeriod())
+	return client.IgnoreNotFound(r.Client.Delete(ctx, pod, gracePeriod))
 }
 
-// GetScheme returns the runtime scheme
-func (r *Reconciler) GetScheme() *runtime.Scheme {
-	return r.Scheme
-}
+// CancelTesting stops the testing of the deleted instance before its finalizer
+// is removed. The ExecutionCompleted condition of an unfinished instance is set
+// to Cancelled first. The running test pods are then deleted gracefully and the
+// instance waits until they are gone or their grace period expired. Finally,
+// the lock is released and, when the LogsPVCRetentionPolicy is Retain, the logs
+// PVCs are released from the instance. The lock is looked up by the name stored
+// in the status, so the release does not depend on the clouds.yaml ConfigMap
+// that may be gone already (e.g. when the namespace is deleted). An empty
+// result means that the finalizer can be removed.
+func (r *Reconciler) CancelTesting(ctx context.Context, instance TestResource) (ctrl.Result, error) {
+	Log := r.GetLogger(ctx)
 
-// GetLockInfo retrieves the lock information ConfigMap for the given instance
-func (r *Reconciler) GetLockInfo(ctx context.Context, instance client.Object) (*corev1.ConfigMap, error) {
-	cm := &corev1.ConfigMap{}
-	objectKey := client.ObjectKey{Namespace: instance.GetNamespace(), Name: testOperatorLockName}
-	err := r.Client.Get(ctx, objectKey, cm)
-	if err != nil {
-		return cm, err
+	conditions := instance.GetConditions()
+	executionCompleted := conditions.Get(testv1beta1.ExecutionCompletedCondition)
+	if !IsTestingFinished(instance) && executionCompleted != nil &&
+		executionCompleted.Reason != testv1beta1.CancelledReason {
+		conditions.Set(condition.FalseCondition(
+			testv1beta1.ExecutionCompletedCondition,
+			testv1beta1.CancelledReason,
+			condition.SeverityWarning,
+			testv1beta1.ExecutionCompletedCancelledMessage))
+
+		if r.Recorder != nil {
+			r.Recorder.Event(
+				instance,
+				corev1.EventTypeWarning,
+				eventReasonCancelled,
+				testv1beta1.ExecutionCompletedCancelledMessage,
+			)
+		}
+
+		// Store the Cancelled outcome before the test pods are terminated
+		return ctrl.Result{Requeue: true}, nil
 	}
 
-	if _, ok := cm.Data[testOperatorLockOwnerField]; !ok {
-		return cm, fmt.Errorf("%w: %s field is missing in the %s config map", ErrLockFieldMissing, testOperatorLockOwnerField, testOperatorLockName)
+	podList := &corev1.PodList{}
+	err := r.Client.List(
+		ctx,
+		podList,
+		client.InNamespace(instance.GetNamespace()),
+		client.MatchingLabels{instanceNameLabel: instance.GetName()},
+	)
+	if err != nil {
+		return ctrl.Result{}, err
 	}
 
-	return cm, err
-}
+	requeueAfter := time.Duration(0)
+	for _, pod := range podList.Items {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
 
-// AcquireLock attempts to acquire a lock for the given instance to prevent concurrent operations
-func (r *Reconciler) AcquireLock(
-	ctx context.Context,
-	instance client.Object,
-	h *helper.Helper,
-	parallel bool,
-) (bool, error) {
-	// Do not wait for the lock if the user wants the tests to be
-	// executed parallely
-	if parallel {
-		return true, nil
-	}
+		if pod.DeletionTimestamp == nil {
+			Log.Info(fmt.Sprintf(InfoCancellingPod, pod.Name))
+			if err := r.DeletePodGracefully(ctx, instance, &pod); err != nil {
+				return ctrl.Result{}, err
+			}
 
-	instanceGUID := string(instance.GetUID())
-	cm, err := r.GetLockInfo(ctx, instance)
-	if err != nil && k8s_errors.IsNotFound(err) {
-		cm := map[string]string{
-			testOperatorLockOwnerField: instanceGUID,
+			requeueAfter = RequeueAfterCancelValue
+			continue
 		}
 
-		cms := []util.Template{
-			{
-				Name:       testOperatorLockName,
-				Namespace:  instance.GetNamespace(),
-				Type:       util.TemplateTypeNone,
-				CustomData: cm,
-			},
+		// Do not block the deletion of the instance when the pod was not
+		// killed once its grace period expired (e.g. the node is down)
+		if remaining := time.Until(pod.DeletionTimestamp.Time); remaining > 0 &&
+			(requeueAfter == 0 || remaining < requeueAfter) {
+			requeueAfter = remaining
 		}
+	}
 
-		err = configmap.EnsureConfigMaps(ctx, h, instance, cms, nil)
-		return err == nil, err
+	if requeueAfter > 0 {
+		Log.Info(InfoWaitingOnCancelledPod)
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
 	}
 
-	if cm.Data[testOperatorLockOwnerField] == instanceGUID {
-		return true, nil
+	if lockReleased, err := r.ReleaseLock(ctx, instance); !lockReleased {
+		Log.Info(fmt.Sprintf(InfoCanNotReleaseLock, GetLockName(instance)))
+		return ctrl.Result{Requeue: true}, err
+	}
+
+	if instance.GetLogsPVCRetentionPolicy() == testv1beta1.LogsPVCRetentionPolicyRetain {
+		if err := r.RetainLogsPVCs(ctx, instance); err != nil {
+			return ctrl.Result{}, err
+		}
 	}
 
-	return false, err
+	return ctrl.Result{}, nil
 }
 
-// ReleaseLock releases the lock for the given instance
-func (r *Reconciler) ReleaseLock(ctx context.Context, instance client.Object) (bool, error) {
+// RetainLogsPVCs removes the owner reference of the instance from its logs
+// PVCs so that they are not deleted together with the instance
+func (r *Reconciler) RetainLogsPVCs(ctx context.Context, instance client.Object) error {
 	Log := r.GetLogger(ctx)
 
-	cm, err := r.GetLockInfo(ctx, instance)
-	if err != nil && k8s_errors.IsNotFound(err) {
-		return true, nil
-	} else if err != nil {
-		return false, err
-	}
-
-	// Lock can be only released by the instance that created it
-	if cm.Data[testOperatorLockOwnerField] != string(instance.GetUID()) {
-		return false, nil
+	pvcList := &corev1.PersistentVolumeClaimList{}
+	err := r.Client.List(
+		ctx,
+		pvcList,
+		client.InNamespace(instance.GetNamespace()),
+		client.MatchingLabels{instanceNameLabel: instance.GetName()},
+	)
+	if err != nil {
+		return err
 	}
 
-	err = r.Client.Delete(ctx, cm)
-	if err != nil && k8s_errors.IsNotFound(err) {
-		return false, nil
+	isInstanceRef := func(ref metav1.OwnerReference) bool {
+		return ref.UID == instance.GetUID()
 	}
 
-	// Check whether the lock was successfully deleted
-	maxRetries := 10
-	lockDeletionSleepPeriod := 10
-	for i := 0; i < maxRetries; i++ {
-		_, err = r.GetLockInfo(ctx, instance)
-		if err != nil && k8s_errors.IsNotFound(err) {
-			return true, nil
+	for _, logsPVC := range pvcList.Items {
+		if !slices.ContainsFunc(logsPVC.OwnerReferences, isInstanceRef) {
+			continue
 		}
 
-		time.Sleep(time.Second * time.Duration(lockDeletionSleepPeriod))
-		Log.Info("Waiting for the test-operator-lock deletion!")
+		Log.Info(fmt.Sprintf(InfoRetainingLogsPVC, logsPVC.Name))
+		patch := client.MergeFrom(logsPVC.DeepCopy())
+		logsPVC.OwnerReferences = slices.DeleteFunc(logsPVC.OwnerReferences, isInstanceRef)
+		if err := r.Client.Patch(ctx, &logsPVC, patch); err != nil && !k8s_errors.IsNotFound(err) {
+			return err
+		}
 	}
 
-	return false, ErrFailedToDeleteLock
+	return nil
 }
 
-// GetPodIfExists returns the pod for the given instance and workflow step if it exists
+// GetPodIfExists returns the pod for the given instance, workflow step and
+// attempt if it exists
 func (r *Reconciler) GetPodIfExists(
 	ctx context.Context,
 	instance client.Object,
 	workflowStepIndex int,
+	attempt int,
 ) (*corev1.Pod, error) {
-	podName := r.GetPodName(instance, workflowStepIndex)
+	podName := r.GetPodName(instance, workflowStepIndex, attempt)
 	pod, err := r.GetPod(ctx, podName, instance.GetNamespace())
 	if err != nil {
 		if k8s_errors.IsNotFound(err) {
@@ -703,11 +2917,12 @@ func (r *Reconciler) VerifyNetworkAttachments(
 	networkAttachments []string,
 	serviceLabels map[string]string,
 	workflowStepIndex int,
+	attempt int,
 	conditions *condition.Conditions,
 	networkAttachmentStatus *map[string][]string,
 ) (ctrl.Result, error) {
 	Log := r.GetLogger(ctx)
-	pod, err := r.GetPodIfExists(ctx, instance, workflowStepIndex)
+	pod, err := r.GetPodIfExists(ctx, instance, workflowStepIndex, attempt)
 	if pod == nil {
 		return ctrl.Result{}, err
 	}
@@ -976,7 +3191,9 @@ func MergeSections(main interface{}, workflow interface{}) {
 	}
 }
 
-// CalculateConfigHash calculates a hash of the entire Spec to detect any changes
+// CalculateConfigHash calculates a hash of the entire Spec to detect any changes.
+// The fields that control the execution of the tests (e.g. Suspend) are not
+// part of the hash so that changing them does not restart the testing.
 func CalculateConfigHash(instance client.Object) string {
 	v := reflect.ValueOf(instance)
 	spec, err := SafetyCheck(v, "Spec")
@@ -989,11 +3206,64 @@ func CalculateConfigHash(instance client.Object) string {
 		return ""
 	}
 
+	data, err = filterConfigHashFields(data)
+	if err != nil {
+		return ""
+	}
+
 	hash := sha256.Sum256(data)
 	return fmt.Sprintf("%x", hash[:8])
 }
 
-// CheckConfigChange checks if the spec has changed and recreates all pods related to the instance if needed
+// filterConfigHashFields drops the ignored and the defaulted fields from the
+// encoded spec. The order of the remaining fields is kept so that the result
+// matches the encoding of the spec that did not have the dropped fields.
+func filterConfigHashFields(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+
+	var filtered bytes.Buffer
+	filtered.WriteByte('{')
+	for decoder.More() {
+		keyToken, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+
+		key, _ := keyToken.(string)
+		if slices.Contains(configHashIgnoredFields, key) {
+			continue
+		}
+
+		if defaultValue, ok := configHashDefaultedFields[key]; ok && string(value) == defaultValue {
+			continue
+		}
+
+		if filtered.Len() > 1 {
+			filtered.WriteByte(',')
+		}
+
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		filtered.Write(encodedKey)
+		filtered.WriteByte(':')
+		filtered.Write(value)
+	}
+	filtered.WriteByte('}')
+
+	return filtered.Bytes(), nil
+}
+
+// CheckConfigChange checks if the spec has changed and recreates all pods of the current run of the instance if needed
 func (r *Reconciler) CheckConfigChange(
 	ctx context.Context,
 	instance client.Object,
@@ -1016,7 +3286,7 @@ func (r *Reconciler) CheckConfigChange(
 
 	var currentHash string
 	for _, pod := range podList.Items {
-		if pod.DeletionTimestamp != nil {
+		if pod.DeletionTimestamp != nil || !isCurrentRunPod(instance, &pod) {
 			continue
 		}
 
@@ -1030,8 +3300,10 @@ func (r *Reconciler) CheckConfigChange(
 		return ctrl.Result{}, nil
 	}
 
+	// The pods kept from an earlier run are not deleted. All steps are
+	// executed again by the current run instead.
 	for _, pod := range podList.Items {
-		if pod.DeletionTimestamp != nil {
+		if pod.DeletionTimestamp != nil || getRunIndexLabel(&pod) != GetRunIndex(instance) {
 			continue
 		}
 
@@ -1042,5 +3314,9 @@ func (r *Reconciler) CheckConfigChange(
 		}
 	}
 
+	if testResource, ok := instance.(TestResource); ok {
+		testResource.GetStatus().Steps = nil
+	}
+
 	return ctrl.Result{Requeue: true}, nil
 }
@@ -20,11 +20,14 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/go-logr/logr"
 	"github.com/openstack-k8s-operators/lib-common/modules/common"
 	"github.com/openstack-k8s-operators/lib-common/modules/common/condition"
 	"github.com/openstack-k8s-operators/lib-common/modules/common/helper"
+	testv1beta1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
+	"github.com/openstack-k8s-operators/test-operator/internal/artifacts"
 	corev1 "k8s.io/api/core/v1"
 	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -37,6 +40,21 @@ type TestResource interface {
 	client.Object
 	GetConditions() *condition.Conditions
 	GetStorageClass() string
+	GetStatus() *testv1beta1.CommonTestStatus
+	GetPriority() int32
+	GetConcurrencyGroup() string
+	GetLockScope() testv1beta1.LockScope
+	GetLockGroup() string
+	GetOpenStackConfigMap() string
+	IsSuspended() bool
+	GetSuspendPolicy() testv1beta1.SuspendPolicy
+	GetTerminationGracePeriod() int64
+	GetLogsPVCRetentionPolicy() testv1beta1.LogsPVCRetentionPolicy
+	GetLogsRetention() *testv1beta1.LogsRetention
+	GetLogsVolume() *testv1beta1.LogsVolume
+	IsArtifactsServerEnabled() bool
+	GetArtifactsServerTTL() int64
+	GetArtifactsUpload() *testv1beta1.ArtifactsUpload
 	SetObservedGeneration()
 }
 
@@ -58,10 +76,10 @@ type TestResourceConfig[T TestResource] struct {
 	SupportsWorkflow bool
 
 	// GenerateServiceConfigMaps creates resource-specific config maps
-	GenerateServiceConfigMaps func(ctx context.Context, helper *helper.Helper, labels map[string]string, instance T, workflowStepIndex int) error
+	GenerateServiceConfigMaps func(ctx context.Context, helper *helper.Helper, labels map[string]string, instance T, workflowStepIndex int, attempt int) error
 
 	// BuildPod creates the resource-specific pod definition
-	BuildPod func(ctx context.Context, instance T, labels, annotations map[string]string, workflowStepIndex int, pvcIndex int) (*corev1.Pod, error)
+	BuildPod func(ctx context.Context, instance T, labels, annotations map[string]string, workflowStepIndex int, attempt int, pvcIndex int) (*corev1.Pod, error)
 
 	// GetInitialConditions returns the condition list for a new instance
 	GetInitialConditions func() []*condition.Condition
@@ -69,15 +87,24 @@ type TestResourceConfig[T TestResource] struct {
 	// ValidateInputs validates resource-specific inputs
 	ValidateInputs func(ctx context.Context, instance T) error
 
+	// GetLogsDirName returns the directory of the logs PVC the test pod
+	// writes its logs to (optional). The whole logs PVC is uploaded when the
+	// directory is not known.
+	GetLogsDirName func(instance T, workflowStepIndex int, attempt int) string
+
+	// ParseTestResults parses the output of a finished test pod (optional)
+	ParseTestResults func(logs string) *testv1beta1.TestResults
+
 	// Optional filed accessors
 	GetParallel                func(instance T) bool
 	GetNetworkAttachments      func(instance T) []string
 	GetNetworkAttachmentStatus func(instance T) *map[string][]string
 
 	// Optional filed accessors - workflow support
-	GetSpec           func(instance T) interface{}
-	GetWorkflowStep   func(instance T, step int) interface{}
-	GetWorkflowLength func(instance T) int
+	GetSpec            func(instance T) interface{}
+	GetWorkflowStep    func(instance T, step int) interface{}
+	GetWorkflowLength  func(instance T) int
+	GetWorkflowOptions func(instance T) testv1beta1.WorkflowOptions
 }
 
 // CommonReconcile executes the standard reconciliation workflow using generics
@@ -146,6 +173,9 @@ func CommonReconcile[T TestResource](
 		cl := condition.CreateList(config.GetInitialConditions()...)
 		conditions.Init(&cl)
 
+		// The rerun annotation set on creation does not request another run
+		instance.GetStatus().RunID = instance.GetAnnotations()[testv1beta1.RerunAnnotation]
+
 		// Register overall status immediately to have an early feedback
 		// e.g. in the cli
 		return ctrl.Result{}, nil
@@ -162,11 +192,30 @@ func CommonReconcile[T TestResource](
 	// Handle service delete
 	if config.NeedsFinalizer && !instance.GetDeletionTimestamp().IsZero() {
 		Log.Info("Reconciling Service delete")
+		ctrlResult, err := r.CancelTesting(ctx, instance)
+		if err != nil || (ctrlResult != ctrl.Result{}) {
+			return ctrlResult, err
+		}
+
 		controllerutil.RemoveFinalizer(instance, helper.GetFinalizer())
 		Log.Info("Reconciled Service delete successfully")
 		return ctrl.Result{}, nil
 	}
 
+	// Start a new run once the current run finished when the rerun annotation
+	// was set to a new value. The pods and PVCs of the finished run are kept.
+	runID := instance.GetAnnotations()[testv1beta1.RerunAnnotation]
+	if runID != "" && runID != instance.GetStatus().RunID && IsTestingFinished(instance) {
+		rerunMode := instance.GetAnnotations()[testv1beta1.RerunModeAnnotation]
+		if !StartNextRun(instance, runID, rerunMode, config.GetInitialConditions()) {
+			Log.Info(fmt.Sprintf(InfoNoFailedSteps, runID))
+			return ctrl.Result{}, nil
+		}
+
+		Log.Info(fmt.Sprintf(InfoStartingRun, instance.GetStatus().RunIndex, testv1beta1.RerunAnnotation))
+		return ctrl.Result{Requeue: true}, nil
+	}
+
 	if config.NeedsNetworkAttachments {
 		networkStatus := config.GetNetworkAttachmentStatus(instance)
 		if networkStatus != nil && *networkStatus == nil {
@@ -175,15 +224,60 @@ func CommonReconcile[T TestResource](
 	}
 
 	workflowLength := 0
+	workflowOptions := testv1beta1.WorkflowOptions{}
 	if config.SupportsWorkflow {
 		workflowLength = config.GetWorkflowLength(instance)
+		workflowOptions = config.GetWorkflowOptions(instance)
+	}
+
+	workflowState, err := r.GetWorkflowState(ctx, instance, workflowLength)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Terminate test pods that exceeded their timeout. The terminated pods
+	// are evaluated as TimedOut in the next reconcile.
+	podsTerminated, err := r.TerminateTimedOutPods(ctx, instance, workflowState, workflowOptions)
+	if err != nil {
+		return ctrl.Result{}, err
+	} else if podsTerminated {
+		return ctrl.Result{Requeue: true}, nil
 	}
 
-	nextAction, workflowStepIndex, err := r.NextAction(ctx, instance, workflowLength)
+	// Report test pods stuck in the Pending phase and terminate the ones that
+	// exceeded their pendingTimeout
+	podsTerminated, err = r.CheckPendingPods(ctx, instance, conditions, workflowState)
+	if err != nil {
+		return ctrl.Result{}, err
+	} else if podsTerminated {
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	nextAction, workflowStepIndex, err := r.NextAction(ctx, instance, workflowState, workflowOptions)
 	if nextAction == Failure {
 		return ctrl.Result{}, err
 	}
 
+	UpdateStepsStatus(instance, instance.GetStatus(), workflowState, nextAction == StopWorkflow)
+	if config.ParseTestResults != nil {
+		r.UpdateTestResults(ctx, instance, instance.GetStatus(), workflowState, config.ParseTestResults)
+	}
+
+	// Upload the logs of the finished workflow steps when requested
+	if err := r.UploadStepArtifacts(ctx, instance, conditions, workflowState); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	testingFinished := nextAction == EndTesting || nextAction == StopWorkflow
+	UpdateTestConditions(instance, conditions, workflowState, testingFinished)
+	attempt := workflowState.NextAttempt(workflowStepIndex)
+	podName := r.GetPodName(instance, workflowStepIndex, attempt)
+
+	// Let the instances waiting for the lock know that its owner is alive
+	if err := r.RefreshLockHeartbeat(ctx, instance, workflowState); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	// Check for config changes and handle pod recreation
 	configHash := CalculateConfigHash(instance)
 	ctrlResult, err := r.CheckConfigChange(ctx, instance, configHash)
@@ -204,57 +298,114 @@ func CommonReconcile[T TestResource](
 	}
 
 	pvcIndex := 0
-	// Create multiple PVCs for parallel execution
-	if parallel && config.SupportsWorkflow && workflowStepIndex < workflowLength {
+	// Create multiple PVCs for parallel execution. Steps of a DAG workflow can
+	// run at the same time as well. Steps with their own logs volume options
+	// do not share the PVC either.
+	if (parallel || IsWorkflowDAG(instance) || IsLogsVolumePerStep(instance)) &&
+		config.SupportsWorkflow && workflowStepIndex < workflowLength {
 		pvcIndex = workflowStepIndex
 	}
+	logsVolume := GetLogsVolume(instance)
+
+	// Serve the logs of the finished testing when the artifacts server is
+	// enabled
+	artifactsResult, err := r.ReconcileArtifactsServer(ctx, instance, testingFinished)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// A suspended instance does not spawn new test pods. It continues with the
+	// next unfinished workflow step once it is resumed.
+	if instance.IsSuspended() && nextAction != EndTesting && nextAction != StopWorkflow {
+		return r.SuspendTesting(ctx, instance, workflowState)
+	}
 
 	switch nextAction {
 	case CheckPending:
 		Log.Info(InfoPendingPod)
-		return ctrl.Result{RequeueAfter: RequeueAfterValue}, nil
+		return ctrl.Result{RequeueAfter: workflowState.RequeueAfter()}, nil
 
 	case Wait:
 		Log.Info(InfoWaitingOnPod)
-		return ctrl.Result{RequeueAfter: RequeueAfterValue}, nil
+		return ctrl.Result{RequeueAfter: workflowState.RequeueAfter()}, nil
+
+	case WaitForRetry:
+		Log.Info(InfoWaitingForRetry)
+		return ctrl.Result{RequeueAfter: workflowState.RequeueAfter()}, nil
+
+	case EndTesting, StopWorkflow:
+		// The logs are uploaded from the volumes of the test pods. The lock
+		// is kept until the uploads finished.
+		if IsArtifactsUploadInProgress(instance) {
+			Log.Info(InfoWaitingForArtifactsUpload)
+			return ctrl.Result{RequeueAfter: RequeueAfterValue}, nil
+		}
 
-	case EndTesting:
-		// All pods created by the instance were completed. Release the lock
-		// so that other instances can spawn their pods.
+		// All pods created by the instance were completed or the workflow
+		// failure policy stopped the workflow. Release the lock so that other
+		// instances can spawn their pods.
 		if lockReleased, err := r.ReleaseLock(ctx, instance); !lockReleased {
-			Log.Info(fmt.Sprintf(InfoCanNotReleaseLock, testOperatorLockName))
-			return ctrl.Result{RequeueAfter: RequeueAfterValue}, err
+			Log.Info(fmt.Sprintf(InfoCanNotReleaseLock, GetLockName(instance)))
+			return ctrl.Result{Requeue: true}, err
 		}
 
 		conditions.MarkTrue(condition.DeploymentReadyCondition, condition.DeploymentReadyMessage)
 
-		if conditions.AllSubConditionIsTrue() {
+		if AllSubConditionIsTrueExcept(conditions, testv1beta1.TestsPassedCondition, testv1beta1.ArtifactsUploadedCondition) {
 			conditions.MarkTrue(condition.ReadyCondition, condition.ReadyMessage)
 		}
 
+		if nextAction == StopWorkflow && workflowState.IsWorkflowTimedOut(workflowOptions) {
+			Log.Info(InfoWorkflowTimedOut)
+			return artifactsResult, nil
+		}
+
+		if nextAction == StopWorkflow {
+			Log.Info(fmt.Sprintf(InfoWorkflowStopped, workflowOptions.WorkflowFailurePolicy))
+			return artifactsResult, nil
+		}
+
 		Log.Info(InfoTestingCompleted)
-		return ctrl.Result{}, nil
+		return artifactsResult, nil
 
 	case CreateFirstPod:
-		lockAcquired, err := r.AcquireLock(ctx, instance, helper, parallel)
+		lockAcquired, err := r.AcquireLock(ctx, instance, parallel, podName)
 		if !lockAcquired {
-			Log.Info(fmt.Sprintf(InfoCanNotAcquireLock, testOperatorLockName))
+			if queuePosition := instance.GetStatus().QueuePosition; err == nil && queuePosition > 0 {
+				Log.Info(fmt.Sprintf(InfoQueuePosition, GetLockName(instance), queuePosition))
+			} else {
+				Log.Info(fmt.Sprintf(InfoCanNotAcquireLock, GetLockName(instance)))
+			}
 			return ctrl.Result{RequeueAfter: RequeueAfterValue}, err
 		}
 
 		Log.Info(fmt.Sprintf(InfoCreatingFirstPod, workflowStepIndex))
 
-	case CreateNextPod:
-		// Confirm that we still hold the lock. This is useful to check if for
-		// example somebody / something deleted the lock and it got claimed by
-		// another instance. This is considered to be an error state.
-		lockAcquired, err := r.AcquireLock(ctx, instance, helper, parallel)
-		if !lockAcquired {
-			Log.Error(err, fmt.Sprintf(ErrConfirmLockOwnership, testOperatorLockName))
-			return ctrl.Result{RequeueAfter: RequeueAfterValue}, err
+	case CreateNextPod, RetryPod:
+		// Confirm that we still hold the lock. The lock is lost when its lease
+		// expired or when somebody / something deleted the lock. The instance
+		// has to wait for the lock again before it spawns more pods. Instances
+		// of a concurrency group acquire a new slot for each test pod.
+		lockHeld, err := r.HoldsLock(ctx, instance, parallel, podName)
+		if err != nil {
+			return ctrl.Result{}, err
 		}
 
-		Log.Info(fmt.Sprintf(InfoCreatingNextPod, workflowStepIndex))
+		if !lockHeld {
+			if GetConcurrencyGroup(instance) == "" {
+				Log.Info(fmt.Sprintf(ErrConfirmLockOwnership, GetLockName(instance)))
+			}
+			lockAcquired, err := r.AcquireLock(ctx, instance, parallel, podName)
+			if !lockAcquired {
+				return ctrl.Result{RequeueAfter: RequeueAfterValue}, err
+			}
+		}
+
+		if nextAction == RetryPod {
+			Log.Info(fmt.Sprintf(InfoRetryingPod, workflowStepIndex, attempt))
+		} else {
+			Log.Info(fmt.Sprintf(InfoCreatingNextPod, workflowStepIndex))
+		}
 
 	default:
 		return ctrl.Result{}, ErrReceivedUnexpectedAction
@@ -263,6 +414,8 @@ func CommonReconcile[T TestResource](
 	serviceLabels := map[string]string{
 		common.AppSelector: config.ServiceName,
 		workflowStepLabel:  strconv.Itoa(workflowStepIndex),
+		attemptLabel:       strconv.Itoa(attempt),
+		runIndexLabel:      strconv.Itoa(instance.GetStatus().RunIndex),
 		instanceNameLabel:  instance.GetName(),
 		operatorNameLabel:  "test-operator",
 	}
@@ -282,26 +435,32 @@ func CommonReconcile[T TestResource](
 	}
 
 	// Create PersistentVolumeClaim
-	ctrlResult, err = r.EnsureLogsPVCExists(
-		ctx,
-		instance,
-		helper,
-		serviceLabels,
-		instance.GetStorageClass(),
-		pvcIndex,
-	)
-	if err != nil {
-		return ctrlResult, err
-	} else if (ctrlResult != ctrl.Result{}) {
-		return ctrlResult, nil
+	if logsVolume.Type != testv1beta1.LogsVolumeTypeEmptyDir {
+		ctrlResult, err = r.EnsureLogsPVCExists(
+			ctx,
+			instance,
+			helper,
+			serviceLabels,
+			instance.GetStorageClass(),
+			logsVolume,
+			pvcIndex,
+		)
+		if err != nil {
+			return ctrlResult, err
+		} else if (ctrlResult != ctrl.Result{}) {
+			return ctrlResult, nil
+		}
 	}
 
 	serviceAnnotations := make(map[string]string)
 	serviceAnnotations["test.openstack.org/config-hash"] = configHash
+	if config.GetLogsDirName != nil {
+		serviceAnnotations[artifacts.LogsDirAnnotation] = config.GetLogsDirName(instance, workflowStepIndex, attempt)
+	}
 
 	// Generate ConfigMaps containing test configuration
 	if config.NeedsConfigMaps {
-		err = config.GenerateServiceConfigMaps(ctx, helper, serviceLabels, instance, workflowStepIndex)
+		err = config.GenerateServiceConfigMaps(ctx, helper, serviceLabels, instance, workflowStepIndex, attempt)
 		if err != nil {
 			conditions.Set(condition.FalseCondition(
 				condition.ServiceConfigReadyCondition,
@@ -338,19 +497,36 @@ func CommonReconcile[T TestResource](
 		serviceLabels,
 		serviceAnnotations,
 		workflowStepIndex,
+		attempt,
 		pvcIndex,
 	)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
+	// Let the kubelet terminate the test pod once its timeout expires
+	if timeout := workflowState.GetPodTimeout(instance, workflowStepIndex, workflowOptions); timeout > 0 {
+		podDef.Spec.ActiveDeadlineSeconds = &timeout
+	}
+
+	if logsVolume.Type == testv1beta1.LogsVolumeTypeEmptyDir {
+		SetEmptyDirLogsVolume(podDef, logsVolume)
+	}
+
+	// Give the test pod time to store its logs when it is terminated
+	gracePeriod := instance.GetTerminationGracePeriod()
+	podDef.Spec.TerminationGracePeriodSeconds = &gracePeriod
+
 	// Create a new pod
 	ctrlResult, err = r.CreatePod(ctx, *helper, podDef)
 	if err != nil {
 		// Release the lock and allow other controllers to spawn
-		// a pod.
-		if lockReleased, lockErr := r.ReleaseLock(ctx, instance); !lockReleased {
-			return ctrl.Result{RequeueAfter: RequeueAfterValue}, lockErr
+		// a pod. The lock is kept while test pods of other workflow
+		// steps are still running.
+		if !workflowState.HasActivePod() {
+			if lockReleased, lockErr := r.ReleaseLock(ctx, instance); !lockReleased {
+				return ctrl.Result{Requeue: true}, lockErr
+			}
 		}
 		conditions.Set(condition.FalseCondition(
 			condition.DeploymentReadyCondition,
@@ -358,6 +534,14 @@ func CommonReconcile[T TestResource](
 			condition.SeverityWarning,
 			condition.DeploymentReadyErrorMessage,
 			err.Error()))
+		if k8s_errors.IsForbidden(err) && strings.Contains(err.Error(), "security context constraint") {
+			conditions.Set(condition.FalseCondition(
+				testv1beta1.TestPodsStartedCondition,
+				testv1beta1.SecurityContextDeniedReason,
+				condition.SeverityError,
+				testv1beta1.TestPodsStartedCreateErrorMessage,
+				err.Error()))
+		}
 		return ctrlResult, err
 	} else if (ctrlResult != ctrl.Result{}) {
 		conditions.Set(condition.FalseCondition(
@@ -376,6 +560,7 @@ func CommonReconcile[T TestResource](
 			config.GetNetworkAttachments(instance),
 			serviceLabels,
 			workflowStepIndex,
+			attempt,
 			conditions,
 			config.GetNetworkAttachmentStatus(instance),
 		)
@@ -385,7 +570,7 @@ func CommonReconcile[T TestResource](
 	}
 
 	// Mark ready if all conditions are true
-	if conditions.AllSubConditionIsTrue() {
+	if AllSubConditionIsTrueExcept(conditions, testv1beta1.TestsPassedCondition) {
 		conditions.MarkTrue(condition.ReadyCondition, condition.ReadyMessage)
 	}
 
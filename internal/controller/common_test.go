@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega" //revive:disable:dot-imports
+
+	testv1beta1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// baselineConfigHash is the config hash that the operator released before the
+// workflow options were added computed for newConfigHashInstance
+const baselineConfigHash = "b957c0a98391ee9a"
+
+// newConfigHashInstance returns an instance whose new spec fields keep the
+// values defaulted by the CRD
+func newConfigHashInstance() *testv1beta1.Tobiko {
+	instance := &testv1beta1.Tobiko{}
+	instance.Name = "tobiko"
+	instance.Namespace = "test"
+	instance.Spec.StorageClass = "local-storage"
+	instance.Spec.ContainerImage = "quay.io/podified-antelope-centos9/openstack-tobiko:current-podified"
+	instance.Spec.OpenStackConfigMap = "openstack-config"
+	instance.Spec.OpenStackConfigSecret = "openstack-config-secret"
+	instance.Spec.Testenv = "sanity"
+	instance.Spec.Version = "master"
+	instance.Spec.NumProcesses = 2
+	instance.Spec.Workflow = []testv1beta1.TobikoWorkflowSpec{
+		{StepName: "first"},
+		{StepName: "second"},
+	}
+
+	instance.Spec.BackoffDelay = 10
+	instance.Spec.BackoffMaxDelay = 300
+	instance.Spec.LockScope = testv1beta1.LockScopeNamespace
+	instance.Spec.SuspendPolicy = testv1beta1.SuspendPolicyWait
+	instance.Spec.TerminationGracePeriodSeconds = 30
+	instance.Spec.LogsPVCRetentionPolicy = testv1beta1.LogsPVCRetentionPolicyDelete
+	instance.Spec.WorkflowFailurePolicy = testv1beta1.WorkflowFailurePolicyContinue
+	instance.Spec.WorkflowMaxFailures = 1
+
+	return instance
+}
+
+func TestCalculateConfigHash(t *testing.T) {
+	tests := []struct {
+		name          string
+		update        func(instance *testv1beta1.Tobiko)
+		matchBaseline bool
+	}{
+		{
+			name:          "default values of the new fields",
+			update:        func(_ *testv1beta1.Tobiko) {},
+			matchBaseline: true,
+		},
+		{
+			name: "fields that do not change the test pods",
+			update: func(instance *testv1beta1.Tobiko) {
+				instance.Spec.Priority = 10
+				instance.Spec.Suspend = true
+				instance.Spec.LockScope = testv1beta1.LockScopeCluster
+				instance.Spec.LogsRetention = &testv1beta1.LogsRetention{KeepRuns: 1}
+			},
+			matchBaseline: true,
+		},
+		{
+			name: "changed baseline field",
+			update: func(instance *testv1beta1.Tobiko) {
+				instance.Spec.Testenv = "faults"
+			},
+			matchBaseline: false,
+		},
+		{
+			name: "new field with a non-default value",
+			update: func(instance *testv1beta1.Tobiko) {
+				instance.Spec.BackoffDelay = 20
+			},
+			matchBaseline: false,
+		},
+		{
+			name: "new workflow step field",
+			update: func(instance *testv1beta1.Tobiko) {
+				instance.Spec.Workflow[1].Timeout = ptr.To[int64](600)
+			},
+			matchBaseline: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			instance := newConfigHashInstance()
+			tt.update(instance)
+
+			if tt.matchBaseline {
+				g.Expect(CalculateConfigHash(instance)).To(Equal(baselineConfigHash))
+			} else {
+				g.Expect(CalculateConfigHash(instance)).ToNot(Equal(baselineConfigHash))
+			}
+		})
+	}
+}
+
+func TestCheckConfigChangeKeepsBaselinePods(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	instance := newConfigHashInstance()
+	instance.Status.Steps = []testv1beta1.WorkflowStepStatus{
+		{Name: "first", Phase: testv1beta1.WorkflowStepSucceeded},
+	}
+
+	// The pods created by the older operator have no runIndex label
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "tobiko-s00-first",
+			Namespace: instance.Namespace,
+			Labels: map[string]string{
+				instanceNameLabel: instance.Name,
+				workflowStepLabel: "0",
+			},
+			Annotations: map[string]string{
+				"test.openstack.org/config-hash": baselineConfigHash,
+			},
+		},
+	}
+
+	r := &Reconciler{Client: fake.NewClientBuilder().WithObjects(pod).Build()}
+	result, err := r.CheckConfigChange(ctx, instance, CalculateConfigHash(instance))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.Requeue).To(BeFalse())
+	g.Expect(instance.Status.Steps).To(HaveLen(1))
+	g.Expect(r.Client.Get(ctx, types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace}, &corev1.Pod{})).To(Succeed())
+}
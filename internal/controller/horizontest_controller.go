@@ -25,9 +25,15 @@ import (
 	"github.com/openstack-k8s-operators/lib-common/modules/common/helper"
 	testv1beta1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
 	"github.com/openstack-k8s-operators/test-operator/internal/horizontest"
+	testutil "github.com/openstack-k8s-operators/test-operator/internal/util"
+	coordinationv1 "k8s.io/api/coordination/v1"
 	corev1 "k8s.io/api/core/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
 
 // HorizonTestReconciler reconciles a HorizonTest object
@@ -43,12 +49,18 @@ func (r *HorizonTestReconciler) GetLogger(ctx context.Context) logr.Logger {
 // +kubebuilder:rbac:groups=test.openstack.org,resources=horizontests,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=test.openstack.org,resources=horizontests/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=test.openstack.org,resources=horizontests/finalizers,verbs=update;patch
+// +kubebuilder:rbac:groups=test.openstack.org,resources=tempests;tobikoes;ansibletests;horizontests,verbs=get;list;watch
 // +kubebuilder:rbac:groups=k8s.cni.cncf.io,resources=network-attachment-definitions,verbs=get;list;watch
 // +kubebuilder:rbac:groups="security.openshift.io",resourceNames=anyuid;privileged;nonroot;nonroot-v2,resources=securitycontextconstraints,verbs=use
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete;
 // +kubebuilder:rbac:groups="",resources=pods,verbs=create;delete;get;list;patch;update;watch
+// +kubebuilder:rbac:groups="",resources=pods/status,verbs=get;patch;update
+// +kubebuilder:rbac:groups="",resources=events,verbs=get;list;create;patch
+// +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=pods/log,verbs=get
 // +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;create;update;watch;patch;delete
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile - HorizonTest
 func (r *HorizonTestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -58,20 +70,23 @@ func (r *HorizonTestReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		ServiceName:             horizontest.ServiceName,
 		NeedsNetworkAttachments: false,
 		NeedsConfigMaps:         true,
-		NeedsFinalizer:          false,
+		NeedsFinalizer:          true,
 		SupportsWorkflow:        false,
 
-		GenerateServiceConfigMaps: func(ctx context.Context, helper *helper.Helper, labels map[string]string, instance *testv1beta1.HorizonTest, _ int) error {
+		GenerateServiceConfigMaps: func(ctx context.Context, helper *helper.Helper, labels map[string]string, instance *testv1beta1.HorizonTest, _ int, _ int) error {
 			return r.generateServiceConfigMaps(ctx, helper, labels, instance)
 		},
 
-		BuildPod: func(ctx context.Context, instance *testv1beta1.HorizonTest, labels, annotations map[string]string, workflowStepIndex int, pvcIndex int) (*corev1.Pod, error) {
-			return r.buildHorizonTestPod(ctx, instance, labels, annotations, workflowStepIndex, pvcIndex)
+		BuildPod: func(ctx context.Context, instance *testv1beta1.HorizonTest, labels, annotations map[string]string, workflowStepIndex int, attempt int, pvcIndex int) (*corev1.Pod, error) {
+			return r.buildHorizonTestPod(ctx, instance, labels, annotations, workflowStepIndex, attempt, pvcIndex)
 		},
 
 		GetInitialConditions: func() []*condition.Condition {
 			return []*condition.Condition{
 				condition.UnknownCondition(condition.ReadyCondition, condition.InitReason, condition.ReadyInitMessage),
+				condition.UnknownCondition(testv1beta1.ExecutionCompletedCondition, condition.InitReason, testv1beta1.ExecutionCompletedInitMessage),
+				condition.UnknownCondition(testv1beta1.TestsPassedCondition, condition.InitReason, testv1beta1.TestsPassedInitMessage),
+				condition.UnknownCondition(testv1beta1.TestPodsStartedCondition, condition.InitReason, testv1beta1.TestPodsStartedInitMessage),
 				condition.UnknownCondition(condition.InputReadyCondition, condition.InitReason, condition.InputReadyInitMessage),
 				condition.UnknownCondition(condition.ServiceConfigReadyCondition, condition.InitReason, condition.ServiceConfigReadyInitMessage),
 				condition.UnknownCondition(condition.DeploymentReadyCondition, condition.InitReason, condition.DeploymentReadyInitMessage),
@@ -85,6 +100,12 @@ func (r *HorizonTestReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			return r.ValidateSecretWithKeys(ctx, instance, instance.Spec.KubeconfigSecretName, []string{})
 		},
 
+		ParseTestResults: testutil.ParsePytestResults,
+
+		GetLogsDirName: func(_ *testv1beta1.HorizonTest, _ int, attempt int) string {
+			return "horizon" + GetAttemptSuffix(attempt)
+		},
+
 		GetParallel: func(instance *testv1beta1.HorizonTest) bool {
 			return instance.Spec.Parallel
 		},
@@ -98,13 +119,14 @@ func (r *HorizonTestReconciler) buildHorizonTestPod(
 	instance *testv1beta1.HorizonTest,
 	labels, annotations map[string]string,
 	workflowStepIndex int,
+	attempt int,
 	pvcIndex int,
 ) (*corev1.Pod, error) {
 	mountCerts := r.CheckSecretExists(ctx, instance, "combined-ca-bundle")
 	mountKubeconfig := len(instance.Spec.KubeconfigSecretName) != 0
 
-	envVars := r.PrepareHorizonTestEnvVars(instance)
-	podName := r.GetPodName(instance, workflowStepIndex)
+	envVars := r.PrepareHorizonTestEnvVars(instance, attempt)
+	podName := r.GetPodName(instance, workflowStepIndex, attempt)
 	logsPVCName := r.GetPVCLogsName(instance, pvcIndex)
 
 	containerImage, err := r.GetContainerImage(ctx, instance)
@@ -132,6 +154,13 @@ func (r *HorizonTestReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&corev1.Pod{}).
 		Owns(&corev1.Secret{}).
 		Owns(&corev1.ConfigMap{}).
+		Watches(
+			&coordinationv1.Lease{},
+			handler.EnqueueRequestsFromMapFunc(r.LockQueueRequests(func() client.ObjectList {
+				return &testv1beta1.HorizonTestList{}
+			})),
+			builder.WithPredicates(predicate.NewPredicateFuncs(IsTestOperatorLease)),
+		).
 		Complete(r)
 }
 
@@ -154,6 +183,7 @@ func (r *HorizonTestReconciler) generateServiceConfigMaps(
 // PrepareHorizonTestEnvVars prepares environment variables for HorizonTest execution
 func (r *HorizonTestReconciler) PrepareHorizonTestEnvVars(
 	instance *testv1beta1.HorizonTest,
+	attempt int,
 ) map[string]env.Setter {
 	// Prepare env vars
 	envVars := make(map[string]env.Setter)
@@ -166,7 +196,7 @@ func (r *HorizonTestReconciler) PrepareHorizonTestEnvVars(
 	// String
 	SetStringEnvVars(envVars, map[string]string{
 		"USE_EXTERNAL_FILES":    "True",
-		"HORIZON_LOGS_DIR_NAME": "horizon",
+		"HORIZON_LOGS_DIR_NAME": "horizon" + GetAttemptSuffix(attempt),
 
 		// Mandatory variables
 		"ADMIN_USERNAME":      instance.Spec.AdminUsername,
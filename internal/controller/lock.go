@@ -0,0 +1,364 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"strings"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	lockHolderKindAnnotation      = "test.openstack.org/holder-kind"
+	lockHolderNameAnnotation      = "test.openstack.org/holder-name"
+	lockHolderNamespaceAnnotation = "test.openstack.org/holder-namespace"
+	lockHolderPodAnnotation       = "test.openstack.org/holder-pod"
+
+	// legacyLockOwnerField is the key of the ConfigMap lock of the earlier
+	// releases that stores the UID of the instance holding the lock
+	legacyLockOwnerField = "owner"
+)
+
+// LeaseCacheByObject restricts the cache of the manager to the Leases created
+// by the test-operator. Without it the operator caches and watches all Leases
+// in the cluster, including the node heartbeats.
+func LeaseCacheByObject() map[client.Object]cache.ByObject {
+	return map[client.Object]cache.ByObject{
+		&coordinationv1.Lease{}: {
+			Label: labels.SelectorFromSet(labels.Set{operatorNameLabel: "test-operator"}),
+		},
+	}
+}
+
+// IsTestOperatorLease returns true when the object is the test-operator lock,
+// a lock shared across namespaces or a slot of a concurrency group
+func IsTestOperatorLease(obj client.Object) bool {
+	name := obj.GetName()
+	return name == testOperatorLockName ||
+		strings.HasPrefix(name, testOperatorLockPrefix) ||
+		strings.HasPrefix(name, testOperatorSemaphorePrefix)
+}
+
+// ErrLockNotRead indicates that the lock was modified before it was read.
+var ErrLockNotRead = errors.New("the lock has to be read before it is modified")
+
+// LockRecord describes the instance that holds the test-operator lock
+type LockRecord struct {
+	// HolderIdentity is the UID of the instance that holds the lock
+	HolderIdentity string
+
+	// HolderKind is the kind of the instance that holds the lock
+	HolderKind string
+
+	// HolderName is the name of the instance that holds the lock
+	HolderName string
+
+	// HolderNamespace is the namespace of the instance that holds the lock
+	HolderNamespace string
+
+	// HolderPod is the name of the test pod the lock is held for. Only the
+	// slots of a concurrency group are held for a single test pod.
+	HolderPod string
+
+	// LeaseDuration tells how long the holder keeps the lock without
+	// renewing it
+	LeaseDuration time.Duration
+
+	// AcquireTime is the time when the holder acquired the lock
+	AcquireTime time.Time
+
+	// RenewTime is the time when the holder renewed the lock for the last time
+	RenewTime time.Time
+
+	// Transitions is the number of times the lock changed its holder
+	Transitions int32
+}
+
+// IsHeldBy returns true when the lock is held by the given instance
+func (l *LockRecord) IsHeldBy(instance client.Object) bool {
+	return l.HolderIdentity == string(instance.GetUID())
+}
+
+// IsExpired returns true when the holder did not renew the lock within the
+// lease duration. The holder of an expired lock lost its ownership.
+func (l *LockRecord) IsExpired() bool {
+	return time.Since(l.RenewTime) > l.LeaseDuration
+}
+
+// TestLock is the lock that allows only a single instance to spawn test pods
+// at a time. The lock remembers the version read by Get. Update and Delete
+// fail with a conflict error when the lock was changed in the meantime.
+type TestLock interface {
+	// Get returns the current record of the lock. A NotFound error is
+	// returned when the lock does not exist.
+	Get(ctx context.Context) (*LockRecord, error)
+
+	// Create creates the lock held by the given instance. An AlreadyExists
+	// error is returned when the lock exists.
+	Create(ctx context.Context, instance client.Object, record LockRecord) error
+
+	// Update stores the given record in the lock and makes the given
+	// instance the owner of the lock
+	Update(ctx context.Context, instance client.Object, record LockRecord) error
+
+	// Delete deletes the lock
+	Delete(ctx context.Context) error
+
+	// Describe returns the name of the lock
+	Describe() string
+}
+
+// leaseLock is a TestLock stored in a coordination.k8s.io Lease
+type leaseLock struct {
+	client client.Client
+	scheme *runtime.Scheme
+	key    client.ObjectKey
+	lease  *coordinationv1.Lease
+}
+
+// NewLeaseLock returns a TestLock stored in the Lease with the given name
+func NewLeaseLock(c client.Client, scheme *runtime.Scheme, namespace string, name string) TestLock {
+	return &leaseLock{
+		client: c,
+		scheme: scheme,
+		key:    client.ObjectKey{Namespace: namespace, Name: name},
+	}
+}
+
+// Get returns the current record of the lock
+func (l *leaseLock) Get(ctx context.Context) (*LockRecord, error) {
+	lease := &coordinationv1.Lease{}
+	if err := l.client.Get(ctx, l.key, lease); err != nil {
+		return nil, err
+	}
+
+	l.lease = lease
+	return leaseToLockRecord(lease), nil
+}
+
+// Create creates the Lease held by the given instance
+func (l *leaseLock) Create(ctx context.Context, instance client.Object, record LockRecord) error {
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      l.key.Name,
+			Namespace: l.key.Namespace,
+			Labels:    map[string]string{operatorNameLabel: "test-operator"},
+		},
+	}
+
+	if err := l.setLockRecord(lease, instance, record); err != nil {
+		return err
+	}
+
+	if err := l.client.Create(ctx, lease); err != nil {
+		return err
+	}
+
+	l.lease = lease
+	return nil
+}
+
+// Update stores the given record in the Lease read by Get
+func (l *leaseLock) Update(ctx context.Context, instance client.Object, record LockRecord) error {
+	if l.lease == nil {
+		return ErrLockNotRead
+	}
+
+	lease := l.lease.DeepCopy()
+	if err := l.setLockRecord(lease, instance, record); err != nil {
+		return err
+	}
+
+	if err := l.client.Update(ctx, lease); err != nil {
+		return err
+	}
+
+	l.lease = lease
+	return nil
+}
+
+// Delete deletes the Lease read by Get
+func (l *leaseLock) Delete(ctx context.Context) error {
+	if l.lease == nil {
+		return ErrLockNotRead
+	}
+
+	return l.client.Delete(ctx, l.lease, client.Preconditions{
+		UID:             &l.lease.UID,
+		ResourceVersion: &l.lease.ResourceVersion,
+	})
+}
+
+// Describe returns the name of the Lease
+func (l *leaseLock) Describe() string {
+	return l.key.Name
+}
+
+// setLockRecord stores the record in the Lease. The holder of the lock from
+// the namespace of the Lease becomes the owner of the Lease so that the Lease
+// is garbage collected together with its holder.
+func (l *leaseLock) setLockRecord(lease *coordinationv1.Lease, instance client.Object, record LockRecord) error {
+	annotations := lease.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[lockHolderKindAnnotation] = record.HolderKind
+	annotations[lockHolderNameAnnotation] = record.HolderName
+	annotations[lockHolderNamespaceAnnotation] = record.HolderNamespace
+	if record.HolderPod != "" {
+		annotations[lockHolderPodAnnotation] = record.HolderPod
+	} else {
+		delete(annotations, lockHolderPodAnnotation)
+	}
+	lease.SetAnnotations(annotations)
+
+	// Owner references can not point to other namespaces. A Lease shared
+	// across namespaces is released only by its holder or by an expiry.
+	lease.OwnerReferences = nil
+	if instance.GetNamespace() == lease.GetNamespace() {
+		if err := controllerutil.SetControllerReference(instance, lease, l.scheme); err != nil {
+			return err
+		}
+	}
+
+	holderIdentity := record.HolderIdentity
+	leaseDurationSeconds := int32(record.LeaseDuration.Seconds()) // #nosec G115
+	transitions := record.Transitions
+	lease.Spec = coordinationv1.LeaseSpec{
+		HolderIdentity:       &holderIdentity,
+		LeaseDurationSeconds: &leaseDurationSeconds,
+		AcquireTime:          &metav1.MicroTime{Time: record.AcquireTime},
+		RenewTime:            &metav1.MicroTime{Time: record.RenewTime},
+		LeaseTransitions:     &transitions,
+	}
+
+	return nil
+}
+
+// leaseToLockRecord returns the record stored in the Lease. A Lease that was
+// never renewed expires based on its creation time.
+func leaseToLockRecord(lease *coordinationv1.Lease) *LockRecord {
+	record := &LockRecord{
+		HolderKind:      lease.GetAnnotations()[lockHolderKindAnnotation],
+		HolderName:      lease.GetAnnotations()[lockHolderNameAnnotation],
+		HolderNamespace: lease.GetAnnotations()[lockHolderNamespaceAnnotation],
+		HolderPod:       lease.GetAnnotations()[lockHolderPodAnnotation],
+		AcquireTime:     lease.GetCreationTimestamp().Time,
+		RenewTime:       lease.GetCreationTimestamp().Time,
+	}
+
+	if lease.Spec.HolderIdentity != nil {
+		record.HolderIdentity = *lease.Spec.HolderIdentity
+	}
+
+	if lease.Spec.LeaseDurationSeconds != nil {
+		record.LeaseDuration = time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
+	}
+
+	if lease.Spec.AcquireTime != nil {
+		record.AcquireTime = lease.Spec.AcquireTime.Time
+	}
+
+	if lease.Spec.RenewTime != nil {
+		record.RenewTime = lease.Spec.RenewTime.Time
+	}
+
+	if lease.Spec.LeaseTransitions != nil {
+		record.Transitions = *lease.Spec.LeaseTransitions
+	}
+
+	return record
+}
+
+// GetLegacyLock returns the test-operator lock ConfigMap created by the
+// earlier releases of the test-operator in the namespace of the instance. The
+// instance that was running when the test-operator was upgraded still holds
+// the ConfigMap lock. Nil is returned when the ConfigMap does not exist.
+//
+// TODO: drop the ConfigMap lock in the next release
+func (r *Reconciler) GetLegacyLock(ctx context.Context, instance client.Object) (*corev1.ConfigMap, error) {
+	cm := &corev1.ConfigMap{}
+	objectKey := client.ObjectKey{Namespace: instance.GetNamespace(), Name: testOperatorLockName}
+	if err := r.Client.Get(ctx, objectKey, cm); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+
+	return cm, nil
+}
+
+// MigrateLegacyLock replaces the ConfigMap lock held by the instance with the
+// Lease lock. The instance skips the queue of the Lease lock as the other
+// instances wait for the ConfigMap lock.
+func (r *Reconciler) MigrateLegacyLock(
+	ctx context.Context,
+	instance client.Object,
+	legacyLock *corev1.ConfigMap,
+) (bool, error) {
+	lock, err := r.GetTestLock(instance)
+	if err != nil {
+		return false, err
+	}
+
+	record, err := lock.Get(ctx)
+	if err != nil && k8s_errors.IsNotFound(err) {
+		if err := lock.Create(ctx, instance, r.newLockRecord(instance, nil)); err != nil {
+			if k8s_errors.IsAlreadyExists(err) {
+				return false, nil
+			}
+			return false, err
+		}
+	} else if err != nil {
+		return false, err
+	} else if !record.IsHeldBy(instance) {
+		// The lock shared across namespaces is held by an instance from
+		// another namespace
+		return false, nil
+	}
+
+	if err := r.Client.Delete(ctx, legacyLock); client.IgnoreNotFound(err) != nil {
+		return false, err
+	}
+
+	setQueuePosition(instance, 0)
+	return true, nil
+}
+
+// ReleaseLegacyLock deletes the ConfigMap lock when it is held by the instance
+func (r *Reconciler) ReleaseLegacyLock(ctx context.Context, instance client.Object) error {
+	legacyLock, err := r.GetLegacyLock(ctx, instance)
+	if err != nil || legacyLock == nil {
+		return err
+	}
+
+	if legacyLock.Data[legacyLockOwnerField] != string(instance.GetUID()) {
+		return nil
+	}
+
+	return client.IgnoreNotFound(r.Client.Delete(ctx, legacyLock))
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega" //revive:disable:dot-imports
+
+	testv1beta1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestAcquireLockLegacyConfigMap(t *testing.T) {
+	tests := []struct {
+		name           string
+		owner          string
+		parallel       bool
+		expectedLock   bool
+		expectedLegacy bool
+	}{
+		{
+			name:           "ConfigMap lock held by another instance",
+			owner:          "other-uid",
+			expectedLock:   false,
+			expectedLegacy: true,
+		},
+		{
+			name:           "ConfigMap lock held by the instance is migrated",
+			owner:          "tobiko-uid",
+			expectedLock:   true,
+			expectedLegacy: false,
+		},
+		{
+			name:           "parallel instance does not wait for the ConfigMap lock",
+			owner:          "other-uid",
+			parallel:       true,
+			expectedLock:   true,
+			expectedLegacy: true,
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := testv1beta1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+
+			instance := &testv1beta1.Tobiko{}
+			instance.Name = "tobiko"
+			instance.Namespace = "test"
+			instance.UID = types.UID("tobiko-uid")
+
+			legacyLock := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: testOperatorLockName, Namespace: "test"},
+				Data:       map[string]string{legacyLockOwnerField: tt.owner},
+			}
+
+			r := &Reconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme).
+					WithObjects(instance, legacyLock).
+					Build(),
+				Scheme: scheme,
+			}
+
+			lockAcquired, err := r.AcquireLock(ctx, instance, tt.parallel, "tobiko-s00-first")
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(lockAcquired).To(Equal(tt.expectedLock))
+
+			lockKey := client.ObjectKey{Namespace: "test", Name: testOperatorLockName}
+			err = r.Client.Get(ctx, lockKey, &corev1.ConfigMap{})
+			g.Expect(err == nil).To(Equal(tt.expectedLegacy))
+
+			if !tt.parallel {
+				err = r.Client.Get(ctx, lockKey, &coordinationv1.Lease{})
+				g.Expect(err == nil).To(Equal(tt.expectedLock))
+			}
+		})
+	}
+}
@@ -0,0 +1,358 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	testv1beta1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
+	"github.com/openstack-k8s-operators/test-operator/internal/artifacts"
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	eventReasonLogsReclaimed = "LogsReclaimed"
+
+	// DefaultLogsRetentionInterval tells how often the logs retention rules
+	// are enforced when no interval is configured
+	DefaultLogsRetentionInterval = time.Minute * 5
+)
+
+const (
+	// InfoLogsPVCsReclaimed is the info message when the logs PVCs of finished runs were deleted
+	InfoLogsPVCsReclaimed = "Deleted %d logs PVCs because of the logsRetention rules. Reclaimed storage: %s."
+	// ErrLogsRetention is the error message when the logs retention rules of an instance can not be enforced
+	ErrLogsRetention = "Can not enforce the logsRetention rules of %s %s/%s"
+)
+
+// LogsRetentionReconciler enforces the LogsRetention rules of the test CRs in
+// all namespaces. It runs in the background every Interval and deletes the
+// logs PVCs of the finished runs that matched the rules.
+type LogsRetentionReconciler struct {
+	Reconciler
+
+	// Interval between two runs of the reconciler
+	Interval time.Duration
+}
+
+// GetLogger returns a logger object with a prefix of "controller.name" and additional controller context fields
+func (r *LogsRetentionReconciler) GetLogger(ctx context.Context) logr.Logger {
+	return log.FromContext(ctx).WithName("Controllers").WithName("LogsRetention")
+}
+
+// +kubebuilder:rbac:groups=test.openstack.org,resources=tempests;tobikoes;ansibletests;horizontests,verbs=get;list;watch
+// +kubebuilder:rbac:groups=test.openstack.org,resources=tempests/status;tobikoes/status;ansibletests/status;horizontests/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=get;list;create;patch
+
+// Start runs the reconciler every Interval until the context is cancelled
+func (r *LogsRetentionReconciler) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		r.Reconcile(ctx)
+	}, r.Interval)
+
+	return nil
+}
+
+// NeedLeaderElection - only the leader deletes the logs PVCs
+func (r *LogsRetentionReconciler) NeedLeaderElection() bool {
+	return true
+}
+
+// Reconcile deletes the logs PVCs of all test CRs that matched their
+// LogsRetention rules and reports the reclaimed storage
+func (r *LogsRetentionReconciler) Reconcile(ctx context.Context) {
+	Log := r.GetLogger(ctx)
+
+	lists := []client.ObjectList{
+		&testv1beta1.TempestList{},
+		&testv1beta1.TobikoList{},
+		&testv1beta1.AnsibleTestList{},
+		&testv1beta1.HorizonTestList{},
+	}
+
+	deletedPVCs := 0
+	reclaimed := resource.Quantity{}
+	for _, list := range lists {
+		if err := r.Client.List(ctx, list); err != nil {
+			Log.Error(err, "Can not list test CRs")
+			continue
+		}
+
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			Log.Error(err, "Can not list test CRs")
+			continue
+		}
+
+		for _, item := range items {
+			instance, ok := item.(TestResource)
+			if !ok || instance.GetLogsRetention() == nil || !instance.GetDeletionTimestamp().IsZero() {
+				continue
+			}
+
+			deleted, size, err := r.ReclaimLogsPVCs(ctx, instance)
+			if err != nil {
+				Log.Error(err, fmt.Sprintf(
+					ErrLogsRetention,
+					r.GetKind(instance),
+					instance.GetNamespace(),
+					instance.GetName(),
+				))
+			}
+
+			deletedPVCs += deleted
+			reclaimed.Add(size)
+		}
+	}
+
+	if deletedPVCs > 0 {
+		Log.Info(fmt.Sprintf(InfoLogsPVCsReclaimed, deletedPVCs, reclaimed.String()))
+	}
+}
+
+// ReclaimLogsPVCs deletes the logs PVCs of the finished runs of the instance
+// that matched its LogsRetention rules. The logs PVCs mounted by the artifacts
+// server or by an uploader pod of the instance are kept until the pod is
+// deleted, they would stay Terminating while it runs. No logs PVC is deleted
+// while the logs are uploaded because a retried uploader pod would not start
+// without its logs PVC. The reclaimed storage is added to the
+// status of the instance and reported in an event. The function returns the
+// number of deleted logs PVCs and the storage they requested.
+func (r *LogsRetentionReconciler) ReclaimLogsPVCs(
+	ctx context.Context,
+	instance TestResource,
+) (int, resource.Quantity, error) {
+	reclaimed := resource.Quantity{}
+
+	pvcList := &corev1.PersistentVolumeClaimList{}
+	err := r.Client.List(
+		ctx,
+		pvcList,
+		client.InNamespace(instance.GetNamespace()),
+		client.MatchingLabels{instanceNameLabel: instance.GetName()},
+	)
+	if err != nil {
+		return 0, reclaimed, err
+	}
+
+	isInstanceRef := func(ref metav1.OwnerReference) bool {
+		return ref.UID == instance.GetUID()
+	}
+
+	if IsArtifactsUploadInProgress(instance) {
+		return 0, reclaimed, nil
+	}
+
+	mountedPVCs, err := r.GetArtifactsServerPVCNames(ctx, instance)
+	if err != nil {
+		return 0, reclaimed, err
+	}
+
+	uploaderPVCs, err := r.GetArtifactsUploaderPVCNames(ctx, instance)
+	if err != nil {
+		return 0, reclaimed, err
+	}
+	mountedPVCs = append(mountedPVCs, uploaderPVCs...)
+
+	now := time.Now()
+	status := instance.GetStatus()
+	deletedPVCs := []string{}
+	for _, logsPVC := range pvcList.Items {
+		// Logs PVCs kept after a previous instance was deleted are not managed
+		// by the test-operator anymore
+		if !logsPVC.DeletionTimestamp.IsZero() || !slices.ContainsFunc(logsPVC.OwnerReferences, isInstanceRef) {
+			continue
+		}
+
+		// The steps that a run in the failed rerun mode did not execute again
+		// keep their logs in the logs PVCs of the earlier runs. These logs
+		// PVCs hold the results of the current run.
+		runIndex := getRunIndexLabel(&logsPVC)
+		if slices.ContainsFunc(status.Steps, func(step testv1beta1.WorkflowStepStatus) bool {
+			return step.LogsPVC == logsPVC.Name
+		}) {
+			runIndex = status.RunIndex
+		}
+
+		if !IsLogsRetentionExpired(instance, runIndex, now) ||
+			slices.Contains(mountedPVCs, logsPVC.Name) {
+			continue
+		}
+
+		if err := r.Client.Delete(ctx, &logsPVC); err != nil && !k8s_errors.IsNotFound(err) {
+			return len(deletedPVCs), reclaimed, err
+		}
+
+		deletedPVCs = append(deletedPVCs, logsPVC.Name)
+		reclaimed.Add(logsPVC.Spec.Resources.Requests[corev1.ResourceStorage])
+	}
+
+	if len(deletedPVCs) == 0 {
+		return 0, reclaimed, nil
+	}
+
+	if r.Recorder != nil {
+		r.Recorder.Eventf(
+			instance,
+			corev1.EventTypeNormal,
+			eventReasonLogsReclaimed,
+			"Deleted logs PVCs %s because of the logsRetention rules. Reclaimed storage: %s.",
+			strings.Join(deletedPVCs, ", "),
+			reclaimed.String(),
+		)
+	}
+
+	patch := client.MergeFrom(instance.DeepCopyObject().(client.Object))
+	total := reclaimed.DeepCopy()
+	if status.ReclaimedLogsStorage != nil {
+		total.Add(*status.ReclaimedLogsStorage)
+	}
+	status.ReclaimedLogsStorage = &total
+
+	return len(deletedPVCs), reclaimed, r.Client.Status().Patch(ctx, instance, patch)
+}
+
+// GetArtifactsServerPVCNames returns the names of the logs PVCs mounted by the
+// artifacts server pod of the instance. An empty list is returned when the
+// server pod does not exist or when it terminated.
+func (r *LogsRetentionReconciler) GetArtifactsServerPVCNames(
+	ctx context.Context,
+	instance TestResource,
+) ([]string, error) {
+	pod, err := r.GetPod(ctx, instance.GetName()+artifacts.NameSuffix, instance.GetNamespace())
+	if k8s_errors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return getMountedPVCNames(*pod), nil
+}
+
+// GetArtifactsUploaderPVCNames returns the names of the logs PVCs mounted by
+// the uploader pods of the instance that did not terminate yet
+func (r *LogsRetentionReconciler) GetArtifactsUploaderPVCNames(
+	ctx context.Context,
+	instance TestResource,
+) ([]string, error) {
+	podList := &corev1.PodList{}
+	err := r.Client.List(
+		ctx,
+		podList,
+		client.InNamespace(instance.GetNamespace()),
+		client.MatchingLabels{artifactsUploadLabel: instance.GetName()},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return getMountedPVCNames(podList.Items...), nil
+}
+
+// getMountedPVCNames returns the names of the PVCs mounted by the given pods.
+// A terminated pod does not hold its volumes anymore.
+func getMountedPVCNames(pods ...corev1.Pod) []string {
+	pvcNames := []string{}
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil {
+				pvcNames = append(pvcNames, volume.PersistentVolumeClaim.ClaimName)
+			}
+		}
+	}
+
+	return pvcNames
+}
+
+// IsLogsRetentionExpired returns true when the logs PVCs of the run with the
+// given index matched the LogsRetention rules of the instance. The logs PVCs
+// of the run that did not finish yet are always kept.
+func IsLogsRetentionExpired(instance TestResource, runIndex int, now time.Time) bool {
+	retention := instance.GetLogsRetention()
+	if retention == nil {
+		return false
+	}
+
+	status := instance.GetStatus()
+	latestFinishedRun := status.RunIndex
+	if !IsTestingFinished(instance) {
+		latestFinishedRun--
+	}
+
+	if runIndex > latestFinishedRun {
+		return false
+	}
+
+	var run *testv1beta1.TestRunStatus
+	if runIndex == status.RunIndex {
+		currentRun := GetCurrentRunStatus(instance)
+		run = &currentRun
+	} else if idx := slices.IndexFunc(status.Runs, func(run testv1beta1.TestRunStatus) bool {
+		return run.Index == runIndex
+	}); idx >= 0 {
+		run = &status.Runs[idx]
+	}
+
+	// The outcome of the runs dropped from the run history is not known
+	if retention.KeepOnFailure && (run == nil || !run.Passed) {
+		return false
+	}
+
+	if retention.DeleteOnSuccess && run != nil && run.Passed {
+		return true
+	}
+
+	// Run indexes increase by one with every run
+	if retention.KeepRuns > 0 && latestFinishedRun-runIndex >= int(retention.KeepRuns) {
+		return true
+	}
+
+	if retention.TTLSecondsAfterFinished > 0 && run != nil && run.FinishTime != nil {
+		ttl := time.Duration(retention.TTLSecondsAfterFinished) * time.Second
+		return now.After(run.FinishTime.Add(ttl))
+	}
+
+	return false
+}
+
+// SetupWithManager sets up the reconciler as a background task of the Manager.
+func (r *LogsRetentionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Interval <= 0 {
+		r.Interval = DefaultLogsRetentionInterval
+	}
+
+	return mgr.Add(r)
+}
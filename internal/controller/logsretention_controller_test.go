@@ -0,0 +1,353 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega" //revive:disable:dot-imports
+
+	"github.com/openstack-k8s-operators/lib-common/modules/common/condition"
+	testv1beta1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newLogsRetentionInstance returns an instance in its fourth run (index 3).
+// The run history contains the failed run 1 that finished five hours ago and
+// the passed run 2 that finished three hours ago. Run 0 was dropped from the
+// history. The current run finished thirty minutes ago when finished is set.
+func newLogsRetentionInstance(
+	retention *testv1beta1.LogsRetention,
+	finished bool,
+	passed bool,
+	now time.Time,
+) *testv1beta1.Tobiko {
+	finishedAt := func(ago time.Duration) *metav1.Time {
+		return &metav1.Time{Time: now.Add(-ago)}
+	}
+
+	instance := &testv1beta1.Tobiko{}
+	instance.Spec.LogsRetention = retention
+
+	status := instance.GetStatus()
+	status.RunIndex = 3
+	status.Runs = []testv1beta1.TestRunStatus{
+		{Index: 1, Passed: false, FinishTime: finishedAt(5 * time.Hour)},
+		{Index: 2, Passed: true, FinishTime: finishedAt(3 * time.Hour)},
+	}
+
+	if !finished {
+		status.Steps = []testv1beta1.WorkflowStepStatus{
+			{Phase: testv1beta1.WorkflowStepRunning},
+		}
+		return instance
+	}
+
+	status.Steps = []testv1beta1.WorkflowStepStatus{
+		{Phase: testv1beta1.WorkflowStepSucceeded, FinishTime: finishedAt(30 * time.Minute)},
+	}
+	status.Conditions = condition.Conditions{
+		*condition.TrueCondition(testv1beta1.ExecutionCompletedCondition, testv1beta1.ExecutionCompletedMessage),
+	}
+	if passed {
+		status.Conditions.Set(condition.TrueCondition(
+			testv1beta1.TestsPassedCondition,
+			testv1beta1.TestsPassedMessage))
+	} else {
+		status.Conditions.Set(condition.FalseCondition(
+			testv1beta1.TestsPassedCondition,
+			testv1beta1.TestsFailedReason,
+			condition.SeverityWarning,
+			testv1beta1.TestsPassedErrorMessage,
+			"smoke"))
+	}
+
+	return instance
+}
+
+func TestIsLogsRetentionExpired(t *testing.T) {
+	tests := []struct {
+		name      string
+		retention *testv1beta1.LogsRetention
+		finished  bool
+		passed    bool
+		runIndex  int
+		expected  bool
+	}{
+		{
+			name:      "no logsRetention",
+			retention: nil,
+			finished:  true,
+			runIndex:  1,
+			expected:  false,
+		},
+		{
+			name:      "no rules",
+			retention: &testv1beta1.LogsRetention{},
+			finished:  true,
+			runIndex:  1,
+			expected:  false,
+		},
+		{
+			name:      "keepRuns deletes the older runs",
+			retention: &testv1beta1.LogsRetention{KeepRuns: 2},
+			finished:  true,
+			runIndex:  1,
+			expected:  true,
+		},
+		{
+			name:      "keepRuns keeps the latest runs",
+			retention: &testv1beta1.LogsRetention{KeepRuns: 2},
+			finished:  true,
+			runIndex:  2,
+			expected:  false,
+		},
+		{
+			name:      "keepRuns does not count the unfinished run",
+			retention: &testv1beta1.LogsRetention{KeepRuns: 1},
+			finished:  false,
+			runIndex:  2,
+			expected:  false,
+		},
+		{
+			name:      "keepRuns deletes the runs dropped from the history",
+			retention: &testv1beta1.LogsRetention{KeepRuns: 1},
+			finished:  true,
+			runIndex:  0,
+			expected:  true,
+		},
+		{
+			name:      "keepOnFailure keeps the failed runs over keepRuns",
+			retention: &testv1beta1.LogsRetention{KeepRuns: 1, KeepOnFailure: true},
+			finished:  true,
+			runIndex:  1,
+			expected:  false,
+		},
+		{
+			name:      "keepOnFailure does not keep the passed runs over keepRuns",
+			retention: &testv1beta1.LogsRetention{KeepRuns: 1, KeepOnFailure: true},
+			finished:  true,
+			runIndex:  2,
+			expected:  true,
+		},
+		{
+			name:      "keepOnFailure keeps the runs dropped from the history",
+			retention: &testv1beta1.LogsRetention{KeepRuns: 1, KeepOnFailure: true},
+			finished:  true,
+			runIndex:  0,
+			expected:  false,
+		},
+		{
+			name:      "TTL expired",
+			retention: &testv1beta1.LogsRetention{TTLSecondsAfterFinished: 3600},
+			finished:  true,
+			runIndex:  2,
+			expected:  true,
+		},
+		{
+			name:      "TTL not expired yet",
+			retention: &testv1beta1.LogsRetention{TTLSecondsAfterFinished: 3600},
+			finished:  true,
+			runIndex:  3,
+			expected:  false,
+		},
+		{
+			name:      "TTL of the current run counts from its last finished step",
+			retention: &testv1beta1.LogsRetention{TTLSecondsAfterFinished: 600},
+			finished:  true,
+			passed:    true,
+			runIndex:  3,
+			expected:  true,
+		},
+		{
+			name:      "TTL does not apply to the unfinished run",
+			retention: &testv1beta1.LogsRetention{TTLSecondsAfterFinished: 600},
+			finished:  false,
+			runIndex:  3,
+			expected:  false,
+		},
+		{
+			name:      "TTL does not apply to the runs without a finish time",
+			retention: &testv1beta1.LogsRetention{TTLSecondsAfterFinished: 600},
+			finished:  true,
+			runIndex:  0,
+			expected:  false,
+		},
+		{
+			name:      "keepOnFailure keeps the failed runs over the TTL",
+			retention: &testv1beta1.LogsRetention{TTLSecondsAfterFinished: 3600, KeepOnFailure: true},
+			finished:  true,
+			runIndex:  1,
+			expected:  false,
+		},
+		{
+			name:      "keepOnFailure keeps the failed current run over the TTL",
+			retention: &testv1beta1.LogsRetention{TTLSecondsAfterFinished: 600, KeepOnFailure: true},
+			finished:  true,
+			passed:    false,
+			runIndex:  3,
+			expected:  false,
+		},
+		{
+			name:      "deleteOnSuccess deletes the passed current run",
+			retention: &testv1beta1.LogsRetention{DeleteOnSuccess: true},
+			finished:  true,
+			passed:    true,
+			runIndex:  3,
+			expected:  true,
+		},
+		{
+			name:      "deleteOnSuccess keeps the failed current run",
+			retention: &testv1beta1.LogsRetention{DeleteOnSuccess: true},
+			finished:  true,
+			passed:    false,
+			runIndex:  3,
+			expected:  false,
+		},
+		{
+			name:      "deleteOnSuccess keeps the unfinished run",
+			retention: &testv1beta1.LogsRetention{DeleteOnSuccess: true},
+			finished:  false,
+			runIndex:  3,
+			expected:  false,
+		},
+		{
+			name:      "deleteOnSuccess ignores keepRuns for the passed runs",
+			retention: &testv1beta1.LogsRetention{DeleteOnSuccess: true, KeepRuns: 5, KeepOnFailure: true},
+			finished:  true,
+			runIndex:  2,
+			expected:  true,
+		},
+	}
+
+	now := time.Now()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			instance := newLogsRetentionInstance(tt.retention, tt.finished, tt.passed, now)
+			g.Expect(IsLogsRetentionExpired(instance, tt.runIndex, now)).To(Equal(tt.expected))
+		})
+	}
+}
+
+func TestReclaimLogsPVCs(t *testing.T) {
+	newUploader := func(phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "tobiko-s01-second-upload-1",
+				Namespace: "test",
+				Labels:    map[string]string{artifactsUploadLabel: "tobiko"},
+			},
+			Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{{
+					Name: "test-operator-logs",
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+							ClaimName: "test-operator-logs-tobiko-run-1",
+						},
+					},
+				}},
+			},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+	}
+
+	tests := []struct {
+		name           string
+		uploadPhase    corev1.PodPhase
+		uploader       *corev1.Pod
+		currentStep    bool
+		expectedDelete int
+	}{
+		{
+			name:           "no upload",
+			expectedDelete: 1,
+		},
+		{
+			name:           "upload of the current run in progress",
+			uploadPhase:    corev1.PodPending,
+			expectedDelete: 0,
+		},
+		{
+			name:           "running uploader mounts the logs PVC",
+			uploader:       newUploader(corev1.PodRunning),
+			expectedDelete: 0,
+		},
+		{
+			name:           "failed uploader does not hold the logs PVC",
+			uploader:       newUploader(corev1.PodFailed),
+			expectedDelete: 1,
+		}, {
+			name:           "logs PVC of a step that was not rerun",
+			currentStep:    true,
+			expectedDelete: 0,
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := testv1beta1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.Background()
+			now := time.Now()
+
+			instance := newLogsRetentionInstance(
+				&testv1beta1.LogsRetention{TTLSecondsAfterFinished: 3600}, false, false, now)
+			instance.Name = "tobiko"
+			instance.Namespace = "test"
+			instance.UID = types.UID("tobiko-uid")
+			instance.Spec.ArtifactsUpload = &testv1beta1.ArtifactsUpload{SecretName: "s3", Bucket: "logs"}
+			instance.Status.Steps[0].RunIndex = instance.Status.RunIndex
+			instance.Status.Steps[0].ArtifactsUploadPhase = tt.uploadPhase
+			if tt.currentStep {
+				instance.Status.Steps = append(instance.Status.Steps, testv1beta1.WorkflowStepStatus{
+					Phase:    testv1beta1.WorkflowStepSucceeded,
+					LogsPVC:  "test-operator-logs-tobiko-run-1",
+					RunIndex: 1,
+				})
+			}
+
+			// The logs PVC of the run 1 that finished five hours ago
+			logsPVC := &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-operator-logs-tobiko-run-1",
+					Namespace: "test",
+					Labels:    map[string]string{instanceNameLabel: "tobiko", runIndexLabel: "1"},
+					OwnerReferences: []metav1.OwnerReference{
+						{Kind: "Tobiko", Name: "tobiko", UID: instance.UID},
+					},
+				},
+			}
+
+			objects := []client.Object{instance, logsPVC}
+			if tt.uploader != nil {
+				objects = append(objects, tt.uploader)
+			}
+
+			r := &LogsRetentionReconciler{Reconciler: Reconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme).
+					WithObjects(objects...).
+					WithStatusSubresource(instance).
+					Build(),
+			}}
+
+			deleted, _, err := r.ReclaimLogsPVCs(ctx, instance)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(deleted).To(Equal(tt.expectedDelete))
+		})
+	}
+}
@@ -0,0 +1,254 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/openstack-k8s-operators/lib-common/modules/common/condition"
+	testv1beta1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// PendingPodIssue describes why a test pod can not leave the Pending phase
+type PendingPodIssue struct {
+	Reason  condition.Reason
+	Message string
+}
+
+// CheckPendingPods inspects the test pods that are stuck in the Pending phase
+// and reports the reason in the TestPodsStarted condition. Pods that exceeded
+// their pendingTimeout are deleted and their steps are recorded as failed in
+// the status. The function returns true when any pod was deleted.
+func (r *Reconciler) CheckPendingPods(
+	ctx context.Context,
+	instance TestResource,
+	conditions *condition.Conditions,
+	state *WorkflowState,
+) (bool, error) {
+	Log := r.GetLogger(ctx)
+
+	terminated := false
+	var reportedPod string
+	var reportedIssue *PendingPodIssue
+	for stepIdx := range state.StepPhases {
+		pod, ok := state.Pods[stepIdx]
+		if !ok {
+			continue
+		}
+
+		if isPendingTimedOut(&pod) {
+			if reportedIssue == nil || reportedIssue.Reason != testv1beta1.PendingTimeoutReason {
+				reportedPod = pod.Name
+				reportedIssue = &PendingPodIssue{
+					Reason:  testv1beta1.PendingTimeoutReason,
+					Message: pod.Status.Message,
+				}
+			}
+			continue
+		}
+
+		if pod.Status.Phase != corev1.PodPending {
+			continue
+		}
+
+		issue, err := r.GetPendingPodIssue(ctx, &pod)
+		if err != nil {
+			return terminated, err
+		}
+
+		if issue != nil {
+			Log.Info(fmt.Sprintf(InfoStuckPod, pod.Name, issue.Message))
+		}
+
+		pendingTimeout := GetWorkflowStepPendingTimeout(instance, stepIdx)
+		if pendingTimeout <= 0 {
+			if issue != nil && reportedIssue == nil {
+				reportedPod, reportedIssue = pod.Name, issue
+			}
+			continue
+		}
+
+		deadline := pod.GetCreationTimestamp().Add(time.Duration(pendingTimeout) * time.Second)
+		if time.Now().Before(deadline) {
+			if timeoutAt, ok := state.TimeoutAt[stepIdx]; !ok || deadline.Before(timeoutAt) {
+				state.TimeoutAt[stepIdx] = deadline
+			}
+
+			if issue != nil && reportedIssue == nil {
+				reportedPod, reportedIssue = pod.Name, issue
+			}
+			continue
+		}
+
+		message := "the pod stayed in the Pending phase"
+		if issue != nil {
+			message = issue.Message
+		}
+
+		Log.Info(fmt.Sprintf(InfoPendingTimeout, pod.Name))
+		if err := r.DeletePodGracefully(ctx, instance, &pod); err != nil {
+			return terminated, err
+		}
+
+		recordDeletedPod(
+			instance,
+			stepIdx,
+			state.Attempts[stepIdx],
+			&pod,
+			testv1beta1.WorkflowStepFailed,
+			podReasonPendingTimeout,
+			message,
+		)
+		terminated = true
+	}
+
+	switch {
+	case reportedIssue != nil && reportedIssue.Reason == testv1beta1.PendingTimeoutReason:
+		conditions.Set(condition.FalseCondition(
+			testv1beta1.TestPodsStartedCondition,
+			reportedIssue.Reason,
+			condition.SeverityError,
+			testv1beta1.TestPodsStartedPendingTimeoutMessage,
+			reportedPod,
+			reportedIssue.Message))
+	case reportedIssue != nil:
+		conditions.Set(condition.FalseCondition(
+			testv1beta1.TestPodsStartedCondition,
+			reportedIssue.Reason,
+			condition.SeverityWarning,
+			testv1beta1.TestPodsStartedPendingMessage,
+			reportedPod,
+			reportedIssue.Message))
+	case len(state.Pods) > 0:
+		conditions.MarkTrue(testv1beta1.TestPodsStartedCondition, testv1beta1.TestPodsStartedMessage)
+	}
+
+	return terminated, nil
+}
+
+// GetPendingPodIssue inspects the status and the warning events of a pending
+// test pod and returns the reason why the pod can not start. Nil is returned
+// when no problem is detected.
+func (r *Reconciler) GetPendingPodIssue(ctx context.Context, pod *corev1.Pod) (*PendingPodIssue, error) {
+	containerStatuses := slices.Concat(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses)
+	for _, containerStatus := range containerStatuses {
+		waiting := containerStatus.State.Waiting
+		if waiting == nil {
+			continue
+		}
+
+		message := fmt.Sprintf("%s: %s", waiting.Reason, waiting.Message)
+		switch waiting.Reason {
+		case "ErrImagePull", "ImagePullBackOff", "InvalidImageName":
+			return &PendingPodIssue{Reason: testv1beta1.ImagePullFailureReason, Message: message}, nil
+		case "CreateContainerConfigError", "CreateContainerError":
+			return &PendingPodIssue{Reason: testv1beta1.ContainerConfigFailureReason, Message: message}, nil
+		}
+	}
+
+	for _, podCondition := range pod.Status.Conditions {
+		if podCondition.Type != corev1.PodScheduled ||
+			podCondition.Status != corev1.ConditionFalse ||
+			podCondition.Reason != corev1.PodReasonUnschedulable {
+			continue
+		}
+
+		return getSchedulingIssue(podCondition.Message), nil
+	}
+
+	events, err := r.Kclient.CoreV1().Events(pod.Namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.AndSelectors(
+			fields.OneTermEqualSelector("involvedObject.name", pod.Name),
+			fields.OneTermEqualSelector("type", corev1.EventTypeWarning),
+		).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var latestEvent *corev1.Event
+	for i := range events.Items {
+		event := &events.Items[i]
+		if event.InvolvedObject.UID != pod.UID {
+			continue
+		}
+
+		// Scheduling failures of a pod that was scheduled in the meantime
+		// are not relevant anymore
+		if event.Reason == "FailedScheduling" && pod.Spec.NodeName != "" {
+			continue
+		}
+
+		if latestEvent == nil || getEventTime(event).After(getEventTime(latestEvent)) {
+			latestEvent = event
+		}
+	}
+
+	if latestEvent == nil {
+		return nil, nil
+	}
+
+	message := fmt.Sprintf("%s: %s", latestEvent.Reason, latestEvent.Message)
+	switch latestEvent.Reason {
+	case "FailedScheduling":
+		return getSchedulingIssue(message), nil
+	case "FailedMount", "FailedAttachVolume":
+		return &PendingPodIssue{Reason: testv1beta1.VolumeMountFailureReason, Message: message}, nil
+	case "Failed":
+		if strings.Contains(latestEvent.Message, "ImagePull") || strings.Contains(latestEvent.Message, "pull image") {
+			return &PendingPodIssue{Reason: testv1beta1.ImagePullFailureReason, Message: message}, nil
+		}
+	}
+
+	return &PendingPodIssue{Reason: testv1beta1.PodPendingReason, Message: message}, nil
+}
+
+// getSchedulingIssue returns the issue of a pod that can not be scheduled.
+// Pods that wait for an unbound PersistentVolumeClaim are reported
+// separately.
+func getSchedulingIssue(message string) *PendingPodIssue {
+	if strings.Contains(message, "unbound") && strings.Contains(message, "PersistentVolumeClaim") {
+		return &PendingPodIssue{Reason: testv1beta1.VolumeNotBoundReason, Message: message}
+	}
+
+	return &PendingPodIssue{Reason: testv1beta1.SchedulingFailureReason, Message: message}
+}
+
+// getEventTime returns the time when the event was observed for the last time
+func getEventTime(event *corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.GetCreationTimestamp().Time
+	}
+}
+
+// isPendingTimedOut returns true when the pod was deleted because it exceeded
+// its pendingTimeout
+func isPendingTimedOut(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodFailed && pod.Status.Reason == podReasonPendingTimeout
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strconv"
+
+	"github.com/openstack-k8s-operators/lib-common/modules/common/condition"
+	testv1beta1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// GetRunSuffix returns the suffix that distinguishes the pods and PVCs of the
+// runs started by the rerun annotation. The first run does not have any
+// suffix.
+func GetRunSuffix(runIndex int) string {
+	if runIndex == 0 {
+		return ""
+	}
+
+	return podNameRunInfix + strconv.Itoa(runIndex)
+}
+
+// GetRunIndex returns the index of the current run of the instance
+func GetRunIndex(instance interface{}) int {
+	testResource, ok := instance.(TestResource)
+	if !ok {
+		return 0
+	}
+
+	return testResource.GetStatus().RunIndex
+}
+
+// GetStepRunIndex returns the index of the run that executes the workflow
+// step with the given index. It differs from the current run index only for
+// the steps that were not executed again by a run in the failed rerun mode.
+func GetStepRunIndex(instance interface{}, stepNum int) int {
+	testResource, ok := instance.(TestResource)
+	if !ok {
+		return 0
+	}
+
+	status := testResource.GetStatus()
+	runIndex := -1
+	for _, step := range status.Steps {
+		if step.Index == stepNum && step.RunIndex > runIndex {
+			runIndex = step.RunIndex
+		}
+	}
+
+	if runIndex < 0 {
+		return status.RunIndex
+	}
+
+	return runIndex
+}
+
+// getRunIndexLabel returns the run index stored in the runIndex label of a test
+// pod or a logs PVC. Objects without the label belong to the first run.
+func getRunIndexLabel(obj client.Object) int {
+	runIndex, err := strconv.Atoi(obj.GetLabels()[runIndexLabel])
+	if err != nil {
+		return 0
+	}
+
+	return runIndex
+}
+
+// isCurrentRunPod returns true when the pod belongs to the run that executes
+// its workflow step
+func isCurrentRunPod(instance client.Object, pod *corev1.Pod) bool {
+	stepNum, err := strconv.Atoi(pod.Labels[workflowStepLabel])
+	if err != nil {
+		return getRunIndexLabel(pod) == GetRunIndex(instance)
+	}
+
+	return getRunIndexLabel(pod) == GetStepRunIndex(instance, stepNum)
+}
+
+// GetCurrentRunStatus returns the status of the current run of the instance
+// in the form in which it is stored in the run history
+func GetCurrentRunStatus(instance TestResource) testv1beta1.TestRunStatus {
+	status := instance.GetStatus()
+
+	run := testv1beta1.TestRunStatus{
+		Index:        status.RunIndex,
+		RunID:        status.RunID,
+		Steps:        status.Steps,
+		StepsSummary: status.StepsSummary,
+		Results:      status.Results,
+	}
+
+	for _, step := range status.Steps {
+		if step.StartTime != nil && (run.StartTime == nil || step.StartTime.Before(run.StartTime)) {
+			run.StartTime = step.StartTime
+		}
+
+		if step.FinishTime != nil && (run.FinishTime == nil || run.FinishTime.Before(step.FinishTime)) {
+			run.FinishTime = step.FinishTime
+		}
+	}
+
+	if testsPassed := status.Conditions.Get(testv1beta1.TestsPassedCondition); testsPassed != nil {
+		run.Passed = testsPassed.Status == corev1.ConditionTrue
+		if !run.Passed {
+			run.Reason = string(testsPassed.Reason)
+		}
+	}
+
+	return run
+}
+
+// StartNextRun records the current run of the instance in its run history and
+// resets the status so that the workflow is executed again. In the failed
+// rerun mode only the steps whose latest test pod failed are executed again
+// and the skipped steps are evaluated again. The other steps keep their status
+// and test results. The pods and PVCs of the previous run are kept. False is
+// returned when there is nothing to execute.
+func StartNextRun(
+	instance TestResource,
+	runID string,
+	rerunMode string,
+	initialConditions []*condition.Condition,
+) bool {
+	status := instance.GetStatus()
+
+	failedSteps := []testv1beta1.WorkflowStepStatus{}
+	for _, step := range status.Steps {
+		if step.RunIndex != GetStepRunIndex(instance, step.Index) {
+			continue
+		}
+
+		if step.Phase == testv1beta1.WorkflowStepFailed || step.Phase == testv1beta1.WorkflowStepTimedOut {
+			failedSteps = append(failedSteps, step)
+		}
+	}
+
+	if rerunMode == testv1beta1.RerunModeFailed && len(failedSteps) == 0 {
+		status.RunID = runID
+		return false
+	}
+
+	status.Runs = append(status.Runs, GetCurrentRunStatus(instance))
+	if len(status.Runs) > maxRunHistory {
+		status.Runs = status.Runs[len(status.Runs)-maxRunHistory:]
+	}
+
+	status.RunIndex++
+	status.RunID = runID
+
+	if rerunMode == testv1beta1.RerunModeFailed {
+		steps := make([]testv1beta1.WorkflowStepStatus, 0, len(status.Steps)+len(failedSteps))
+		for _, step := range status.Steps {
+			// Skipped steps have no test pod to keep
+			if step.Phase == testv1beta1.WorkflowStepSkipped && step.PodName == "" {
+				step.RunIndex = status.RunIndex
+				step.Phase = testv1beta1.WorkflowStepPending
+			}
+			steps = append(steps, step)
+		}
+		for _, failedStep := range failedSteps {
+			steps = append(steps, testv1beta1.WorkflowStepStatus{
+				Name:      failedStep.Name,
+				Index:     failedStep.Index,
+				Phase:     testv1beta1.WorkflowStepPending,
+				DependsOn: failedStep.DependsOn,
+				RunIndex:  status.RunIndex,
+			})
+		}
+		status.Steps = steps
+	} else {
+		status.Steps = nil
+		status.StepsSummary = nil
+		status.Results = nil
+	}
+
+	cl := condition.CreateList(initialConditions...)
+	instance.GetConditions().Init(&cl)
+
+	return true
+}
@@ -0,0 +1,426 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	testOperatorSemaphorePrefix = "test-operator-semaphore-"
+	testOperatorConfigName      = "test-operator-config"
+	concurrencyGroupsConfigKey  = "concurrency-groups"
+	defaultConcurrencyLimit     = 1
+)
+
+// semaphoreSlot is a slot of a concurrency group. Each slot is stored in its
+// own Lease in the LockNamespace and is held for a single test pod.
+type semaphoreSlot struct {
+	index  int
+	lock   TestLock
+	record *LockRecord
+}
+
+// GetConcurrencyGroup returns the concurrency group the instance belongs to.
+// An empty string is returned when the instance does not belong to any group.
+func GetConcurrencyGroup(instance client.Object) string {
+	if testResource, ok := instance.(TestResource); ok {
+		return testResource.GetConcurrencyGroup()
+	}
+
+	return ""
+}
+
+// GetLockName returns the name of the lock the instance waits for. It is
+// either the test-operator-lock, the lock shared across namespaces or the
+// semaphore of its concurrency group.
+func GetLockName(instance client.Object) string {
+	if group := GetConcurrencyGroup(instance); group != "" {
+		return GetSemaphoreName(group)
+	}
+
+	if lockName := GetSharedLockNameFromStatus(instance); lockName != "" {
+		return lockName
+	}
+
+	return testOperatorLockName
+}
+
+// GetSemaphoreName returns the name of the semaphore of the given concurrency
+// group. The Leases holding the slots of the group are named after it.
+func GetSemaphoreName(group string) string {
+	return testOperatorSemaphorePrefix + group
+}
+
+// GetSemaphoreSlotName returns the name of the Lease holding the slot of the
+// given concurrency group with the given index
+func GetSemaphoreSlotName(group string, index int) string {
+	return fmt.Sprintf("%s-%d", GetSemaphoreName(group), index)
+}
+
+// parseSemaphoreSlotName returns the concurrency group and the index of the
+// slot stored in the Lease with the given name. False is returned when the
+// Lease does not hold a slot of any concurrency group.
+func parseSemaphoreSlotName(name string) (string, int, bool) {
+	slotName, ok := strings.CutPrefix(name, testOperatorSemaphorePrefix)
+	if !ok {
+		return "", 0, false
+	}
+
+	separator := strings.LastIndex(slotName, "-")
+	if separator <= 0 {
+		return "", 0, false
+	}
+
+	index, err := strconv.Atoi(slotName[separator+1:])
+	if err != nil || index < 0 {
+		return "", 0, false
+	}
+
+	return slotName[:separator], index, true
+}
+
+// GetConcurrencyGroupLimit returns how many test pods of the given concurrency
+// group can run at the same time. The limits are read from the
+// concurrency-groups key of the test-operator-config ConfigMap in the
+// LockNamespace, e.g.:
+//
+//	concurrency-groups: |
+//	  default: 3
+//	  tobiko-disruptive: 1
+//
+// Groups without a configured limit allow a single test pod at a time.
+func (r *Reconciler) GetConcurrencyGroupLimit(ctx context.Context, group string) (int, error) {
+	if r.LockNamespace == "" {
+		return 0, ErrLockNamespaceNotSet
+	}
+
+	cm := &corev1.ConfigMap{}
+	objectKey := client.ObjectKey{Namespace: r.LockNamespace, Name: testOperatorConfigName}
+	if err := r.Client.Get(ctx, objectKey, cm); err != nil {
+		if k8s_errors.IsNotFound(err) {
+			return defaultConcurrencyLimit, nil
+		}
+		return 0, err
+	}
+
+	value, ok := cm.Data[concurrencyGroupsConfigKey]
+	if !ok {
+		return defaultConcurrencyLimit, nil
+	}
+
+	limits := map[string]int{}
+	if err := yaml.Unmarshal([]byte(value), &limits); err != nil {
+		return 0, fmt.Errorf("can not parse %s in %s: %w", concurrencyGroupsConfigKey, testOperatorConfigName, err)
+	}
+
+	if limit, ok := limits[group]; ok && limit > 0 {
+		return limit, nil
+	}
+
+	return defaultConcurrencyLimit, nil
+}
+
+// AcquireSemaphore attempts to acquire a slot of the given concurrency group
+// for the test pod with the given name. The slot is granted when the group
+// has a free slot for every instance waiting for the group ahead of the
+// instance in the queue. Slots whose holders are gone or did not renew their
+// lease in time are free. The instance acquires a slot for each test pod, so
+// the limit of the group applies to the number of running test pods.
+func (r *Reconciler) AcquireSemaphore(
+	ctx context.Context,
+	instance client.Object,
+	group string,
+	podName string,
+) (bool, error) {
+	limit, err := r.GetConcurrencyGroupLimit(ctx, group)
+	if err != nil {
+		return false, err
+	}
+
+	slots, err := r.getSemaphoreSlots(ctx, group)
+	if err != nil {
+		return false, err
+	}
+
+	usedSlots := 0
+	staleSlots := map[int]semaphoreSlot{}
+	staleReasons := map[int]string{}
+	for _, slot := range slots {
+		if slot.record.IsHeldBy(instance) && slot.record.HolderPod == podName && !slot.record.IsExpired() {
+			setQueuePosition(instance, 0)
+			return true, nil
+		}
+
+		staleReason, err := r.GetStaleLockReason(ctx, slot.record)
+		if err != nil {
+			return false, err
+		}
+
+		switch {
+		case staleReason == "":
+			usedSlots++
+		case slot.index >= limit:
+			// The slots above a lowered limit are not used anymore
+			if err := slot.lock.Delete(ctx); err != nil && !k8s_errors.IsNotFound(err) && !k8s_errors.IsConflict(err) {
+				return false, err
+			}
+		default:
+			staleSlots[slot.index] = slot
+			staleReasons[slot.index] = staleReason
+		}
+	}
+
+	position, err := r.GetQueuePosition(ctx, instance, "")
+	if err != nil {
+		return false, err
+	}
+
+	if int(position) > limit-usedSlots {
+		setQueuePosition(instance, position)
+		return false, nil
+	}
+
+	existingSlots := map[int]bool{}
+	for _, slot := range slots {
+		existingSlots[slot.index] = true
+	}
+
+	record := r.newLockRecord(instance, nil)
+	record.HolderPod = podName
+	for index := range limit {
+		if slot, ok := staleSlots[index]; ok {
+			newRecord := r.newLockRecord(instance, slot.record)
+			newRecord.HolderPod = podName
+			lockAcquired, err := r.takeOverLock(ctx, instance, slot.lock, slot.record, newRecord, staleReasons[index])
+			if err != nil {
+				return false, err
+			}
+
+			if lockAcquired {
+				setQueuePosition(instance, 0)
+				return true, nil
+			}
+			continue
+		}
+
+		if existingSlots[index] {
+			continue
+		}
+
+		// The creation fails when another instance took the slot in the
+		// meantime
+		lock := NewLeaseLock(r.Client, r.Scheme, r.LockNamespace, GetSemaphoreSlotName(group, index))
+		if err := lock.Create(ctx, instance, record); err != nil {
+			if k8s_errors.IsAlreadyExists(err) {
+				continue
+			}
+			return false, err
+		}
+
+		setQueuePosition(instance, 0)
+		return true, nil
+	}
+
+	setQueuePosition(instance, position)
+	return false, nil
+}
+
+// HoldsSemaphore returns true when the instance holds a slot of the given
+// concurrency group for the test pod with the given name. Any slot held by
+// the instance counts when the name is empty.
+func (r *Reconciler) HoldsSemaphore(
+	ctx context.Context,
+	instance client.Object,
+	group string,
+	podName string,
+) (bool, error) {
+	slots, err := r.getSemaphoreSlots(ctx, group)
+	if err != nil {
+		return false, err
+	}
+
+	for _, slot := range slots {
+		if slot.record.IsHeldBy(instance) && !slot.record.IsExpired() &&
+			(podName == "" || slot.record.HolderPod == podName) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// ReleaseSemaphore releases all slots of the given concurrency group held by
+// the instance and notifies the instances waiting for the group. The Leases
+// of the released slots are deleted.
+func (r *Reconciler) ReleaseSemaphore(
+	ctx context.Context,
+	instance client.Object,
+	group string,
+) (bool, error) {
+	slots, err := r.getSemaphoreSlots(ctx, group)
+	if err != nil {
+		return false, err
+	}
+
+	heldSlots := []semaphoreSlot{}
+	for _, slot := range slots {
+		if slot.record.IsHeldBy(instance) {
+			heldSlots = append(heldSlots, slot)
+		}
+	}
+
+	return r.releaseSemaphoreSlots(ctx, instance, heldSlots)
+}
+
+// RefreshSemaphore releases the slots of the given concurrency group held for
+// the test pods of the instance that finished. The leases of the other slots
+// held by the instance are renewed when they were renewed more than
+// LockHeartbeatInterval ago. The slots of the test pods that are not known
+// yet are kept, they were acquired for pods that are being created.
+func (r *Reconciler) RefreshSemaphore(
+	ctx context.Context,
+	instance client.Object,
+	group string,
+	state *WorkflowState,
+) error {
+	slots, err := r.getSemaphoreSlots(ctx, group)
+	if err != nil {
+		return err
+	}
+
+	finishedPods := map[string]bool{}
+	for _, pod := range state.Pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			finishedPods[pod.Name] = true
+		}
+	}
+
+	finishedSlots := []semaphoreSlot{}
+	for _, slot := range slots {
+		if !slot.record.IsHeldBy(instance) {
+			continue
+		}
+
+		if finishedPods[slot.record.HolderPod] {
+			finishedSlots = append(finishedSlots, slot)
+			continue
+		}
+
+		if slot.record.IsExpired() || time.Since(slot.record.RenewTime) < LockHeartbeatInterval {
+			continue
+		}
+
+		slot.record.RenewTime = time.Now()
+		slot.record.LeaseDuration = LockStaleTimeout
+
+		// The update fails when the slot was taken over in the meantime
+		if err := client.IgnoreNotFound(ignoreConflict(slot.lock.Update(ctx, instance, *slot.record))); err != nil {
+			return err
+		}
+	}
+
+	_, err = r.releaseSemaphoreSlots(ctx, instance, finishedSlots)
+	return err
+}
+
+// releaseSemaphoreSlots deletes the given slots and notifies the instances
+// waiting for the concurrency group. It returns false when a slot changed in
+// the meantime and the release has to be retried.
+func (r *Reconciler) releaseSemaphoreSlots(
+	ctx context.Context,
+	instance client.Object,
+	slots []semaphoreSlot,
+) (bool, error) {
+	Log := r.GetLogger(ctx)
+
+	if len(slots) == 0 {
+		return true, nil
+	}
+
+	for _, slot := range slots {
+		err := slot.lock.Delete(ctx)
+		if k8s_errors.IsConflict(err) {
+			return false, nil
+		} else if err != nil && !k8s_errors.IsNotFound(err) {
+			return false, err
+		}
+	}
+
+	if err := r.NotifyLockWaiters(ctx, instance); err != nil {
+		Log.Error(err, "Can not notify instances waiting for the semaphore")
+	}
+
+	return true, nil
+}
+
+// getSemaphoreSlots returns the slots of the given concurrency group stored
+// in the LockNamespace ordered by their index
+func (r *Reconciler) getSemaphoreSlots(ctx context.Context, group string) ([]semaphoreSlot, error) {
+	if r.LockNamespace == "" {
+		return nil, ErrLockNamespaceNotSet
+	}
+
+	leases := &coordinationv1.LeaseList{}
+	if err := r.Client.List(ctx, leases, client.InNamespace(r.LockNamespace)); err != nil {
+		return nil, err
+	}
+
+	slots := []semaphoreSlot{}
+	for _, lease := range leases.Items {
+		slotGroup, index, ok := parseSemaphoreSlotName(lease.Name)
+		if !ok || slotGroup != group {
+			continue
+		}
+
+		lock := NewLeaseLock(r.Client, r.Scheme, r.LockNamespace, lease.Name)
+		record, err := lock.Get(ctx)
+		if k8s_errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		slots = append(slots, semaphoreSlot{index: index, lock: lock, record: record})
+	}
+
+	slices.SortFunc(slots, func(a, b semaphoreSlot) int {
+		return a.index - b.index
+	})
+
+	return slots, nil
+}
+
+// ignoreConflict returns nil when the error is a conflict error
+func ignoreConflict(err error) error {
+	if k8s_errors.IsConflict(err) {
+		return nil
+	}
+
+	return err
+}
@@ -28,9 +28,14 @@ import (
 	"github.com/openstack-k8s-operators/lib-common/modules/common/util"
 	testv1beta1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
 	"github.com/openstack-k8s-operators/test-operator/internal/tempest"
+	coordinationv1 "k8s.io/api/coordination/v1"
 	corev1 "k8s.io/api/core/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
 
 // TempestReconciler reconciles a Tempest object
@@ -46,12 +51,18 @@ func (r *TempestReconciler) GetLogger(ctx context.Context) logr.Logger {
 // +kubebuilder:rbac:groups=test.openstack.org,resources=tempests,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=test.openstack.org,resources=tempests/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=test.openstack.org,resources=tempests/finalizers,verbs=update;patch
+// +kubebuilder:rbac:groups=test.openstack.org,resources=tempests;tobikoes;ansibletests;horizontests,verbs=get;list;watch
 // +kubebuilder:rbac:groups=k8s.cni.cncf.io,resources=network-attachment-definitions,verbs=get;list;watch
 // +kubebuilder:rbac:groups="security.openshift.io",resourceNames=anyuid;privileged;nonroot;nonroot-v2,resources=securitycontextconstraints,verbs=use
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete;
 // +kubebuilder:rbac:groups="",resources=pods,verbs=create;delete;get;list;patch;update;watch
+// +kubebuilder:rbac:groups="",resources=pods/status,verbs=get;patch;update
+// +kubebuilder:rbac:groups="",resources=events,verbs=get;list;create;patch
+// +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=pods/log,verbs=get
 // +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;create;update;watch;patch;delete
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile - Tempest
 func (r *TempestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -64,17 +75,20 @@ func (r *TempestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		NeedsFinalizer:          true,
 		SupportsWorkflow:        true,
 
-		GenerateServiceConfigMaps: func(ctx context.Context, helper *helper.Helper, _ map[string]string, instance *testv1beta1.Tempest, workflowStep int) error {
-			return r.generateServiceConfigMaps(ctx, helper, instance, workflowStep)
+		GenerateServiceConfigMaps: func(ctx context.Context, helper *helper.Helper, _ map[string]string, instance *testv1beta1.Tempest, workflowStep int, attempt int) error {
+			return r.generateServiceConfigMaps(ctx, helper, instance, workflowStep, attempt)
 		},
 
-		BuildPod: func(ctx context.Context, instance *testv1beta1.Tempest, labels, annotations map[string]string, workflowStepIndex int, pvcIndex int) (*corev1.Pod, error) {
-			return r.buildTempestPod(ctx, instance, labels, annotations, workflowStepIndex, pvcIndex)
+		BuildPod: func(ctx context.Context, instance *testv1beta1.Tempest, labels, annotations map[string]string, workflowStepIndex int, attempt int, pvcIndex int) (*corev1.Pod, error) {
+			return r.buildTempestPod(ctx, instance, labels, annotations, workflowStepIndex, attempt, pvcIndex)
 		},
 
 		GetInitialConditions: func() []*condition.Condition {
 			return []*condition.Condition{
 				condition.UnknownCondition(condition.ReadyCondition, condition.InitReason, condition.ReadyInitMessage),
+				condition.UnknownCondition(testv1beta1.ExecutionCompletedCondition, condition.InitReason, testv1beta1.ExecutionCompletedInitMessage),
+				condition.UnknownCondition(testv1beta1.TestsPassedCondition, condition.InitReason, testv1beta1.TestsPassedInitMessage),
+				condition.UnknownCondition(testv1beta1.TestPodsStartedCondition, condition.InitReason, testv1beta1.TestPodsStartedInitMessage),
 				condition.UnknownCondition(condition.InputReadyCondition, condition.InitReason, condition.InputReadyInitMessage),
 				condition.UnknownCondition(condition.ServiceConfigReadyCondition, condition.InitReason, condition.ServiceConfigReadyInitMessage),
 				condition.UnknownCondition(condition.DeploymentReadyCondition, condition.InitReason, condition.DeploymentReadyInitMessage),
@@ -89,6 +103,12 @@ func (r *TempestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			return r.ValidateSecretWithKeys(ctx, instance, instance.Spec.SSHKeySecretName, []string{})
 		},
 
+		ParseTestResults: tempest.ParseResults,
+
+		GetLogsDirName: func(instance *testv1beta1.Tempest, workflowStepIndex int, attempt int) string {
+			return r.GetPodName(instance, workflowStepIndex, attempt)
+		},
+
 		GetSpec: func(instance *testv1beta1.Tempest) interface{} {
 			return &instance.Spec
 		},
@@ -101,6 +121,10 @@ func (r *TempestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			return len(instance.Spec.Workflow)
 		},
 
+		GetWorkflowOptions: func(instance *testv1beta1.Tempest) testv1beta1.WorkflowOptions {
+			return instance.Spec.WorkflowOptions
+		},
+
 		GetParallel: func(instance *testv1beta1.Tempest) bool {
 			return instance.Spec.Parallel
 		},
@@ -122,6 +146,7 @@ func (r *TempestReconciler) buildTempestPod(
 	instance *testv1beta1.Tempest,
 	labels, annotations map[string]string,
 	workflowStepIndex int,
+	attempt int,
 	pvcIndex int,
 ) (*corev1.Pod, error) {
 	mountSSHKey := len(instance.Spec.SSHKeySecretName) != 0
@@ -129,7 +154,7 @@ func (r *TempestReconciler) buildTempestPod(
 
 	customDataConfigMapName := GetCustomDataConfigMapName(instance, workflowStepIndex)
 	envVarsConfigMapName := GetEnvVarsConfigMapName(instance, workflowStepIndex)
-	podName := r.GetPodName(instance, workflowStepIndex)
+	podName := r.GetPodName(instance, workflowStepIndex, attempt)
 	logsPVCName := r.GetPVCLogsName(instance, pvcIndex)
 
 	containerImage, err := r.GetContainerImage(ctx, instance)
@@ -158,6 +183,13 @@ func (r *TempestReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&corev1.Pod{}).
 		Owns(&corev1.Secret{}).
 		Owns(&corev1.ConfigMap{}).
+		Watches(
+			&coordinationv1.Lease{},
+			handler.EnqueueRequestsFromMapFunc(r.LockQueueRequests(func() client.ObjectList {
+				return &testv1beta1.TempestList{}
+			})),
+			builder.WithPredicates(predicate.NewPredicateFuncs(IsTestOperatorLease)),
+		).
 		Complete(r)
 }
 
@@ -165,6 +197,7 @@ func (r *TempestReconciler) setTempestConfigVars(envVars map[string]string,
 	customData map[string]string,
 	instance *testv1beta1.Tempest,
 	workflowStepIndex int,
+	attempt int,
 ) {
 	tRun := instance.Spec.TempestRun
 
@@ -200,7 +233,7 @@ func (r *TempestReconciler) setTempestConfigVars(envVars map[string]string,
 		})
 	}
 
-	envVars["TEMPEST_WORKFLOW_STEP_DIR_NAME"] = r.GetPodName(instance, workflowStepIndex)
+	envVars["TEMPEST_WORKFLOW_STEP_DIR_NAME"] = r.GetPodName(instance, workflowStepIndex, attempt)
 
 	for _, img := range tRun.ExtraImages {
 		SetDictEnvVar(envVars, map[string]string{
@@ -289,6 +322,7 @@ func (r *TempestReconciler) generateServiceConfigMaps(
 	h *helper.Helper,
 	instance *testv1beta1.Tempest,
 	workflowStepIndex int,
+	attempt int,
 ) error {
 	// Create/update configmaps from template
 	cmLabels := labels.GetLabels(instance, labels.GetGroupLabel(tempest.ServiceName), map[string]string{})
@@ -307,7 +341,7 @@ func (r *TempestReconciler) generateServiceConfigMaps(
 	customData := make(map[string]string)
 	envVars := make(map[string]string)
 
-	r.setTempestConfigVars(envVars, customData, instance, workflowStepIndex)
+	r.setTempestConfigVars(envVars, customData, instance, workflowStepIndex, attempt)
 	r.setTempestconfConfigVars(envVars, customData, instance)
 
 	for key, data := range instance.Spec.ConfigOverwrite {
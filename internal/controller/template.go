@@ -0,0 +1,159 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+
+	testv1beta1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// templateKinds maps the kinds that can be created from a TestTemplate to
+// their list types
+var templateKinds = map[string]func() client.ObjectList{
+	"Tempest":     func() client.ObjectList { return &testv1beta1.TempestList{} },
+	"Tobiko":      func() client.ObjectList { return &testv1beta1.TobikoList{} },
+	"AnsibleTest": func() client.ObjectList { return &testv1beta1.AnsibleTestList{} },
+	"HorizonTest": func() client.ObjectList { return &testv1beta1.HorizonTestList{} },
+}
+
+// ownedTest is a test CR created from a TestTemplate
+type ownedTest struct {
+	kind     string
+	instance TestResource
+}
+
+// BuildTestFromTemplate returns the test CR of the given name created from the
+// template and controlled by the owner. The spec is passed to the test CR as
+// it is so that it is validated and defaulted by the API server.
+func BuildTestFromTemplate(
+	owner client.Object,
+	scheme *runtime.Scheme,
+	template testv1beta1.TestTemplate,
+	name string,
+	labels map[string]string,
+	annotations map[string]string,
+) (*unstructured.Unstructured, error) {
+	spec := map[string]interface{}{}
+	if len(template.Spec.Raw) > 0 {
+		if err := json.Unmarshal(template.Spec.Raw, &spec); err != nil {
+			return nil, err
+		}
+	}
+
+	testLabels := map[string]string{}
+	for key, value := range template.Labels {
+		testLabels[key] = value
+	}
+	for key, value := range labels {
+		testLabels[key] = value
+	}
+
+	testAnnotations := map[string]string{}
+	for key, value := range template.Annotations {
+		testAnnotations[key] = value
+	}
+	for key, value := range annotations {
+		testAnnotations[key] = value
+	}
+
+	test := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	test.SetAPIVersion(testv1beta1.GroupVersion.String())
+	test.SetKind(template.Kind)
+	test.SetNamespace(owner.GetNamespace())
+	test.SetName(name)
+	test.SetLabels(testLabels)
+	test.SetAnnotations(testAnnotations)
+
+	if err := controllerutil.SetControllerReference(owner, test, scheme); err != nil {
+		return nil, err
+	}
+
+	return test, nil
+}
+
+// GetTemplateFromRef returns a TestTemplate holding the spec of the referenced
+// test CR
+func GetTemplateFromRef(
+	ctx context.Context,
+	c client.Client,
+	namespace string,
+	ref testv1beta1.TestTemplateRef,
+) (testv1beta1.TestTemplate, error) {
+	test := &unstructured.Unstructured{}
+	test.SetAPIVersion(testv1beta1.GroupVersion.String())
+	test.SetKind(ref.Kind)
+
+	err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ref.Name}, test)
+	if err != nil {
+		return testv1beta1.TestTemplate{}, err
+	}
+
+	spec, err := json.Marshal(test.Object["spec"])
+	if err != nil {
+		return testv1beta1.TestTemplate{}, err
+	}
+
+	return testv1beta1.TestTemplate{
+		Kind: ref.Kind,
+		Spec: runtime.RawExtension{Raw: spec},
+	}, nil
+}
+
+// GetOwnedTests returns the test CRs of all kinds that carry the given label
+// and that are controlled by the owner
+func GetOwnedTests(
+	ctx context.Context,
+	c client.Client,
+	owner client.Object,
+	labels map[string]string,
+) ([]ownedTest, error) {
+	tests := []ownedTest{}
+	for kind, newList := range templateKinds {
+		list := newList()
+		err := c.List(ctx, list,
+			client.InNamespace(owner.GetNamespace()),
+			client.MatchingLabels(labels),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range items {
+			testResource, ok := item.(TestResource)
+			if !ok || !metav1.IsControlledBy(testResource, owner) {
+				continue
+			}
+
+			tests = append(tests, ownedTest{kind: kind, instance: testResource})
+		}
+	}
+
+	return tests, nil
+}
@@ -0,0 +1,471 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/openstack-k8s-operators/lib-common/modules/common/condition"
+	"github.com/openstack-k8s-operators/lib-common/modules/common/helper"
+	testv1beta1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
+	testutil "github.com/openstack-k8s-operators/test-operator/internal/util"
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	testScheduleLabel         = "testSchedule"
+	scheduledAtAnnotation     = "test.openstack.org/scheduled-at"
+	eventReasonTestScheduled  = "TestScheduled"
+	eventReasonTestReplaced   = "TestReplaced"
+	eventReasonTestSkipped    = "TestSkipped"
+	eventReasonTestNotCreated = "TestNotCreated"
+)
+
+// scheduledRun is a test CR created by a TestSchedule
+type scheduledRun struct {
+	kind        string
+	instance    TestResource
+	scheduledAt time.Time
+}
+
+// IsFinished returns true when the test CR finished its execution
+func (s *scheduledRun) IsFinished() bool {
+	return IsTestingFinished(s.instance)
+}
+
+// TestScheduleReconciler reconciles a TestSchedule object
+type TestScheduleReconciler struct {
+	Reconciler
+}
+
+// GetLogger returns a logger object with a prefix of "controller.name" and additional controller context fields
+func (r *TestScheduleReconciler) GetLogger(ctx context.Context) logr.Logger {
+	return log.FromContext(ctx).WithName("Controllers").WithName("TestSchedule")
+}
+
+// +kubebuilder:rbac:groups=test.openstack.org,resources=testschedules,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=test.openstack.org,resources=testschedules/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=test.openstack.org,resources=testschedules/finalizers,verbs=update;patch
+// +kubebuilder:rbac:groups=test.openstack.org,resources=tempests;tobikoes;ansibletests;horizontests,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups="",resources=events,verbs=get;list;create;patch
+
+// Reconcile - TestSchedule
+func (r *TestScheduleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, _err error) {
+	Log := r.GetLogger(ctx)
+
+	instance := &testv1beta1.TestSchedule{}
+	err := r.Client.Get(ctx, req.NamespacedName, instance)
+	if err != nil {
+		if k8s_errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	// Create a helper
+	helper, err := helper.NewHelper(
+		instance,
+		r.Client,
+		r.Kclient,
+		r.Scheme,
+		r.Log,
+	)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	isNewInstance := len(instance.Status.Conditions) == 0
+	if isNewInstance {
+		instance.Status.Conditions = condition.Conditions{}
+	}
+
+	// Save a copy of the conditions so that we can restore the LastTransitionTime
+	// when a condition's state doesn't change.
+	savedConditions := instance.Status.Conditions.DeepCopy()
+
+	// Always patch the instance status when exiting this function so we
+	// can persist any changes.
+	defer func() {
+		// Don't update the status, if reconciler Panics
+		if r := recover(); r != nil {
+			Log.Info(fmt.Sprintf("panic during reconcile %v\n", r))
+			panic(r)
+		}
+		condition.RestoreLastTransitionTimes(&instance.Status.Conditions, savedConditions)
+		err := helper.PatchInstance(ctx, instance)
+		if err != nil {
+			_err = err
+			return
+		}
+	}()
+
+	if isNewInstance {
+		cl := condition.CreateList(
+			condition.UnknownCondition(condition.ReadyCondition, condition.InitReason, condition.ReadyInitMessage),
+		)
+		instance.Status.Conditions.Init(&cl)
+
+		// Register overall status immediately to have an early feedback
+		// e.g. in the cli
+		return ctrl.Result{}, nil
+	}
+	instance.Status.ObservedGeneration = instance.Generation
+
+	schedule, err := testutil.ParseSchedule(instance.Spec.Schedule)
+	if err != nil {
+		instance.Status.Conditions.MarkFalse(
+			condition.ReadyCondition,
+			condition.ErrorReason,
+			condition.SeverityError,
+			testv1beta1.TestScheduleInvalidMessage,
+			instance.Spec.Schedule,
+			err.Error(),
+		)
+		instance.Status.NextScheduleTime = nil
+
+		// The schedule is parsed again once the spec changes
+		return ctrl.Result{}, nil
+	}
+
+	runs, err := r.getScheduledRuns(ctx, instance)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	runs, err = r.pruneScheduledRuns(ctx, instance, runs)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	r.updateRunStatus(instance, runs)
+
+	now := time.Now()
+	scheduledAt, nextScheduleAt := r.getScheduleTimes(instance, schedule, now)
+	if !nextScheduleAt.IsZero() {
+		instance.Status.NextScheduleTime = &metav1.Time{Time: nextScheduleAt}
+	} else {
+		instance.Status.NextScheduleTime = nil
+	}
+
+	if !scheduledAt.IsZero() {
+		result, err := r.startScheduledRun(ctx, instance, runs, scheduledAt)
+		if err != nil || !result.IsZero() {
+			return result, err
+		}
+	}
+
+	if nextScheduleAt.IsZero() {
+		// The schedule never matches (e.g. "0 0 30 2 *")
+		instance.Status.Conditions.MarkTrue(condition.ReadyCondition, condition.ReadyMessage)
+		return ctrl.Result{}, nil
+	}
+
+	instance.Status.Conditions.MarkTrue(
+		condition.ReadyCondition,
+		testv1beta1.TestScheduleMessage,
+		nextScheduleAt.Format(time.RFC3339),
+	)
+
+	return ctrl.Result{RequeueAfter: time.Until(nextScheduleAt)}, nil
+}
+
+// getScheduleTimes returns the most recent scheduled time that was not started
+// yet and the next scheduled time. The zero time is returned for the first
+// value when there is no run to start. Runs that missed the starting deadline
+// are skipped.
+func (r *TestScheduleReconciler) getScheduleTimes(
+	instance *testv1beta1.TestSchedule,
+	schedule *testutil.Schedule,
+	now time.Time,
+) (time.Time, time.Time) {
+	earliest := instance.CreationTimestamp.Time
+	if instance.Status.LastScheduleTime != nil {
+		earliest = instance.Status.LastScheduleTime.Time
+	}
+
+	if deadline := instance.Spec.StartingDeadlineSeconds; deadline != nil {
+		deadlineStart := now.Add(-time.Duration(*deadline) * time.Second)
+		if deadlineStart.After(earliest) {
+			earliest = deadlineStart
+		}
+	}
+
+	scheduledAt := time.Time{}
+	for t := schedule.Next(earliest); !t.IsZero() && !t.After(now); t = schedule.Next(t) {
+		scheduledAt = t
+	}
+
+	return scheduledAt, schedule.Next(now)
+}
+
+// startScheduledRun creates the test CR for the given scheduled time while
+// respecting the concurrency policy of the schedule
+func (r *TestScheduleReconciler) startScheduledRun(
+	ctx context.Context,
+	instance *testv1beta1.TestSchedule,
+	runs []scheduledRun,
+	scheduledAt time.Time,
+) (ctrl.Result, error) {
+	Log := r.GetLogger(ctx)
+
+	activeRuns := []scheduledRun{}
+	for _, run := range runs {
+		if !run.IsFinished() {
+			activeRuns = append(activeRuns, run)
+		}
+	}
+
+	if len(activeRuns) > 0 {
+		switch instance.Spec.ConcurrencyPolicy {
+		case testv1beta1.ForbidConcurrent:
+			// The run is skipped like the run of a CronJob with the Forbid
+			// policy. The next run is started at its scheduled time.
+			Log.Info(fmt.Sprintf("Skipping run scheduled at %s: %d runs did not finish yet",
+				scheduledAt.Format(time.RFC3339), len(activeRuns)))
+			r.Recorder.Eventf(
+				instance,
+				corev1.EventTypeNormal,
+				eventReasonTestSkipped,
+				"Skipped run scheduled at %s because %s %s did not finish yet",
+				scheduledAt.Format(time.RFC3339),
+				activeRuns[0].kind,
+				activeRuns[0].instance.GetName(),
+			)
+			instance.Status.LastScheduleTime = &metav1.Time{Time: scheduledAt}
+			return ctrl.Result{}, nil
+
+		case testv1beta1.ReplaceConcurrent:
+			for _, run := range activeRuns {
+				err := r.Client.Delete(ctx, run.instance, client.PropagationPolicy(metav1.DeletePropagationBackground))
+				if err != nil && !k8s_errors.IsNotFound(err) {
+					return ctrl.Result{}, err
+				}
+
+				Log.Info(fmt.Sprintf("Deleted unfinished %s %s", run.kind, run.instance.GetName()))
+				r.Recorder.Eventf(
+					instance,
+					corev1.EventTypeNormal,
+					eventReasonTestReplaced,
+					"Deleted unfinished %s %s",
+					run.kind,
+					run.instance.GetName(),
+				)
+			}
+		}
+	}
+
+	child, err := r.buildScheduledRun(instance, scheduledAt)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	err = r.Client.Create(ctx, child)
+	if err != nil && !k8s_errors.IsAlreadyExists(err) {
+		instance.Status.Conditions.MarkFalse(
+			condition.ReadyCondition,
+			condition.ErrorReason,
+			condition.SeverityWarning,
+			testv1beta1.TestScheduleCreateErrorMessage,
+			err.Error(),
+		)
+		r.Recorder.Eventf(
+			instance,
+			corev1.EventTypeWarning,
+			eventReasonTestNotCreated,
+			"Can not create %s %s: %s",
+			child.GetKind(),
+			child.GetName(),
+			err.Error(),
+		)
+		return ctrl.Result{}, err
+	}
+
+	if err == nil {
+		Log.Info(fmt.Sprintf("Created %s %s", child.GetKind(), child.GetName()))
+		r.Recorder.Eventf(
+			instance,
+			corev1.EventTypeNormal,
+			eventReasonTestScheduled,
+			"Created %s %s",
+			child.GetKind(),
+			child.GetName(),
+		)
+		instance.Status.Active = append(instance.Status.Active, corev1.ObjectReference{
+			APIVersion: child.GetAPIVersion(),
+			Kind:       child.GetKind(),
+			Namespace:  child.GetNamespace(),
+			Name:       child.GetName(),
+			UID:        child.GetUID(),
+		})
+	}
+
+	instance.Status.LastScheduleTime = &metav1.Time{Time: scheduledAt}
+
+	return ctrl.Result{}, nil
+}
+
+// buildScheduledRun returns the test CR created from the template for the
+// given scheduled time
+func (r *TestScheduleReconciler) buildScheduledRun(
+	instance *testv1beta1.TestSchedule,
+	scheduledAt time.Time,
+) (*unstructured.Unstructured, error) {
+	return BuildTestFromTemplate(
+		instance,
+		r.Scheme,
+		instance.Spec.Template,
+		GetScheduledRunName(instance.Name, scheduledAt),
+		map[string]string{testScheduleLabel: instance.Name},
+		map[string]string{scheduledAtAnnotation: scheduledAt.Format(time.RFC3339)},
+	)
+}
+
+// GetScheduledRunName returns the name of the test CR created by the schedule
+// for the given scheduled time. The name of the schedule is shortened when
+// the name would be longer than a DNS label, as the name of the test CR is
+// used in the names and labels of the test pods. The hash of the full name
+// keeps the names of different schedules apart.
+func GetScheduledRunName(scheduleName string, scheduledAt time.Time) string {
+	suffix := fmt.Sprintf("-%d", scheduledAt.Unix()/60)
+	if len(scheduleName)+len(suffix) <= validation.DNS1123LabelMaxLength {
+		return scheduleName + suffix
+	}
+
+	nameHash := GetStringHash(scheduleName, 5)
+	prefix := scheduleName[:validation.DNS1123LabelMaxLength-len(suffix)-len(nameHash)-1]
+	return strings.TrimRight(prefix, "-.") + "-" + nameHash + suffix
+}
+
+// getScheduledRuns returns the test CRs created by the schedule ordered by
+// their scheduled time (oldest first)
+func (r *TestScheduleReconciler) getScheduledRuns(
+	ctx context.Context,
+	instance *testv1beta1.TestSchedule,
+) ([]scheduledRun, error) {
+	tests, err := GetOwnedTests(ctx, r.Client, instance, map[string]string{testScheduleLabel: instance.Name})
+	if err != nil {
+		return nil, err
+	}
+
+	runs := []scheduledRun{}
+	for _, test := range tests {
+		scheduledAt, err := time.Parse(time.RFC3339, test.instance.GetAnnotations()[scheduledAtAnnotation])
+		if err != nil {
+			scheduledAt = test.instance.GetCreationTimestamp().Time
+		}
+
+		runs = append(runs, scheduledRun{
+			kind:        test.kind,
+			instance:    test.instance,
+			scheduledAt: scheduledAt,
+		})
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].scheduledAt.Before(runs[j].scheduledAt)
+	})
+
+	return runs, nil
+}
+
+// pruneScheduledRuns deletes the oldest finished test CRs so that at most
+// historyLimit finished test CRs are kept. The remaining runs are returned.
+func (r *TestScheduleReconciler) pruneScheduledRuns(
+	ctx context.Context,
+	instance *testv1beta1.TestSchedule,
+	runs []scheduledRun,
+) ([]scheduledRun, error) {
+	Log := r.GetLogger(ctx)
+
+	finished := 0
+	for _, run := range runs {
+		if run.IsFinished() {
+			finished++
+		}
+	}
+
+	remaining := []scheduledRun{}
+	for _, run := range runs {
+		if finished <= int(instance.Spec.HistoryLimit) || !run.IsFinished() {
+			remaining = append(remaining, run)
+			continue
+		}
+
+		err := r.Client.Delete(ctx, run.instance, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !k8s_errors.IsNotFound(err) {
+			return nil, err
+		}
+
+		Log.Info(fmt.Sprintf("Deleted finished %s %s exceeding the history limit", run.kind, run.instance.GetName()))
+		finished--
+	}
+
+	return remaining, nil
+}
+
+// updateRunStatus stores the active runs and the time of the last successful
+// run in the status of the schedule
+func (r *TestScheduleReconciler) updateRunStatus(
+	instance *testv1beta1.TestSchedule,
+	runs []scheduledRun,
+) {
+	instance.Status.Active = nil
+	for _, run := range runs {
+		if !run.IsFinished() {
+			instance.Status.Active = append(instance.Status.Active, corev1.ObjectReference{
+				APIVersion: testv1beta1.GroupVersion.String(),
+				Kind:       run.kind,
+				Namespace:  run.instance.GetNamespace(),
+				Name:       run.instance.GetName(),
+				UID:        run.instance.GetUID(),
+			})
+			continue
+		}
+
+		if !run.instance.GetConditions().IsTrue(testv1beta1.TestsPassedCondition) {
+			continue
+		}
+
+		lastSuccessfulTime := instance.Status.LastSuccessfulTime
+		if lastSuccessfulTime == nil || run.scheduledAt.After(lastSuccessfulTime.Time) {
+			instance.Status.LastSuccessfulTime = &metav1.Time{Time: run.scheduledAt}
+		}
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *TestScheduleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&testv1beta1.TestSchedule{}).
+		Owns(&testv1beta1.Tempest{}).
+		Owns(&testv1beta1.Tobiko{}).
+		Owns(&testv1beta1.AnsibleTest{}).
+		Owns(&testv1beta1.HorizonTest{}).
+		Complete(r)
+}
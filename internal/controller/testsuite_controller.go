@@ -0,0 +1,614 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/openstack-k8s-operators/lib-common/modules/common/condition"
+	"github.com/openstack-k8s-operators/lib-common/modules/common/helper"
+	testv1beta1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	testSuiteLabel           = "testSuite"
+	testSuiteStepLabel       = "testSuiteStep"
+	eventReasonTestCreated   = "TestCreated"
+	eventReasonTestInvalid   = "TestInvalid"
+	testSuiteStepInvalid     = "InvalidTemplate"
+	testSuiteStepNotOwned    = "AlreadyExists"
+	testSuiteResultSeparator = "/"
+)
+
+// TestSuiteReconciler reconciles a TestSuite object
+type TestSuiteReconciler struct {
+	Reconciler
+}
+
+// GetLogger returns a logger object with a prefix of "controller.name" and additional controller context fields
+func (r *TestSuiteReconciler) GetLogger(ctx context.Context) logr.Logger {
+	return log.FromContext(ctx).WithName("Controllers").WithName("TestSuite")
+}
+
+// +kubebuilder:rbac:groups=test.openstack.org,resources=testsuites,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=test.openstack.org,resources=testsuites/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=test.openstack.org,resources=testsuites/finalizers,verbs=update;patch
+// +kubebuilder:rbac:groups=test.openstack.org,resources=tempests;tobikoes;ansibletests;horizontests,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups="",resources=events,verbs=get;list;create;patch
+
+// Reconcile - TestSuite
+func (r *TestSuiteReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, _err error) {
+	Log := r.GetLogger(ctx)
+
+	instance := &testv1beta1.TestSuite{}
+	err := r.Client.Get(ctx, req.NamespacedName, instance)
+	if err != nil {
+		if k8s_errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	// Create a helper
+	helper, err := helper.NewHelper(
+		instance,
+		r.Client,
+		r.Kclient,
+		r.Scheme,
+		r.Log,
+	)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	conditions := instance.GetConditions()
+	isNewInstance := len(*conditions) == 0
+	if isNewInstance {
+		*conditions = condition.Conditions{}
+	}
+
+	// Save a copy of the conditions so that we can restore the LastTransitionTime
+	// when a condition's state doesn't change.
+	savedConditions := conditions.DeepCopy()
+
+	// Always patch the instance status when exiting this function so we
+	// can persist any changes.
+	defer func() {
+		// Don't update the status, if reconciler Panics
+		if r := recover(); r != nil {
+			Log.Info(fmt.Sprintf("panic during reconcile %v\n", r))
+			panic(r)
+		}
+		condition.RestoreLastTransitionTimes(conditions, savedConditions)
+		if conditions.IsUnknown(condition.ReadyCondition) {
+			conditions.Set(conditions.Mirror(condition.ReadyCondition))
+		}
+		err := helper.PatchInstance(ctx, instance)
+		if err != nil {
+			_err = err
+			return
+		}
+	}()
+
+	if isNewInstance {
+		cl := condition.CreateList(
+			condition.UnknownCondition(condition.ReadyCondition, condition.InitReason, condition.ReadyInitMessage),
+			condition.UnknownCondition(testv1beta1.ExecutionCompletedCondition, condition.InitReason, testv1beta1.ExecutionCompletedInitMessage),
+			condition.UnknownCondition(testv1beta1.TestsPassedCondition, condition.InitReason, testv1beta1.TestsPassedInitMessage),
+		)
+		conditions.Init(&cl)
+
+		// Register overall status immediately to have an early feedback
+		// e.g. in the cli
+		return ctrl.Result{}, nil
+	}
+	instance.Status.ObservedGeneration = instance.Generation
+
+	// Ready mirrors the other conditions until the testing finishes
+	conditions.MarkUnknown(condition.ReadyCondition, condition.InitReason, condition.ReadyInitMessage)
+
+	tests, err := GetOwnedTests(ctx, r.Client, instance, map[string]string{testSuiteLabel: instance.Name})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	testsByStep := map[string]ownedTest{}
+	for _, test := range tests {
+		testsByStep[test.instance.GetLabels()[testSuiteStepLabel]] = test
+	}
+
+	steps := r.getStepsStatus(instance, testsByStep)
+
+	timedOut, err := r.stopTimedOutSteps(ctx, instance, steps, testsByStep)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	failedSteps := 0
+	for _, step := range steps {
+		if step.Phase == testv1beta1.WorkflowStepFailed || step.Phase == testv1beta1.WorkflowStepTimedOut {
+			failedSteps++
+		}
+	}
+	workflowStopped := timedOut || ShouldStopWorkflow(instance.Spec.WorkflowOptions, failedSteps)
+
+	// Steps that are skipped can unblock other steps, so the steps are
+	// evaluated until none of them changes
+	dependencies := GetWorkflowDependencies(instance, len(steps))
+	for changed := true; changed; {
+		changed = false
+		for stepIdx := range steps {
+			if steps[stepIdx].Phase != testv1beta1.WorkflowStepPending {
+				continue
+			}
+
+			if workflowStopped {
+				steps[stepIdx].Phase = testv1beta1.WorkflowStepSkipped
+				changed = true
+				continue
+			}
+
+			if !isTestSuiteStepRunnable(steps, dependencies[stepIdx]) {
+				continue
+			}
+
+			runnable, decided := evaluateRunIf(instance, stepIdx, getTestSuiteStepPhases(steps))
+			if !decided {
+				continue
+			}
+
+			if !runnable {
+				steps[stepIdx].Phase = testv1beta1.WorkflowStepSkipped
+				changed = true
+				continue
+			}
+
+			err := r.createStepTest(ctx, instance, stepIdx, &steps[stepIdx])
+			if err != nil {
+				instance.Status.Steps = steps
+				return ctrl.Result{}, err
+			}
+
+			if steps[stepIdx].Phase == testv1beta1.WorkflowStepFailed {
+				failedSteps++
+				workflowStopped = ShouldStopWorkflow(instance.Spec.WorkflowOptions, failedSteps)
+				changed = true
+			}
+		}
+	}
+
+	instance.Status.Steps = steps
+	testingFinished := r.updateSuiteStatus(instance, testsByStep, timedOut)
+
+	if testingFinished {
+		conditions.MarkTrue(condition.ReadyCondition, condition.ReadyMessage)
+		Log.Info("Reconciled Service successfully")
+		return ctrl.Result{}, nil
+	}
+
+	if deadline := r.getWorkflowDeadline(instance, steps); !deadline.IsZero() {
+		return ctrl.Result{RequeueAfter: time.Until(deadline)}, nil
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// getStepsStatus returns the status of the steps based on the test CRs created
+// by the suite. A step whose test CR disappeared is started again.
+func (r *TestSuiteReconciler) getStepsStatus(
+	instance *testv1beta1.TestSuite,
+	testsByStep map[string]ownedTest,
+) []testv1beta1.TestSuiteStepStatus {
+	previousSteps := map[string]testv1beta1.TestSuiteStepStatus{}
+	for _, step := range instance.Status.Steps {
+		previousSteps[step.Name] = step
+	}
+
+	steps := make([]testv1beta1.TestSuiteStepStatus, len(instance.Spec.Workflow))
+	for stepIdx, workflowStep := range instance.Spec.Workflow {
+		step, ok := previousSteps[workflowStep.StepName]
+		if !ok {
+			step = testv1beta1.TestSuiteStepStatus{
+				Name:  workflowStep.StepName,
+				Phase: testv1beta1.WorkflowStepPending,
+			}
+		}
+		step.Index = stepIdx
+		step.DependsOn = workflowStep.DependsOn
+		step.Kind = getTestSuiteStepKind(workflowStep)
+
+		test, ok := testsByStep[workflowStep.StepName]
+		switch {
+		case ok && IsTestingFinished(test.instance):
+			step.Kind = test.kind
+			step.InstanceName = test.instance.GetName()
+			if step.FinishTime == nil {
+				step.FinishTime = &metav1.Time{Time: time.Now()}
+			}
+
+			testsPassed := test.instance.GetConditions().Get(testv1beta1.TestsPassedCondition)
+			if testsPassed != nil && testsPassed.Status == corev1.ConditionTrue {
+				step.Phase = testv1beta1.WorkflowStepSucceeded
+				step.Reason = ""
+			} else {
+				step.Phase = testv1beta1.WorkflowStepFailed
+				if testsPassed != nil {
+					step.Reason = string(testsPassed.Reason)
+				}
+			}
+
+		case ok:
+			step.Kind = test.kind
+			step.InstanceName = test.instance.GetName()
+			step.Phase = testv1beta1.WorkflowStepRunning
+
+		case step.Phase == testv1beta1.WorkflowStepRunning:
+			step.Phase = testv1beta1.WorkflowStepPending
+		}
+
+		steps[stepIdx] = step
+	}
+
+	return steps
+}
+
+// stopTimedOutSteps deletes the test CRs of the running steps once the
+// workflow timeout expired. It returns true when the timeout expired.
+func (r *TestSuiteReconciler) stopTimedOutSteps(
+	ctx context.Context,
+	instance *testv1beta1.TestSuite,
+	steps []testv1beta1.TestSuiteStepStatus,
+	testsByStep map[string]ownedTest,
+) (bool, error) {
+	deadline := r.getWorkflowDeadline(instance, steps)
+	if deadline.IsZero() || time.Now().Before(deadline) {
+		return false, nil
+	}
+
+	for stepIdx := range steps {
+		if steps[stepIdx].Phase != testv1beta1.WorkflowStepRunning {
+			continue
+		}
+
+		test := testsByStep[steps[stepIdx].Name]
+		err := r.Client.Delete(ctx, test.instance, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !k8s_errors.IsNotFound(err) {
+			return true, err
+		}
+
+		steps[stepIdx].Phase = testv1beta1.WorkflowStepTimedOut
+		steps[stepIdx].Reason = string(testv1beta1.TimedOutReason)
+		steps[stepIdx].FinishTime = &metav1.Time{Time: time.Now()}
+	}
+
+	return true, nil
+}
+
+// getWorkflowDeadline returns the time when the workflow timeout of the suite
+// expires. The zero time is returned when there is no timeout or when no test
+// CR was created yet.
+func (r *TestSuiteReconciler) getWorkflowDeadline(
+	instance *testv1beta1.TestSuite,
+	steps []testv1beta1.TestSuiteStepStatus,
+) time.Time {
+	if instance.Spec.WorkflowTimeout <= 0 {
+		return time.Time{}
+	}
+
+	var startTime *metav1.Time
+	for _, step := range steps {
+		if step.StartTime != nil && (startTime == nil || step.StartTime.Before(startTime)) {
+			startTime = step.StartTime
+		}
+	}
+
+	if startTime == nil {
+		return time.Time{}
+	}
+
+	return startTime.Add(time.Duration(instance.Spec.WorkflowTimeout) * time.Second)
+}
+
+// createStepTest creates the test CR for the step with the given index and
+// updates the status of the step
+func (r *TestSuiteReconciler) createStepTest(
+	ctx context.Context,
+	instance *testv1beta1.TestSuite,
+	stepIdx int,
+	step *testv1beta1.TestSuiteStepStatus,
+) error {
+	Log := r.GetLogger(ctx)
+	workflowStep := instance.Spec.Workflow[stepIdx]
+
+	template := testv1beta1.TestTemplate{}
+	if workflowStep.Template != nil {
+		template = *workflowStep.Template
+	} else if workflowStep.TemplateRef != nil {
+		var err error
+		template, err = GetTemplateFromRef(ctx, r.Client, instance.Namespace, *workflowStep.TemplateRef)
+		if err != nil {
+			instance.Status.Conditions.MarkFalse(
+				condition.ReadyCondition,
+				condition.ErrorReason,
+				condition.SeverityWarning,
+				testv1beta1.TestSuiteTemplateErrorMessage,
+				workflowStep.StepName,
+				err.Error(),
+			)
+			return err
+		}
+	}
+
+	test, err := BuildTestFromTemplate(
+		instance,
+		r.Scheme,
+		template,
+		fmt.Sprintf("%s-%s", instance.Name, workflowStep.StepName),
+		map[string]string{
+			testSuiteLabel:     instance.Name,
+			testSuiteStepLabel: workflowStep.StepName,
+		},
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = r.Client.Create(ctx, test)
+	switch {
+	case err == nil:
+		Log.Info(fmt.Sprintf("Created %s %s for step %s", test.GetKind(), test.GetName(), step.Name))
+		r.Recorder.Eventf(
+			instance,
+			corev1.EventTypeNormal,
+			eventReasonTestCreated,
+			"Created %s %s for step %s",
+			test.GetKind(),
+			test.GetName(),
+			step.Name,
+		)
+
+	case k8s_errors.IsAlreadyExists(err):
+		// The test CR was created by an earlier reconcile that was not
+		// observed yet. A test CR not controlled by the suite fails the step.
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(test.GroupVersionKind())
+		if err := r.Client.Get(ctx, client.ObjectKeyFromObject(test), existing); err != nil {
+			return err
+		}
+
+		if !metav1.IsControlledBy(existing, instance) {
+			step.Phase = testv1beta1.WorkflowStepFailed
+			step.Reason = testSuiteStepNotOwned
+			step.FinishTime = &metav1.Time{Time: time.Now()}
+			return nil
+		}
+
+	case k8s_errors.IsInvalid(err) || k8s_errors.IsForbidden(err) || k8s_errors.IsBadRequest(err):
+		// The template was rejected by the API server. Creating the test CR
+		// again would fail the same way.
+		Log.Info(fmt.Sprintf(testv1beta1.TestSuiteCreateErrorMessage, step.Name, err.Error()))
+		r.Recorder.Eventf(
+			instance,
+			corev1.EventTypeWarning,
+			eventReasonTestInvalid,
+			testv1beta1.TestSuiteCreateErrorMessage,
+			step.Name,
+			err.Error(),
+		)
+		step.Phase = testv1beta1.WorkflowStepFailed
+		step.Reason = testSuiteStepInvalid
+		step.FinishTime = &metav1.Time{Time: time.Now()}
+		return nil
+
+	default:
+		instance.Status.Conditions.MarkFalse(
+			condition.ReadyCondition,
+			condition.ErrorReason,
+			condition.SeverityWarning,
+			testv1beta1.TestSuiteCreateErrorMessage,
+			step.Name,
+			err.Error(),
+		)
+		return err
+	}
+
+	step.Phase = testv1beta1.WorkflowStepRunning
+	step.Kind = test.GetKind()
+	step.InstanceName = test.GetName()
+	step.Reason = ""
+	step.FinishTime = nil
+	if step.StartTime == nil {
+		step.StartTime = &metav1.Time{Time: time.Now()}
+	}
+
+	return nil
+}
+
+// updateSuiteStatus sets the summary, the combined test results and the
+// conditions of the suite based on the state of its steps. It returns true
+// when all steps reached their final phase.
+func (r *TestSuiteReconciler) updateSuiteStatus(
+	instance *testv1beta1.TestSuite,
+	testsByStep map[string]ownedTest,
+	timedOut bool,
+) bool {
+	conditions := instance.GetConditions()
+
+	summary := &testv1beta1.WorkflowStepsSummary{Total: len(instance.Status.Steps)}
+	results := []testv1beta1.TestResults{}
+	testsFailed := []string{}
+	infrastructureFailed := []string{}
+	timedOutSteps := []string{}
+	testingFinished := true
+	testsCreated := false
+	for _, step := range instance.Status.Steps {
+		if step.InstanceName != "" {
+			testsCreated = true
+		}
+
+		switch step.Phase {
+		case testv1beta1.WorkflowStepSucceeded:
+			summary.Succeeded++
+		case testv1beta1.WorkflowStepTimedOut:
+			summary.Failed++
+			timedOutSteps = append(timedOutSteps, step.Name)
+		case testv1beta1.WorkflowStepFailed:
+			summary.Failed++
+			if step.Reason == string(testv1beta1.InfrastructureFailureReason) ||
+				step.Reason == string(testv1beta1.TimedOutReason) {
+				infrastructureFailed = append(infrastructureFailed, fmt.Sprintf("%s (%s)", step.Name, step.Reason))
+			} else {
+				testsFailed = append(testsFailed, step.Name)
+			}
+		case testv1beta1.WorkflowStepSkipped:
+		default:
+			testingFinished = false
+		}
+
+		test, ok := testsByStep[step.Name]
+		if !ok {
+			continue
+		}
+
+		for _, testResults := range test.instance.GetStatus().Results {
+			stepResults := testResults
+			stepResults.FailedTests = append([]string{}, testResults.FailedTests...)
+			stepResults.Index = step.Index
+			stepResults.StepName = step.Name
+			if testResults.StepName != "" && testResults.StepName != test.instance.GetName() {
+				stepResults.StepName = step.Name + testSuiteResultSeparator + testResults.StepName
+			}
+			results = append(results, stepResults)
+		}
+	}
+
+	instance.Status.StepsSummary = summary
+	instance.Status.Results = results
+
+	switch {
+	case len(infrastructureFailed) > 0:
+		conditions.Set(condition.FalseCondition(
+			testv1beta1.ExecutionCompletedCondition,
+			testv1beta1.InfrastructureFailureReason,
+			condition.SeverityError,
+			testv1beta1.ExecutionCompletedInfrastructureErrorMessage,
+			strings.Join(infrastructureFailed, ", ")))
+	case timedOut:
+		conditions.Set(condition.FalseCondition(
+			testv1beta1.ExecutionCompletedCondition,
+			testv1beta1.TimedOutReason,
+			condition.SeverityError,
+			testv1beta1.ExecutionCompletedTimedOutMessage,
+			strings.Join(timedOutSteps, ", ")))
+	case testingFinished:
+		conditions.MarkTrue(testv1beta1.ExecutionCompletedCondition, testv1beta1.ExecutionCompletedMessage)
+	case !testsCreated:
+		conditions.MarkUnknown(testv1beta1.ExecutionCompletedCondition, condition.InitReason, testv1beta1.ExecutionCompletedInitMessage)
+	default:
+		conditions.Set(condition.FalseCondition(
+			testv1beta1.ExecutionCompletedCondition,
+			condition.RequestedReason,
+			condition.SeverityInfo,
+			testv1beta1.ExecutionCompletedRunningMessage))
+	}
+
+	switch {
+	case len(testsFailed) > 0:
+		conditions.Set(condition.FalseCondition(
+			testv1beta1.TestsPassedCondition,
+			testv1beta1.TestsFailedReason,
+			condition.SeverityError,
+			testv1beta1.TestsPassedErrorMessage,
+			strings.Join(testsFailed, ", ")))
+	case testingFinished && (len(infrastructureFailed) > 0 || timedOut):
+		conditions.Set(condition.FalseCondition(
+			testv1beta1.TestsPassedCondition,
+			testv1beta1.InfrastructureFailureReason,
+			condition.SeverityWarning,
+			testv1beta1.TestsPassedUnavailableMessage))
+	case testingFinished:
+		conditions.MarkTrue(testv1beta1.TestsPassedCondition, testv1beta1.TestsPassedMessage)
+	default:
+		conditions.MarkUnknown(testv1beta1.TestsPassedCondition, condition.InitReason, testv1beta1.TestsPassedInitMessage)
+	}
+
+	return testingFinished
+}
+
+// isTestSuiteStepRunnable returns true when all the given dependencies of
+// a step reached their final phase
+func isTestSuiteStepRunnable(steps []testv1beta1.TestSuiteStepStatus, dependencies []int) bool {
+	for _, dependencyIdx := range dependencies {
+		switch steps[dependencyIdx].Phase {
+		case testv1beta1.WorkflowStepSucceeded,
+			testv1beta1.WorkflowStepFailed,
+			testv1beta1.WorkflowStepTimedOut,
+			testv1beta1.WorkflowStepSkipped:
+			continue
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// getTestSuiteStepPhases returns the phases of the given steps
+func getTestSuiteStepPhases(steps []testv1beta1.TestSuiteStepStatus) []testv1beta1.WorkflowStepPhase {
+	phases := make([]testv1beta1.WorkflowStepPhase, len(steps))
+	for stepIdx, step := range steps {
+		phases[stepIdx] = step.Phase
+	}
+
+	return phases
+}
+
+// getTestSuiteStepKind returns the kind of the test CR created for the step
+func getTestSuiteStepKind(step testv1beta1.TestSuiteStep) string {
+	if step.Template != nil {
+		return step.Template.Kind
+	}
+
+	if step.TemplateRef != nil {
+		return step.TemplateRef.Kind
+	}
+
+	return ""
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *TestSuiteReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&testv1beta1.TestSuite{}).
+		Owns(&testv1beta1.Tempest{}).
+		Owns(&testv1beta1.Tobiko{}).
+		Owns(&testv1beta1.AnsibleTest{}).
+		Owns(&testv1beta1.HorizonTest{}).
+		Complete(r)
+}
@@ -28,9 +28,15 @@ import (
 	"github.com/openstack-k8s-operators/lib-common/modules/common/util"
 	testv1beta1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
 	"github.com/openstack-k8s-operators/test-operator/internal/tobiko"
+	testutil "github.com/openstack-k8s-operators/test-operator/internal/util"
+	coordinationv1 "k8s.io/api/coordination/v1"
 	corev1 "k8s.io/api/core/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
 
 // TobikoReconciler reconciles a Tobiko object
@@ -46,12 +52,18 @@ func (r *TobikoReconciler) GetLogger(ctx context.Context) logr.Logger {
 // +kubebuilder:rbac:groups=test.openstack.org,resources=tobikoes,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=test.openstack.org,resources=tobikoes/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=test.openstack.org,resources=tobikoes/finalizers,verbs=update;patch
+// +kubebuilder:rbac:groups=test.openstack.org,resources=tempests;tobikoes;ansibletests;horizontests,verbs=get;list;watch
 // +kubebuilder:rbac:groups=k8s.cni.cncf.io,resources=network-attachment-definitions,verbs=get;list;watch
 // +kubebuilder:rbac:groups="security.openshift.io",resourceNames=anyuid;privileged;nonroot;nonroot-v2,resources=securitycontextconstraints,verbs=use
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete;
 // +kubebuilder:rbac:groups="",resources=pods,verbs=create;delete;get;list;patch;update;watch
+// +kubebuilder:rbac:groups="",resources=pods/status,verbs=get;patch;update
+// +kubebuilder:rbac:groups="",resources=events,verbs=get;list;create;patch
+// +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=pods/log,verbs=get
 // +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;create;update;watch;patch;delete
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile - Tobiko
 func (r *TobikoReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -61,20 +73,23 @@ func (r *TobikoReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		ServiceName:             tobiko.ServiceName,
 		NeedsNetworkAttachments: true,
 		NeedsConfigMaps:         true,
-		NeedsFinalizer:          false,
+		NeedsFinalizer:          true,
 		SupportsWorkflow:        true,
 
-		GenerateServiceConfigMaps: func(ctx context.Context, helper *helper.Helper, labels map[string]string, instance *testv1beta1.Tobiko, workflowStepIndex int) error {
+		GenerateServiceConfigMaps: func(ctx context.Context, helper *helper.Helper, labels map[string]string, instance *testv1beta1.Tobiko, workflowStepIndex int, _ int) error {
 			return r.generateServiceConfigMaps(ctx, helper, labels, instance, workflowStepIndex)
 		},
 
-		BuildPod: func(ctx context.Context, instance *testv1beta1.Tobiko, labels, annotations map[string]string, workflowStepIndex int, pvcIndex int) (*corev1.Pod, error) {
-			return r.buildTobikoPod(ctx, instance, labels, annotations, workflowStepIndex, pvcIndex)
+		BuildPod: func(ctx context.Context, instance *testv1beta1.Tobiko, labels, annotations map[string]string, workflowStepIndex int, attempt int, pvcIndex int) (*corev1.Pod, error) {
+			return r.buildTobikoPod(ctx, instance, labels, annotations, workflowStepIndex, attempt, pvcIndex)
 		},
 
 		GetInitialConditions: func() []*condition.Condition {
 			return []*condition.Condition{
 				condition.UnknownCondition(condition.ReadyCondition, condition.InitReason, condition.ReadyInitMessage),
+				condition.UnknownCondition(testv1beta1.ExecutionCompletedCondition, condition.InitReason, testv1beta1.ExecutionCompletedInitMessage),
+				condition.UnknownCondition(testv1beta1.TestsPassedCondition, condition.InitReason, testv1beta1.TestsPassedInitMessage),
+				condition.UnknownCondition(testv1beta1.TestPodsStartedCondition, condition.InitReason, testv1beta1.TestPodsStartedInitMessage),
 				condition.UnknownCondition(condition.InputReadyCondition, condition.InitReason, condition.InputReadyInitMessage),
 				condition.UnknownCondition(condition.ServiceConfigReadyCondition, condition.InitReason, condition.ServiceConfigReadyInitMessage),
 				condition.UnknownCondition(condition.DeploymentReadyCondition, condition.InitReason, condition.DeploymentReadyInitMessage),
@@ -89,6 +104,12 @@ func (r *TobikoReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 			return r.ValidateSecretWithKeys(ctx, instance, instance.Spec.KubeconfigSecretName, []string{})
 		},
 
+		ParseTestResults: testutil.ParsePytestResults,
+
+		GetLogsDirName: func(instance *testv1beta1.Tobiko, workflowStepIndex int, attempt int) string {
+			return r.GetPodName(instance, workflowStepIndex, attempt)
+		},
+
 		GetSpec: func(instance *testv1beta1.Tobiko) interface{} {
 			return &instance.Spec
 		},
@@ -101,6 +122,10 @@ func (r *TobikoReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 			return len(instance.Spec.Workflow)
 		},
 
+		GetWorkflowOptions: func(instance *testv1beta1.Tobiko) testv1beta1.WorkflowOptions {
+			return instance.Spec.WorkflowOptions
+		},
+
 		GetParallel: func(instance *testv1beta1.Tobiko) bool {
 			return instance.Spec.Parallel
 		},
@@ -122,6 +147,7 @@ func (r *TobikoReconciler) buildTobikoPod(
 	instance *testv1beta1.Tobiko,
 	labels, annotations map[string]string,
 	workflowStepIndex int,
+	attempt int,
 	pvcIndex int,
 ) (*corev1.Pod, error) {
 	Log := r.GetLogger(ctx)
@@ -134,8 +160,8 @@ func (r *TobikoReconciler) buildTobikoPod(
 	}
 
 	// Prepare Tobiko env vars
-	envVars := r.PrepareTobikoEnvVars(instance, workflowStepIndex)
-	podName := r.GetPodName(instance, workflowStepIndex)
+	envVars := r.PrepareTobikoEnvVars(instance, workflowStepIndex, attempt)
+	podName := r.GetPodName(instance, workflowStepIndex, attempt)
 	logsPVCName := r.GetPVCLogsName(instance, pvcIndex)
 
 	containerImage, err := r.GetContainerImage(ctx, instance)
@@ -165,6 +191,13 @@ func (r *TobikoReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&corev1.Pod{}).
 		Owns(&corev1.Secret{}).
 		Owns(&corev1.ConfigMap{}).
+		Watches(
+			&coordinationv1.Lease{},
+			handler.EnqueueRequestsFromMapFunc(r.LockQueueRequests(func() client.ObjectList {
+				return &testv1beta1.TobikoList{}
+			})),
+			builder.WithPredicates(predicate.NewPredicateFuncs(IsTestOperatorLease)),
+		).
 		Complete(r)
 }
 
@@ -215,6 +248,7 @@ func (r *TobikoReconciler) generateServiceConfigMaps(
 func (r *TobikoReconciler) PrepareTobikoEnvVars(
 	instance *testv1beta1.Tobiko,
 	workflowStepIndex int,
+	attempt int,
 ) map[string]env.Setter {
 	// Prepare env vars
 	envVars := make(map[string]env.Setter)
@@ -235,7 +269,7 @@ func (r *TobikoReconciler) PrepareTobikoEnvVars(
 	// String
 	SetStringEnvVars(envVars, map[string]string{
 		"USE_EXTERNAL_FILES":    "True",
-		"TOBIKO_LOGS_DIR_NAME":  r.GetPodName(instance, workflowStepIndex),
+		"TOBIKO_LOGS_DIR_NAME":  r.GetPodName(instance, workflowStepIndex, attempt),
 		"TOBIKO_TESTENV":        instance.Spec.Testenv,
 		"TOBIKO_VERSION":        instance.Spec.Version,
 		"TOBIKO_PYTEST_ADDOPTS": PreparePytestAddopts(instance.Spec.PytestAddopts, instance.Spec.SkipRegexList),
@@ -0,0 +1,101 @@
+package tempest
+
+import (
+	"bufio"
+	"regexp"
+	"strconv"
+	"strings"
+
+	testv1beta1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
+	util "github.com/openstack-k8s-operators/test-operator/internal/util"
+)
+
+var (
+	// totalsRegex matches a single count in the Totals section printed by
+	// stestr, e.g. " - Passed: 120"
+	totalsRegex = regexp.MustCompile(`^- (Passed|Failed|Skipped|Expected Fail): (\d+)$`)
+
+	// failedTestRegex matches a failed test printed by stestr, e.g.
+	// "{0} tempest.api.compute.test_a.Test.test_b [1.23s] ... FAILED"
+	failedTestRegex = regexp.MustCompile(`^\{\d+\} (.+?) \[[\d.]+s\] \.\.\. FAILED$`)
+
+	// failedSectionRegex matches the header of the section printed by stestr
+	// at the end of a run that lists the failed tests with their output, e.g.
+	// "Failed 2 tests - output below:"
+	failedSectionRegex = regexp.MustCompile(`^Failed \d+ tests? - output below:$`)
+
+	// underlineRegex matches the line that underlines the name of a failed
+	// test in the section of the failed tests
+	underlineRegex = regexp.MustCompile(`^-+$`)
+)
+
+// ParseResults parses the output of a tempest run and returns the number of
+// passed, failed, skipped and expected-failure tests together with the names
+// of the failed tests. The counts are taken from the last Totals section
+// printed by stestr. The failed tests are collected both from the progress
+// lines and from the section of the failed tests printed at the end of the
+// run, so that they are listed even when only the end of a long output is
+// parsed. Nil is returned when the output does not contain any Totals section.
+func ParseResults(logs string) *testv1beta1.TestResults {
+	var results *testv1beta1.TestResults
+
+	scanner := bufio.NewScanner(strings.NewReader(logs))
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), 1024*1024)
+	failedTests := []string{}
+	failedSection := false
+	previousLine := ""
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		lastLine := previousLine
+		previousLine = line
+
+		if line == "Totals" {
+			results = &testv1beta1.TestResults{}
+			failedSection = false
+			continue
+		}
+
+		if failedSectionRegex.MatchString(line) {
+			failedSection = true
+			continue
+		}
+
+		// The name of a failed test is underlined by dashes of the same length
+		if failedSection && underlineRegex.MatchString(line) && len(line) == len(lastLine) {
+			failedTests = util.AppendFailedTest(failedTests, lastLine)
+			continue
+		}
+
+		if match := failedTestRegex.FindStringSubmatch(line); match != nil {
+			failedTests = util.AppendFailedTest(failedTests, match[1])
+			continue
+		}
+
+		match := totalsRegex.FindStringSubmatch(line)
+		if match == nil || results == nil {
+			continue
+		}
+
+		value, err := strconv.Atoi(match[2])
+		if err != nil {
+			continue
+		}
+
+		switch match[1] {
+		case "Passed":
+			results.Passed = value
+		case "Failed":
+			results.Failed = value
+		case "Skipped":
+			results.Skipped = value
+		case "Expected Fail":
+			results.ExpectedFailures = value
+		}
+	}
+
+	if results != nil && len(failedTests) > 0 {
+		results.FailedTests = failedTests
+	}
+
+	return results
+}
@@ -0,0 +1,137 @@
+package tempest
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega" //revive:disable:dot-imports
+
+	testv1beta1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
+)
+
+const tempestPassedOutput = `{0} setUpClass (tempest.api.compute.admin.test_live_migration.LiveMigrationTest) ... SKIPPED: Less than 2 compute nodes, skipping migration test.
+{1} tempest.api.compute.servers.test_create_server.ServersTestJSON.test_list_servers[id-9a438d88-10c6-4bcd-8b5b-5b6e25e1346f,smoke] [0.512345s] ... ok
+{0} tempest.api.identity.v3.test_tokens.TokensV3Test.test_create_token[id-6f8e4436-fc96-4282-8122-e41df57197a9] [1.234567s] ... ok
+
+======
+Totals
+======
+Ran: 2 tests in 12.3456 sec.
+ - Passed: 2
+ - Skipped: 1
+ - Expected Fail: 0
+ - Unexpected Success: 0
+ - Failed: 0
+Sum of execute time for each test: 1.7469 sec.
+
+==============
+Worker Balance
+==============
+ - Worker 0 (1 tests) => 0:00:01.234567
+ - Worker 1 (1 tests) => 0:00:00.512345
+`
+
+const tempestFailedOutput = `{0} tempest.api.network.test_networks.NetworksTest.test_create_update_delete_network_subnet[id-0e269138-0da6-4efc-a46d-578161e7b221,smoke] [2.345678s] ... ok
+{1} tempest.api.network.test_ports.PortsTestJSON.test_create_port_in_allowed_allocation_pools[id-0435f278-40ae-48cb-a404-b8a087bc09b1] [3.456789s] ... FAILED
+{0} tempest.scenario.test_server_basic_ops.TestServerBasicOps.test_server_basic_ops[compute,id-7fff3fb3-91d8-4fd0-bd7d-0204f1f180ba,network,smoke] [45.678901s] ... FAILED
+
+==============================
+Failed 2 tests - output below:
+==============================
+
+tempest.api.network.test_ports.PortsTestJSON.test_create_port_in_allowed_allocation_pools[id-0435f278-40ae-48cb-a404-b8a087bc09b1]
+----------------------------------------------------------------------------------------------------------------------------------
+
+Captured traceback:
+~~~~~~~~~~~~~~~~~~~
+    Traceback (most recent call last):
+      File "/usr/lib/python3.9/site-packages/tempest/lib/common/rest_client.py", line 986, in _error_checker
+        raise exceptions.Conflict(resp_body, resp=resp)
+    tempest.lib.exceptions.Conflict: Conflict with state of target resource
+
+======
+Totals
+======
+Ran: 3 tests in 98.7654 sec.
+ - Passed: 1
+ - Skipped: 0
+ - Expected Fail: 1
+ - Unexpected Success: 0
+ - Failed: 2
+Sum of execute time for each test: 51.4814 sec.
+`
+
+// tempestFailedSectionOutput is the end of tempestFailedOutput that lacks the
+// progress lines of the failed tests
+var tempestFailedSectionOutput = tempestFailedOutput[strings.Index(tempestFailedOutput, "=============================="):]
+
+func TestParseResults(t *testing.T) {
+	tests := []struct {
+		name     string
+		logs     string
+		expected *testv1beta1.TestResults
+	}{
+		{
+			name:     "empty output",
+			logs:     "",
+			expected: nil,
+		},
+		{
+			name:     "output without totals",
+			logs:     "{0} tempest.api.compute.test_a.Test.test_b [1.23s] ... FAILED\n",
+			expected: nil,
+		},
+		{
+			name: "passed tests",
+			logs: tempestPassedOutput,
+			expected: &testv1beta1.TestResults{
+				Passed:  2,
+				Skipped: 1,
+			},
+		},
+		{
+			name: "failed tests",
+			logs: tempestFailedOutput,
+			expected: &testv1beta1.TestResults{
+				Passed:           1,
+				Failed:           2,
+				ExpectedFailures: 1,
+				FailedTests: []string{
+					"tempest.api.network.test_ports.PortsTestJSON.test_create_port_in_allowed_allocation_pools[id-0435f278-40ae-48cb-a404-b8a087bc09b1]",
+					"tempest.scenario.test_server_basic_ops.TestServerBasicOps.test_server_basic_ops[compute,id-7fff3fb3-91d8-4fd0-bd7d-0204f1f180ba,network,smoke]",
+				},
+			},
+		},
+		{
+			name: "failed tests listed only at the end of the output",
+			logs: tempestFailedSectionOutput,
+			expected: &testv1beta1.TestResults{
+				Passed:           1,
+				Failed:           2,
+				ExpectedFailures: 1,
+				FailedTests: []string{
+					"tempest.api.network.test_ports.PortsTestJSON.test_create_port_in_allowed_allocation_pools[id-0435f278-40ae-48cb-a404-b8a087bc09b1]",
+				},
+			},
+		},
+		{
+			name: "counts are taken from the last totals",
+			logs: tempestFailedOutput + tempestPassedOutput,
+			expected: &testv1beta1.TestResults{
+				Passed:  2,
+				Skipped: 1,
+				FailedTests: []string{
+					"tempest.api.network.test_ports.PortsTestJSON.test_create_port_in_allowed_allocation_pools[id-0435f278-40ae-48cb-a404-b8a087bc09b1]",
+					"tempest.scenario.test_server_basic_ops.TestServerBasicOps.test_server_basic_ops[compute,id-7fff3fb3-91d8-4fd0-bd7d-0204f1f180ba,network,smoke]",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(ParseResults(tt.logs)).To(Equal(tt.expected))
+		})
+	}
+}
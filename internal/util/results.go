@@ -0,0 +1,94 @@
+package util //nolint:revive // util is a legitimate package name for utility functions
+
+import (
+	"bufio"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	testv1beta1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
+)
+
+const (
+	// MaxFailedTests is the maximum number of failed test names that are
+	// stored in the status of a test CR
+	MaxFailedTests = 100
+)
+
+var (
+	// pytestSummaryRegex matches the final summary line printed by pytest,
+	// e.g. "===== 1 failed, 10 passed, 2 skipped in 12.34s ====="
+	pytestSummaryRegex = regexp.MustCompile(`^=+ (.*\d+ \w+.*) in [\d.]+s.*=+$`)
+
+	// pytestCountRegex matches a single count in the pytest summary line
+	pytestCountRegex = regexp.MustCompile(`(\d+) (\w+)`)
+
+	// pytestFailedTestRegex matches a failed test in the short test summary
+	// printed by pytest, e.g. "FAILED tests/test_a.py::test_b - AssertionError"
+	pytestFailedTestRegex = regexp.MustCompile(`^(?:FAILED|ERROR) (\S+)`)
+)
+
+// ParsePytestResults parses the output of pytest and returns the number of
+// passed, failed, skipped and expected-failure tests together with the names
+// of the failed tests. When pytest was executed multiple times the results
+// are summed up. Nil is returned when the output does not contain any pytest
+// summary.
+func ParsePytestResults(logs string) *testv1beta1.TestResults {
+	var results *testv1beta1.TestResults
+
+	scanner := bufio.NewScanner(strings.NewReader(logs))
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), 1024*1024)
+	failedTests := []string{}
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if match := pytestFailedTestRegex.FindStringSubmatch(line); match != nil {
+			failedTests = AppendFailedTest(failedTests, match[1])
+			continue
+		}
+
+		match := pytestSummaryRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		if results == nil {
+			results = &testv1beta1.TestResults{}
+		}
+
+		for _, count := range pytestCountRegex.FindAllStringSubmatch(match[1], -1) {
+			value, err := strconv.Atoi(count[1])
+			if err != nil {
+				continue
+			}
+
+			switch count[2] {
+			case "passed":
+				results.Passed += value
+			case "failed", "error", "errors":
+				results.Failed += value
+			case "skipped":
+				results.Skipped += value
+			case "xfailed":
+				results.ExpectedFailures += value
+			}
+		}
+	}
+
+	if results != nil && len(failedTests) > 0 {
+		results.FailedTests = failedTests
+	}
+
+	return results
+}
+
+// AppendFailedTest appends the name of a failed test unless it is already
+// listed or the list reached MaxFailedTests
+func AppendFailedTest(failedTests []string, testName string) []string {
+	if len(failedTests) >= MaxFailedTests || slices.Contains(failedTests, testName) {
+		return failedTests
+	}
+
+	return append(failedTests, testName)
+}
@@ -0,0 +1,136 @@
+package util //nolint:revive // util is a legitimate package name for utility functions
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega" //revive:disable:dot-imports
+
+	testv1beta1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
+)
+
+const tobikoPassedOutput = `============================= test session starts ==============================
+platform linux -- Python 3.9.18, pytest-7.4.4, pluggy-1.3.0
+rootdir: /var/lib/tobiko/tobiko
+configfile: tox.ini
+plugins: html-4.1.1, metadata-3.0.0, rerunfailures-13.0, subtests-0.11.0, timeout-2.2.0, xdist-3.5.0
+collected 27 items
+
+tobiko/tests/scenario/neutron/test_network.py::NetworkTest::test_network_is_reachable PASSED [  3%]
+tobiko/tests/scenario/neutron/test_router.py::RouterTest::test_router_is_scheduled_on_l3_agents SKIPPED [  7%]
+tobiko/tests/scenario/nova/test_server.py::CirrosServerTest::test_ping_server PASSED [ 11%]
+
+- generated xml file: /var/lib/tempest/external_files/tobiko_results_01_scenario.xml -
+- Generated html report: file:///var/lib/tempest/external_files/tobiko_results_01_scenario.html -
+================= 25 passed, 2 skipped in 734.21s (0:12:14) ==================
+`
+
+const tobikoFailedOutput = `============================= test session starts ==============================
+platform linux -- Python 3.9.18, pytest-7.4.4, pluggy-1.3.0
+collected 31 items
+
+tobiko/tests/faults/ha/test_cloud_recovery.py::DisruptTripleoNodesTest::test_reboot_controller_main_vip FAILED [  3%]
+tobiko/tests/faults/ha/test_cloud_recovery.py::DisruptTripleoNodesTest::test_kill_ovn_controller PASSED [  6%]
+
+=================================== FAILURES ===================================
+___________ DisruptTripleoNodesTest.test_reboot_controller_main_vip ____________
+
+self = <tobiko.tests.faults.ha.test_cloud_recovery.DisruptTripleoNodesTest testMethod=test_reboot_controller_main_vip>
+
+>       raise tobiko.RetryTimeLimitError(timeout=600.0)
+E       tobiko.common._retry.RetryTimeLimitError: Retry time limit exceeded (timeout=600.0)
+
+tobiko/common/_retry.py:120: RetryTimeLimitError
+=========================== short test summary info ============================
+FAILED tobiko/tests/faults/ha/test_cloud_recovery.py::DisruptTripleoNodesTest::test_reboot_controller_main_vip - tobiko.common._retry.RetryTimeLimitError: Retry time limit exceeded (timeout=600.0)
+ERROR tobiko/tests/faults/ha/test_cloud_recovery.py::DisruptTripleoNodesTest::test_network_disruptor_main_vip - tobiko.common._exception.ObjectNotFound: Object not found
+===== 1 failed, 28 passed, 1 xfailed, 1 error in 2412.87s (0:40:12) =====
+`
+
+func TestParsePytestResults(t *testing.T) {
+	manyFailedTests := []string{}
+	manyFailedLines := []string{}
+	for i := range MaxFailedTests + 10 {
+		testName := fmt.Sprintf("tobiko/tests/functional/test_a.py::ATest::test_%d", i)
+		manyFailedLines = append(manyFailedLines, "FAILED "+testName+" - AssertionError")
+		if i < MaxFailedTests {
+			manyFailedTests = append(manyFailedTests, testName)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		logs     string
+		expected *testv1beta1.TestResults
+	}{
+		{
+			name:     "empty output",
+			logs:     "",
+			expected: nil,
+		},
+		{
+			name:     "output without summary",
+			logs:     "tobiko: error: unrecognized arguments: --foo\n",
+			expected: nil,
+		},
+		{
+			name: "passed tests",
+			logs: tobikoPassedOutput,
+			expected: &testv1beta1.TestResults{
+				Passed:  25,
+				Skipped: 2,
+			},
+		},
+		{
+			name: "failed tests",
+			logs: tobikoFailedOutput,
+			expected: &testv1beta1.TestResults{
+				Passed:           28,
+				Failed:           2,
+				ExpectedFailures: 1,
+				FailedTests: []string{
+					"tobiko/tests/faults/ha/test_cloud_recovery.py::DisruptTripleoNodesTest::test_reboot_controller_main_vip",
+					"tobiko/tests/faults/ha/test_cloud_recovery.py::DisruptTripleoNodesTest::test_network_disruptor_main_vip",
+				},
+			},
+		},
+		{
+			name: "multiple pytest runs are summed up",
+			logs: tobikoPassedOutput + tobikoFailedOutput,
+			expected: &testv1beta1.TestResults{
+				Passed:           53,
+				Failed:           2,
+				Skipped:          2,
+				ExpectedFailures: 1,
+				FailedTests: []string{
+					"tobiko/tests/faults/ha/test_cloud_recovery.py::DisruptTripleoNodesTest::test_reboot_controller_main_vip",
+					"tobiko/tests/faults/ha/test_cloud_recovery.py::DisruptTripleoNodesTest::test_network_disruptor_main_vip",
+				},
+			},
+		},
+		{
+			name: "summary with warnings and deselected tests",
+			logs: "====== 3 passed, 5 deselected, 2 warnings in 12.01s ======\n",
+			expected: &testv1beta1.TestResults{
+				Passed: 3,
+			},
+		},
+		{
+			name: "failed tests are listed once and truncated",
+			logs: strings.Join(manyFailedLines, "\n") + "\n" + manyFailedLines[0] +
+				fmt.Sprintf("\n===== %d failed in 1.00s =====\n", MaxFailedTests+10),
+			expected: &testv1beta1.TestResults{
+				Failed:      MaxFailedTests + 10,
+				FailedTests: manyFailedTests,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(ParsePytestResults(tt.logs)).To(Equal(tt.expected))
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package util //nolint:revive // util is a legitimate package name for utility functions
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidScheduleFieldCount indicates that the schedule does not consist
+	// of five fields
+	ErrInvalidScheduleFieldCount = errors.New("expected 5 fields (minute hour day-of-month month day-of-week)")
+
+	// ErrInvalidScheduleValue indicates that a field of the schedule contains
+	// a value that can not be parsed
+	ErrInvalidScheduleValue = errors.New("invalid value")
+
+	// ErrScheduleOutOfRange indicates that a field of the schedule contains
+	// a value that is out of the allowed range
+	ErrScheduleOutOfRange = errors.New("value out of range")
+)
+
+// scheduleSearchLimit is how far in the future Next looks for a matching time
+// before it gives up (e.g. for "0 0 30 2 *").
+const scheduleSearchLimit = 5 * 366 * 24 * time.Hour
+
+// scheduleField describes the allowed values of a single cron field
+type scheduleField struct {
+	name  string
+	min   uint
+	max   uint
+	names map[string]uint
+}
+
+var (
+	minuteField = scheduleField{name: "minute", min: 0, max: 59}
+	hourField   = scheduleField{name: "hour", min: 0, max: 23}
+	domField    = scheduleField{name: "day-of-month", min: 1, max: 31}
+	monthField  = scheduleField{name: "month", min: 1, max: 12, names: map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// Both 0 and 7 stand for Sunday
+	dowField = scheduleField{name: "day-of-week", min: 0, max: 7, names: map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var scheduleMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Schedule is a parsed cron schedule evaluated in UTC. Each field is stored as
+// a bitset of the matching values.
+type Schedule struct {
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+
+	// domRestricted and dowRestricted tell whether the day-of-month and
+	// day-of-week fields were restricted. When both of them are restricted
+	// a day matches when it matches either of them (like in cron).
+	domRestricted bool
+	dowRestricted bool
+}
+
+// ParseSchedule parses the standard five field cron format (minute hour
+// day-of-month month day-of-week). Each field accepts *, values, ranges
+// (1-5), steps (*/15, 1-30/2) and comma separated lists of them. Months and
+// days of the week can be given by their three letter names. The macros
+// @yearly, @annually, @monthly, @weekly, @daily, @midnight and @hourly are
+// supported as well.
+func ParseSchedule(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if macro, ok := scheduleMacros[strings.ToLower(spec)]; ok {
+		spec = macro
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, ErrInvalidScheduleFieldCount
+	}
+
+	schedule := &Schedule{}
+	var err error
+	if schedule.minute, _, err = parseScheduleField(fields[0], minuteField); err != nil {
+		return nil, err
+	}
+	if schedule.hour, _, err = parseScheduleField(fields[1], hourField); err != nil {
+		return nil, err
+	}
+	if schedule.dom, schedule.domRestricted, err = parseScheduleField(fields[2], domField); err != nil {
+		return nil, err
+	}
+	if schedule.month, _, err = parseScheduleField(fields[3], monthField); err != nil {
+		return nil, err
+	}
+	if schedule.dow, schedule.dowRestricted, err = parseScheduleField(fields[4], dowField); err != nil {
+		return nil, err
+	}
+
+	// Sunday can be written as 7
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow |= 1
+	}
+
+	return schedule, nil
+}
+
+// parseScheduleField returns the bitset of values matched by the field and
+// whether the field restricts the values (i.e. it does not start with * or ?)
+func parseScheduleField(value string, field scheduleField) (uint64, bool, error) {
+	var bits uint64
+	for _, part := range strings.Split(value, ",") {
+		partBits, err := parseScheduleRange(part, field)
+		if err != nil {
+			return 0, false, fmt.Errorf("%s %q: %w", field.name, part, err)
+		}
+		bits |= partBits
+	}
+
+	restricted := !strings.HasPrefix(value, "*") && !strings.HasPrefix(value, "?")
+	return bits, restricted, nil
+}
+
+// parseScheduleRange parses a single item of a comma separated field list
+func parseScheduleRange(value string, field scheduleField) (uint64, error) {
+	rangeValue, stepValue, hasStep := strings.Cut(value, "/")
+
+	step := uint(1)
+	if hasStep {
+		parsedStep, err := strconv.ParseUint(stepValue, 10, 8)
+		if err != nil || parsedStep == 0 {
+			return 0, ErrInvalidScheduleValue
+		}
+		step = uint(parsedStep)
+	}
+
+	var start, end uint
+	switch {
+	case rangeValue == "*" || rangeValue == "?":
+		start, end = field.min, field.max
+	case strings.Contains(rangeValue, "-"):
+		startValue, endValue, _ := strings.Cut(rangeValue, "-")
+		var err error
+		if start, err = parseScheduleValue(startValue, field); err != nil {
+			return 0, err
+		}
+		if end, err = parseScheduleValue(endValue, field); err != nil {
+			return 0, err
+		}
+	default:
+		var err error
+		if start, err = parseScheduleValue(rangeValue, field); err != nil {
+			return 0, err
+		}
+		end = start
+
+		// "5/15" means every 15 minutes starting at minute 5
+		if hasStep {
+			end = field.max
+		}
+	}
+
+	if start > end {
+		return 0, ErrScheduleOutOfRange
+	}
+
+	var bits uint64
+	for i := start; i <= end; i += step {
+		bits |= 1 << i
+	}
+
+	return bits, nil
+}
+
+// parseScheduleValue parses a single number or name of the field
+func parseScheduleValue(value string, field scheduleField) (uint, error) {
+	if number, ok := field.names[strings.ToLower(value)]; ok {
+		return number, nil
+	}
+
+	number, err := strconv.ParseUint(value, 10, 8)
+	if err != nil {
+		return 0, ErrInvalidScheduleValue
+	}
+
+	if uint(number) < field.min || uint(number) > field.max {
+		return 0, ErrScheduleOutOfRange
+	}
+
+	return uint(number), nil
+}
+
+// Next returns the first time matching the schedule that is later than the
+// given time. The zero time is returned when there is no such time within
+// the next five years.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(scheduleSearchLimit)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// matchesDay returns true when the day of the given time matches the
+// day-of-month and day-of-week fields of the schedule
+func (s *Schedule) matchesDay(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domRestricted && s.dowRestricted {
+		return domMatch || dowMatch
+	}
+
+	return domMatch && dowMatch
+}
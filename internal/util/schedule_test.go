@@ -0,0 +1,225 @@
+package util //nolint:revive // util is a legitimate package name for utility functions
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega" //revive:disable:dot-imports
+)
+
+func TestParseScheduleErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule string
+		expected error
+	}{
+		{
+			name:     "missing field",
+			schedule: "* * * *",
+			expected: ErrInvalidScheduleFieldCount,
+		},
+		{
+			name:     "unknown macro",
+			schedule: "@every-minute",
+			expected: ErrInvalidScheduleFieldCount,
+		},
+		{
+			name:     "minute out of range",
+			schedule: "60 * * * *",
+			expected: ErrScheduleOutOfRange,
+		},
+		{
+			name:     "day-of-month zero",
+			schedule: "* * 0 * *",
+			expected: ErrScheduleOutOfRange,
+		},
+		{
+			name:     "month out of range",
+			schedule: "* * * 13 *",
+			expected: ErrScheduleOutOfRange,
+		},
+		{
+			name:     "day-of-week out of range",
+			schedule: "* * * * 8",
+			expected: ErrScheduleOutOfRange,
+		},
+		{
+			name:     "reversed range",
+			schedule: "10-5 * * * *",
+			expected: ErrScheduleOutOfRange,
+		},
+		{
+			name:     "zero step",
+			schedule: "*/0 * * * *",
+			expected: ErrInvalidScheduleValue,
+		},
+		{
+			name:     "unknown month name",
+			schedule: "* * * foo *",
+			expected: ErrInvalidScheduleValue,
+		},
+		{
+			name:     "month name in day-of-week",
+			schedule: "* * * * jan",
+			expected: ErrInvalidScheduleValue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			_, err := ParseSchedule(tt.schedule)
+			g.Expect(err).To(MatchError(tt.expected))
+		})
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	date := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	// 2024-01-01 is a Monday
+	tests := []struct {
+		name     string
+		schedule string
+		from     time.Time
+		expected time.Time
+	}{
+		{
+			name:     "every minute",
+			schedule: "* * * * *",
+			from:     time.Date(2024, time.January, 1, 10, 7, 30, 0, time.UTC),
+			expected: date(2024, time.January, 1, 10, 8),
+		},
+		{
+			name:     "the given time does not match itself",
+			schedule: "0 * * * *",
+			from:     date(2024, time.January, 1, 10, 0),
+			expected: date(2024, time.January, 1, 11, 0),
+		},
+		{
+			name:     "list",
+			schedule: "0,30 * * * *",
+			from:     date(2024, time.January, 1, 10, 10),
+			expected: date(2024, time.January, 1, 10, 30),
+		},
+		{
+			name:     "range",
+			schedule: "0 9-17 * * *",
+			from:     date(2024, time.January, 1, 18, 0),
+			expected: date(2024, time.January, 2, 9, 0),
+		},
+		{
+			name:     "range with step",
+			schedule: "0 1-10/3 * * *",
+			from:     date(2024, time.January, 1, 5, 0),
+			expected: date(2024, time.January, 1, 7, 0),
+		},
+		{
+			name:     "step of all values",
+			schedule: "*/15 * * * *",
+			from:     date(2024, time.January, 1, 10, 7),
+			expected: date(2024, time.January, 1, 10, 15),
+		},
+		{
+			name:     "step from a start value",
+			schedule: "5/15 * * * *",
+			from:     date(2024, time.January, 1, 10, 7),
+			expected: date(2024, time.January, 1, 10, 20),
+		},
+		{
+			name:     "step from a start value rolls over to the next hour",
+			schedule: "5/15 * * * *",
+			from:     date(2024, time.January, 1, 10, 50),
+			expected: date(2024, time.January, 1, 11, 5),
+		},
+		{
+			name:     "step on day-of-week",
+			schedule: "0 0 * * */2",
+			from:     date(2024, time.January, 1, 0, 0),
+			expected: date(2024, time.January, 2, 0, 0),
+		},
+		{
+			name:     "named months and days",
+			schedule: "30 6 * jan,jul mon-fri",
+			from:     date(2024, time.January, 31, 7, 0),
+			expected: date(2024, time.July, 1, 6, 30),
+		},
+		{
+			name:     "names are case insensitive",
+			schedule: "0 0 * * SUN",
+			from:     date(2024, time.January, 1, 0, 0),
+			expected: date(2024, time.January, 7, 0, 0),
+		},
+		{
+			name:     "sunday as seven",
+			schedule: "0 0 * * 7",
+			from:     date(2024, time.January, 1, 0, 0),
+			expected: date(2024, time.January, 7, 0, 0),
+		},
+		{
+			name:     "day-of-month only",
+			schedule: "0 0 13 * *",
+			from:     date(2024, time.January, 1, 0, 0),
+			expected: date(2024, time.January, 13, 0, 0),
+		},
+		{
+			name:     "day-of-month or day-of-week matches the day-of-week first",
+			schedule: "0 0 13 * fri",
+			from:     date(2024, time.January, 1, 0, 0),
+			expected: date(2024, time.January, 5, 0, 0),
+		},
+		{
+			name:     "day-of-month or day-of-week matches the day-of-month first",
+			schedule: "0 0 13 * fri",
+			from:     date(2024, time.January, 12, 0, 0),
+			expected: date(2024, time.January, 13, 0, 0),
+		},
+		{
+			name:     "day-of-month skips the months without the day",
+			schedule: "0 0 31 * *",
+			from:     date(2024, time.January, 31, 0, 0),
+			expected: date(2024, time.March, 31, 0, 0),
+		},
+		{
+			name:     "month rolls over to the next year",
+			schedule: "0 0 * * *",
+			from:     date(2024, time.December, 31, 23, 59),
+			expected: date(2025, time.January, 1, 0, 0),
+		},
+		{
+			name:     "yearly macro",
+			schedule: "@yearly",
+			from:     date(2024, time.June, 1, 0, 0),
+			expected: date(2025, time.January, 1, 0, 0),
+		},
+		{
+			name:     "leap day",
+			schedule: "0 12 29 2 *",
+			from:     date(2024, time.March, 1, 0, 0),
+			expected: date(2028, time.February, 29, 12, 0),
+		},
+		{
+			name:     "time in another location",
+			schedule: "0 12 * * *",
+			from:     time.Date(2024, time.January, 1, 13, 30, 0, 0, time.FixedZone("CEST", 2*60*60)),
+			expected: date(2024, time.January, 1, 12, 0),
+		},
+		{
+			name:     "day that never comes",
+			schedule: "0 0 30 2 *",
+			from:     date(2024, time.January, 1, 0, 0),
+			expected: time.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			schedule, err := ParseSchedule(tt.schedule)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(schedule.Next(tt.from)).To(Equal(tt.expected))
+		})
+	}
+}
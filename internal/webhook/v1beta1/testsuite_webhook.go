@@ -0,0 +1,91 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	testv1beta1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
+)
+
+var (
+	// ErrInvalidTestSuiteType is returned when an unexpected object type is passed to the webhook
+	ErrInvalidTestSuiteType = errors.New("invalid object type for TestSuite webhook")
+)
+
+// testsuitelog is for logging in this package.
+var testsuitelog = logf.Log.WithName("testsuite-resource")
+
+// SetupTestSuiteWebhookWithManager registers the webhook for TestSuite in the manager.
+func SetupTestSuiteWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&testv1beta1.TestSuite{}).
+		WithValidator(&TestSuiteCustomValidator{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-test-openstack-org-v1beta1-testsuite,mutating=false,failurePolicy=fail,sideEffects=None,groups=test.openstack.org,resources=testsuites,verbs=create;update,versions=v1beta1,name=vtestsuite-v1beta1.kb.io,admissionReviewVersions=v1
+
+// TestSuiteCustomValidator struct is responsible for validating the TestSuite resource
+// when it is created, updated, or deleted.
+type TestSuiteCustomValidator struct {
+}
+
+var _ webhook.CustomValidator = &TestSuiteCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type TestSuite.
+func (v *TestSuiteCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	testsuite, ok := obj.(*testv1beta1.TestSuite)
+	if !ok {
+		return nil, fmt.Errorf("expected a TestSuite object but got %T: %w", obj, ErrInvalidTestSuiteType)
+	}
+	testsuitelog.Info("Validation for TestSuite upon creation", "name", testsuite.GetName())
+
+	// Call the validation function from api/v1beta1
+	return testsuite.ValidateCreate()
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type TestSuite.
+func (v *TestSuiteCustomValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	testsuite, ok := newObj.(*testv1beta1.TestSuite)
+	if !ok {
+		return nil, fmt.Errorf("expected a TestSuite object for the newObj but got %T: %w", newObj, ErrInvalidTestSuiteType)
+	}
+	testsuitelog.Info("Validation for TestSuite upon update", "name", testsuite.GetName())
+
+	// Call the validation function from api/v1beta1
+	return testsuite.ValidateUpdate(oldObj)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type TestSuite.
+func (v *TestSuiteCustomValidator) ValidateDelete(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	testsuite, ok := obj.(*testv1beta1.TestSuite)
+	if !ok {
+		return nil, fmt.Errorf("expected a TestSuite object but got %T: %w", obj, ErrInvalidTestSuiteType)
+	}
+	testsuitelog.Info("Validation for TestSuite upon deletion", "name", testsuite.GetName())
+
+	// Call the validation function from api/v1beta1
+	return testsuite.ValidateDelete()
+}
@@ -17,13 +17,19 @@ limitations under the License.
 package functional_test
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	. "github.com/onsi/gomega" //revive:disable:dot-imports
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	"github.com/openstack-k8s-operators/lib-common/modules/common/condition"
 	testv1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
+	coordinationv1 "k8s.io/api/coordination/v1"
 	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
@@ -31,6 +37,8 @@ import (
 
 const (
 	TestOperatorConfig          = "test-operator-config"
+	TestOperatorLockName        = "test-operator-lock"
+	TestOperatorLockNamespace   = "test-operator-locks"
 	OpenStackConfigMapName      = "openstack-config"
 	OpenStackConfigSecretName   = "openstack-config-secret" // #nosec G101
 	DefaultStorageClass         = "local-storage"
@@ -100,6 +108,17 @@ func CreateTestOperatorConfigMap(namespace string) *corev1.ConfigMap {
 	}
 }
 
+// CreateTestOperatorResources creates the OpenStack ConfigMap and Secret and
+// the test-operator-config ConfigMap needed by a test CR to spawn test pods
+func CreateTestOperatorResources(namespace string) {
+	openstackConfigMap, openstackSecret := CreateCommonOpenstackResources(namespace)
+	openstackConfigMap.Data["clouds.yaml"] = "clouds:\n  default:\n    auth:\n" +
+		"      auth_url: https://keystone.example.com/v3\n      username: admin"
+	Expect(k8sClient.Create(ctx, openstackConfigMap)).Should(Succeed())
+	Expect(k8sClient.Create(ctx, openstackSecret)).Should(Succeed())
+	Expect(k8sClient.Create(ctx, CreateTestOperatorConfigMap(namespace))).Should(Succeed())
+}
+
 func CreateExtraConfigMap(namespace string, name string) {
 	cm := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
@@ -248,6 +267,19 @@ func GetTestOperatorPVC(namespace string, instanceName string) *corev1.Persisten
 	return &pvc
 }
 
+func GetTestOperatorPVCs(namespace string, instanceName string) []corev1.PersistentVolumeClaim {
+	pvcList := &corev1.PersistentVolumeClaimList{}
+	listOpts := []client.ListOption{
+		client.InNamespace(namespace),
+		client.MatchingLabels{
+			"instanceName": instanceName,
+			"operator":     "test-operator",
+		},
+	}
+	Expect(k8sClient.List(ctx, pvcList, listOpts...)).Should(Succeed())
+	return pvcList.Items
+}
+
 func GetTestOperatorPod(namespace string, instanceName string) *corev1.Pod {
 	var pod corev1.Pod
 	Eventually(func(g Gomega) {
@@ -266,6 +298,102 @@ func GetTestOperatorPod(namespace string, instanceName string) *corev1.Pod {
 	return &pod
 }
 
+func GetTestOperatorPods(namespace string, instanceName string) []corev1.Pod {
+	podList := &corev1.PodList{}
+	listOpts := []client.ListOption{
+		client.InNamespace(namespace),
+		client.MatchingLabels{
+			"instanceName": instanceName,
+			"operator":     "test-operator",
+		},
+	}
+	Expect(k8sClient.List(ctx, podList, listOpts...)).Should(Succeed())
+	return podList.Items
+}
+
+func SetTestOperatorPodPhase(pod *corev1.Pod, phase corev1.PodPhase) {
+	Eventually(func(g Gomega) {
+		updatedPod := &corev1.Pod{}
+		g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), updatedPod)).Should(Succeed())
+		updatedPod.Status.Phase = phase
+		g.Expect(k8sClient.Status().Update(ctx, updatedPod)).Should(Succeed())
+	}, timeout, interval).Should(Succeed())
+}
+
+// SetTestOperatorConfig sets the key of the test-operator-config ConfigMap in
+// the given namespace
+func SetTestOperatorConfig(namespace string, key string, value string) {
+	Eventually(func(g Gomega) {
+		cm := &corev1.ConfigMap{}
+		cmName := types.NamespacedName{Namespace: namespace, Name: TestOperatorConfig}
+		g.Expect(k8sClient.Get(ctx, cmName, cm)).Should(Succeed())
+		cm.Data[key] = value
+		g.Expect(k8sClient.Update(ctx, cm)).Should(Succeed())
+	}, timeout, interval).Should(Succeed())
+}
+
+// SetRerunAnnotation requests a new run of the given test CR in the given
+// rerun mode
+func SetRerunAnnotation(instance client.Object, runID string, rerunMode string) {
+	Eventually(func(g Gomega) {
+		g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(instance), instance)).Should(Succeed())
+		annotations := instance.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[testv1.RerunAnnotation] = runID
+		annotations[testv1.RerunModeAnnotation] = rerunMode
+		instance.SetAnnotations(annotations)
+		g.Expect(k8sClient.Update(ctx, instance)).Should(Succeed())
+	}, timeout, interval).Should(Succeed())
+}
+
+func GetTestOperatorLock(namespace string) *coordinationv1.Lease {
+	lock := &coordinationv1.Lease{}
+	Eventually(func(g Gomega) {
+		g.Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: TestOperatorLockName}, lock)).Should(Succeed())
+	}, timeout, interval).Should(Succeed())
+	return lock
+}
+
+func CreateTestOperatorLease(namespace string, holderIdentity string, renewTime time.Time) *coordinationv1.Lease {
+	leaseDurationSeconds := int32(300)
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      TestOperatorLockName,
+			Namespace: namespace,
+			Labels:    map[string]string{"operator": "test-operator"},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &holderIdentity,
+			LeaseDurationSeconds: &leaseDurationSeconds,
+			AcquireTime:          &metav1.MicroTime{Time: renewTime},
+			RenewTime:            &metav1.MicroTime{Time: renewTime},
+		},
+	}
+}
+
+func ExpectTestOperatorLockReleased(namespace string) {
+	Eventually(func(g Gomega) {
+		lock := &coordinationv1.Lease{}
+		err := k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: TestOperatorLockName}, lock)
+		g.Expect(k8s_errors.IsNotFound(err)).To(BeTrue())
+	}, timeout*2, interval).Should(Succeed())
+}
+
+func GetSemaphoreSlots(group string) []coordinationv1.Lease {
+	leases := &coordinationv1.LeaseList{}
+	Expect(k8sClient.List(ctx, leases, client.InNamespace(TestOperatorLockNamespace))).Should(Succeed())
+
+	slots := []coordinationv1.Lease{}
+	for _, lease := range leases.Items {
+		if strings.HasPrefix(lease.Name, "test-operator-semaphore-"+group+"-") {
+			slots = append(slots, lease)
+		}
+	}
+	return slots
+}
+
 // AnsibleTest helpers
 func CreateAnsibleTest(name types.NamespacedName, spec map[string]any) client.Object {
 	raw := map[string]any{
@@ -407,3 +535,117 @@ func TobikoConditionGetter(name types.NamespacedName) condition.Conditions {
 	instance := GetTobiko(name)
 	return instance.Status.Conditions
 }
+
+// SetTobikoSuspend suspends or resumes the Tobiko instance
+func SetTobikoSuspend(name types.NamespacedName, suspend bool, suspendPolicy testv1.SuspendPolicy) {
+	Eventually(func(g Gomega) {
+		instance := GetTobiko(name)
+		instance.Spec.Suspend = suspend
+		instance.Spec.SuspendPolicy = suspendPolicy
+		g.Expect(k8sClient.Update(ctx, instance)).Should(Succeed())
+	}, timeout, interval).Should(Succeed())
+}
+
+// SetSuspend suspends or resumes the test CR of any kind
+func SetSuspend(instance client.Object, suspend bool, suspendPolicy testv1.SuspendPolicy) {
+	patch := fmt.Sprintf(`{"spec":{"suspend":%t,"suspendPolicy":%q}}`, suspend, suspendPolicy)
+	Eventually(func(g Gomega) {
+		g.Expect(k8sClient.Patch(ctx, instance, client.RawPatch(types.MergePatchType, []byte(patch)))).Should(Succeed())
+	}, timeout, interval).Should(Succeed())
+}
+
+// TestSchedule helpers
+func CreateTestSchedule(name types.NamespacedName, spec map[string]any) client.Object {
+	raw := map[string]any{
+		"apiVersion": "test.openstack.org/v1beta1",
+		"kind":       "TestSchedule",
+		"metadata": map[string]any{
+			"name":      name.Name,
+			"namespace": name.Namespace,
+		},
+		"spec": spec,
+	}
+	return CreateUnstructured(raw)
+}
+
+func GetTestSchedule(name types.NamespacedName) *testv1.TestSchedule {
+	instance := &testv1.TestSchedule{}
+	Eventually(func(g Gomega) {
+		g.Expect(k8sClient.Get(ctx, name, instance)).Should(Succeed())
+	}, timeout, interval).Should(Succeed())
+	return instance
+}
+
+func GetDefaultTestScheduleSpec() map[string]any {
+	return map[string]any{
+		"schedule": "* * * * *",
+		"template": map[string]any{
+			"kind": "Tobiko",
+			"spec": GetDefaultTobikoSpec(),
+		},
+	}
+}
+
+func ScheduleConditionGetter(name types.NamespacedName) condition.Conditions {
+	instance := GetTestSchedule(name)
+	return instance.Status.Conditions
+}
+
+// SetTestScheduleLastScheduleTime moves the last scheduled run of the
+// TestSchedule to the given time so that the runs scheduled since then are
+// started without waiting for the schedule
+func SetTestScheduleLastScheduleTime(name types.NamespacedName, lastScheduleTime time.Time) {
+	Eventually(func(g Gomega) {
+		instance := GetTestSchedule(name)
+		instance.Status.LastScheduleTime = &metav1.Time{Time: lastScheduleTime}
+		g.Expect(k8sClient.Status().Update(ctx, instance)).Should(Succeed())
+	}, timeout, interval).Should(Succeed())
+}
+
+// TestSuite helpers
+func CreateTestSuite(name types.NamespacedName, spec map[string]any) client.Object {
+	raw := map[string]any{
+		"apiVersion": "test.openstack.org/v1beta1",
+		"kind":       "TestSuite",
+		"metadata": map[string]any{
+			"name":      name.Name,
+			"namespace": name.Namespace,
+		},
+		"spec": spec,
+	}
+	return CreateUnstructured(raw)
+}
+
+func GetTestSuite(name types.NamespacedName) *testv1.TestSuite {
+	instance := &testv1.TestSuite{}
+	Eventually(func(g Gomega) {
+		g.Expect(k8sClient.Get(ctx, name, instance)).Should(Succeed())
+	}, timeout, interval).Should(Succeed())
+	return instance
+}
+
+func GetDefaultTestSuiteSpec() map[string]any {
+	return map[string]any{
+		"workflow": []any{
+			map[string]any{
+				"stepName": "first",
+				"template": map[string]any{
+					"kind": "Tobiko",
+					"spec": GetDefaultTobikoSpec(),
+				},
+			},
+			map[string]any{
+				"stepName": "second",
+				"template": map[string]any{
+					"kind": "Tobiko",
+					"spec": GetDefaultTobikoSpec(),
+				},
+			},
+		},
+	}
+}
+
+func SuiteConditionGetter(name types.NamespacedName) condition.Conditions {
+	instance := GetTestSuite(name)
+	return instance.Status.Conditions
+}
@@ -0,0 +1,1471 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package functional_test
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2" //revive:disable:dot-imports
+	. "github.com/onsi/gomega"    //revive:disable:dot-imports
+
+	"github.com/openstack-k8s-operators/lib-common/modules/common/condition"
+	testv1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
+	//revive:disable-next-line:dot-imports
+	. "github.com/openstack-k8s-operators/lib-common/modules/common/test/helpers"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// testKind holds the helpers of a single test kind used by the tables that
+// exercise the shared behaviour with every test kind
+type testKind struct {
+	create          func(name types.NamespacedName, spec map[string]any) client.Object
+	getDefaultSpec  func() map[string]any
+	conditionGetter func(name types.NamespacedName) condition.Conditions
+}
+
+var (
+	tempestKind     = testKind{CreateTempest, GetDefaultTempestSpec, TempestConditionGetter}
+	tobikoKind      = testKind{CreateTobiko, GetDefaultTobikoSpec, TobikoConditionGetter}
+	ansibleTestKind = testKind{CreateAnsibleTest, GetDefaultAnsibleTestSpec, AnsibleTestConditionGetter}
+	horizonTestKind = testKind{CreateHorizonTest, GetDefaultHorizonTestSpec, HorizonTestConditionGetter}
+)
+
+// The behaviour shared by all test kinds is implemented once in
+// CommonReconcile. It is exercised through Tobiko instances, the suspend and
+// cancel paths are exercised with every test kind.
+var _ = Describe("Common controller", func() {
+	var tobikoName types.NamespacedName
+
+	BeforeEach(func() {
+		tobikoName = types.NamespacedName{
+			Name:      "tobiko",
+			Namespace: namespace,
+		}
+
+		CreateTestOperatorResources(namespace)
+	})
+
+	When("A workflow step fails", func() {
+		var spec map[string]any
+
+		BeforeEach(func() {
+			spec = GetDefaultTobikoSpec()
+			spec["workflow"] = []map[string]any{
+				{"stepName": "first-step"},
+				{"stepName": "second-step"},
+			}
+		})
+
+		It("should continue with the next step by default", func() {
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, spec))
+
+			pod := GetTestOperatorPod(namespace, tobikoName.Name)
+			SetTestOperatorPodPhase(pod, corev1.PodFailed)
+
+			Eventually(func(g Gomega) {
+				g.Expect(GetTestOperatorPods(namespace, tobikoName.Name)).To(HaveLen(2))
+			}, timeout*2, interval).Should(Succeed())
+		})
+
+		It("should skip the remaining steps with StopOnFirstFailure policy", func() {
+			spec["workflowFailurePolicy"] = "StopOnFirstFailure"
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, spec))
+
+			pod := GetTestOperatorPod(namespace, tobikoName.Name)
+			SetTestOperatorPodPhase(pod, corev1.PodFailed)
+
+			Eventually(func(g Gomega) {
+				steps := GetTobiko(tobikoName).Status.Steps
+				g.Expect(steps).To(HaveLen(2))
+				g.Expect(steps[0].Phase).To(Equal(testv1.WorkflowStepFailed))
+				g.Expect(steps[1].Phase).To(Equal(testv1.WorkflowStepSkipped))
+			}, timeout*2, interval).Should(Succeed())
+
+			Expect(GetTestOperatorPods(namespace, tobikoName.Name)).To(HaveLen(1))
+			ExpectTestOperatorLockReleased(namespace)
+		})
+	})
+
+	When("A test pod fails and backoffLimit is set", func() {
+		BeforeEach(func() {
+			spec := GetDefaultTobikoSpec()
+			spec["backoffLimit"] = 1
+			spec["backoffDelay"] = 0
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, spec))
+		})
+
+		It("should retry the failed pod up to backoffLimit times", func() {
+			pod := GetTestOperatorPod(namespace, tobikoName.Name)
+			SetTestOperatorPodPhase(pod, corev1.PodFailed)
+
+			var retryPod corev1.Pod
+			Eventually(func(g Gomega) {
+				pods := GetTestOperatorPods(namespace, tobikoName.Name)
+				g.Expect(pods).To(HaveLen(2))
+				g.Expect(pods).To(ContainElement(HaveField("ObjectMeta.Name", tobikoName.Name+"-retry-1"), &retryPod))
+				g.Expect(retryPod.Labels).To(HaveKeyWithValue("attempt", "1"))
+
+				steps := GetTobiko(tobikoName).Status.Steps
+				g.Expect(steps).To(HaveLen(1))
+				g.Expect(steps[0].Attempts).To(Equal(2))
+			}, timeout*2, interval).Should(Succeed())
+
+			SetTestOperatorPodPhase(&retryPod, corev1.PodFailed)
+
+			Eventually(func(g Gomega) {
+				steps := GetTobiko(tobikoName).Status.Steps
+				g.Expect(steps).To(HaveLen(1))
+				g.Expect(steps[0].Phase).To(Equal(testv1.WorkflowStepFailed))
+			}, timeout*2, interval).Should(Succeed())
+
+			Expect(GetTestOperatorPods(namespace, tobikoName.Name)).To(HaveLen(2))
+			ExpectTestOperatorLockReleased(namespace)
+		})
+	})
+
+	When("The name of a retried test pod exceeds the DNS label length", func() {
+		It("should be rejected by the webhook", func() {
+			spec := GetDefaultTobikoSpec()
+			spec["backoffLimit"] = 1
+			tobiko := &unstructured.Unstructured{Object: map[string]any{
+				"apiVersion": "test.openstack.org/v1beta1",
+				"kind":       "Tobiko",
+				"metadata": map[string]any{
+					// The name fits without the -retry-1 suffix
+					"name":      strings.Repeat("t", 56),
+					"namespace": namespace,
+				},
+				"spec": spec,
+			}}
+			err := k8sClient.Create(ctx, tobiko)
+			Expect(k8s_errors.IsInvalid(err)).To(BeTrue())
+		})
+	})
+
+	When("A test pod finishes", func() {
+		BeforeEach(func() {
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, GetDefaultTobikoSpec()))
+		})
+
+		It("should report the test pod in status.steps", func() {
+			pod := GetTestOperatorPod(namespace, tobikoName.Name)
+			pvc := GetTestOperatorPVC(namespace, tobikoName.Name)
+			SetTestOperatorPodPhase(pod, corev1.PodSucceeded)
+
+			Eventually(func(g Gomega) {
+				status := GetTobiko(tobikoName).Status
+				g.Expect(status.Steps).To(HaveLen(1))
+				g.Expect(status.Steps[0].Name).To(Equal(tobikoName.Name))
+				g.Expect(status.Steps[0].Phase).To(Equal(testv1.WorkflowStepSucceeded))
+				g.Expect(status.Steps[0].PodName).To(Equal(pod.Name))
+				g.Expect(status.Steps[0].LogsPVC).To(Equal(pvc.Name))
+
+				g.Expect(status.StepsSummary).ToNot(BeNil())
+				g.Expect(status.StepsSummary.Total).To(Equal(1))
+				g.Expect(status.StepsSummary.Succeeded).To(Equal(1))
+				g.Expect(status.StepsSummary.Failed).To(Equal(0))
+			}, timeout*2, interval).Should(Succeed())
+		})
+
+		It("should set TestsPassed to true when the tests pass", func() {
+			pod := GetTestOperatorPod(namespace, tobikoName.Name)
+			SetTestOperatorPodPhase(pod, corev1.PodSucceeded)
+
+			th.ExpectCondition(
+				tobikoName,
+				ConditionGetterFunc(TobikoConditionGetter),
+				testv1.ExecutionCompletedCondition,
+				corev1.ConditionTrue,
+			)
+			th.ExpectCondition(
+				tobikoName,
+				ConditionGetterFunc(TobikoConditionGetter),
+				testv1.TestsPassedCondition,
+				corev1.ConditionTrue,
+			)
+		})
+
+		It("should distinguish failed tests from a completed execution", func() {
+			pod := GetTestOperatorPod(namespace, tobikoName.Name)
+			SetTestOperatorPodPhase(pod, corev1.PodFailed)
+
+			th.ExpectCondition(
+				tobikoName,
+				ConditionGetterFunc(TobikoConditionGetter),
+				testv1.ExecutionCompletedCondition,
+				corev1.ConditionTrue,
+			)
+			th.ExpectConditionWithDetails(
+				tobikoName,
+				ConditionGetterFunc(TobikoConditionGetter),
+				testv1.TestsPassedCondition,
+				corev1.ConditionFalse,
+				testv1.TestsFailedReason,
+				fmt.Sprintf(testv1.TestsPassedErrorMessage, tobikoName.Name),
+			)
+			th.ExpectCondition(
+				tobikoName,
+				ConditionGetterFunc(TobikoConditionGetter),
+				condition.ReadyCondition,
+				corev1.ConditionTrue,
+			)
+			ExpectTestOperatorLockReleased(namespace)
+		})
+
+		It("should report an infrastructure failure when the pod is OOMKilled", func() {
+			pod := GetTestOperatorPod(namespace, tobikoName.Name)
+			Eventually(func(g Gomega) {
+				updatedPod := &corev1.Pod{}
+				g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), updatedPod)).Should(Succeed())
+				updatedPod.Status.Phase = corev1.PodFailed
+				updatedPod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+					Name: pod.Spec.Containers[0].Name,
+					State: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{
+							ExitCode: 137,
+							Reason:   "OOMKilled",
+						},
+					},
+				}}
+				g.Expect(k8sClient.Status().Update(ctx, updatedPod)).Should(Succeed())
+			}, timeout, interval).Should(Succeed())
+
+			th.ExpectConditionWithDetails(
+				tobikoName,
+				ConditionGetterFunc(TobikoConditionGetter),
+				testv1.ExecutionCompletedCondition,
+				corev1.ConditionFalse,
+				testv1.InfrastructureFailureReason,
+				fmt.Sprintf(testv1.ExecutionCompletedInfrastructureErrorMessage, tobikoName.Name+" (OOMKilled)"),
+			)
+			th.ExpectConditionWithDetails(
+				tobikoName,
+				ConditionGetterFunc(TobikoConditionGetter),
+				testv1.TestsPassedCondition,
+				corev1.ConditionFalse,
+				testv1.InfrastructureFailureReason,
+				testv1.TestsPassedUnavailableMessage,
+			)
+			ExpectTestOperatorLockReleased(namespace)
+		})
+
+		It("should start a new run when the rerun annotation changes", func() {
+			pod := GetTestOperatorPod(namespace, tobikoName.Name)
+			pvc := GetTestOperatorPVC(namespace, tobikoName.Name)
+			SetTestOperatorPodPhase(pod, corev1.PodFailed)
+
+			th.ExpectCondition(
+				tobikoName,
+				ConditionGetterFunc(TobikoConditionGetter),
+				testv1.ExecutionCompletedCondition,
+				corev1.ConditionTrue,
+			)
+
+			SetRerunAnnotation(GetTobiko(tobikoName), "second", testv1.RerunModeAll)
+
+			var rerunPod corev1.Pod
+			Eventually(func(g Gomega) {
+				pods := GetTestOperatorPods(namespace, tobikoName.Name)
+				g.Expect(pods).To(HaveLen(2))
+				for _, p := range pods {
+					if p.Name != pod.Name {
+						rerunPod = p
+					}
+				}
+			}, timeout, interval).Should(Succeed())
+			Expect(rerunPod.Name).To(Equal(tobikoName.Name + "-run-1"))
+			Expect(rerunPod.Labels).To(HaveKeyWithValue("runIndex", "1"))
+
+			Eventually(func(g Gomega) {
+				status := GetTobiko(tobikoName).Status
+				g.Expect(status.RunIndex).To(Equal(1))
+				g.Expect(status.RunID).To(Equal("second"))
+				g.Expect(status.Runs).To(HaveLen(1))
+				g.Expect(status.Runs[0].Index).To(Equal(0))
+				g.Expect(status.Runs[0].Passed).To(BeFalse())
+				g.Expect(status.Runs[0].Reason).To(Equal(string(testv1.TestsFailedReason)))
+				g.Expect(status.Runs[0].Steps).To(HaveLen(1))
+				g.Expect(status.Runs[0].Steps[0].PodName).To(Equal(pod.Name))
+				g.Expect(status.Steps).To(HaveLen(1))
+				g.Expect(status.Steps[0].PodName).To(Equal(rerunPod.Name))
+				g.Expect(status.Steps[0].LogsPVC).To(Equal(pvc.Name + "-run-1"))
+			}, timeout, interval).Should(Succeed())
+
+			SetTestOperatorPodPhase(&rerunPod, corev1.PodSucceeded)
+			th.ExpectCondition(
+				tobikoName,
+				ConditionGetterFunc(TobikoConditionGetter),
+				testv1.TestsPassedCondition,
+				corev1.ConditionTrue,
+			)
+			Expect(GetTestOperatorPods(namespace, tobikoName.Name)).To(HaveLen(2))
+		})
+	})
+
+	When("A test pod is stuck in the Pending phase", func() {
+		var spec map[string]any
+
+		BeforeEach(func() {
+			spec = GetDefaultTobikoSpec()
+		})
+
+		It("should report why the pod can not start", func() {
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, spec))
+
+			pod := GetTestOperatorPod(namespace, tobikoName.Name)
+			Eventually(func(g Gomega) {
+				updatedPod := &corev1.Pod{}
+				g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), updatedPod)).Should(Succeed())
+				updatedPod.Status.Phase = corev1.PodPending
+				updatedPod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+					Name: pod.Spec.Containers[0].Name,
+					State: corev1.ContainerState{
+						Waiting: &corev1.ContainerStateWaiting{
+							Reason:  "ImagePullBackOff",
+							Message: "Back-off pulling image",
+						},
+					},
+				}}
+				g.Expect(k8sClient.Status().Update(ctx, updatedPod)).Should(Succeed())
+			}, timeout, interval).Should(Succeed())
+
+			th.ExpectConditionWithDetails(
+				tobikoName,
+				ConditionGetterFunc(TobikoConditionGetter),
+				testv1.TestPodsStartedCondition,
+				corev1.ConditionFalse,
+				testv1.ImagePullFailureReason,
+				fmt.Sprintf(testv1.TestPodsStartedPendingMessage, pod.Name, "ImagePullBackOff: Back-off pulling image"),
+			)
+		})
+
+		It("should fail the step when pendingTimeout expires", func() {
+			spec["pendingTimeout"] = 1
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, spec))
+
+			pod := GetTestOperatorPod(namespace, tobikoName.Name)
+
+			Eventually(func(g Gomega) {
+				steps := GetTobiko(tobikoName).Status.Steps
+				g.Expect(steps).To(HaveLen(1))
+				g.Expect(steps[0].Phase).To(Equal(testv1.WorkflowStepFailed))
+				g.Expect(steps[0].PodName).To(Equal(pod.Name))
+				g.Expect(steps[0].PodDeleted).To(BeTrue())
+				g.Expect(steps[0].Reason).To(Equal("PendingTimeout"))
+				g.Expect(steps[0].Message).To(Equal("the pod stayed in the Pending phase"))
+			}, timeout*2, interval).Should(Succeed())
+
+			Expect(GetTestOperatorPods(namespace, tobikoName.Name)).To(BeEmpty())
+
+			th.ExpectConditionWithDetails(
+				tobikoName,
+				ConditionGetterFunc(TobikoConditionGetter),
+				testv1.TestPodsStartedCondition,
+				corev1.ConditionFalse,
+				testv1.PendingTimeoutReason,
+				fmt.Sprintf(testv1.TestPodsStartedPendingTimeoutMessage, pod.Name, "the pod stayed in the Pending phase"),
+			)
+			ExpectTestOperatorLockReleased(namespace)
+		})
+	})
+
+	When("A workflow step has a timeout", func() {
+		var spec map[string]any
+
+		BeforeEach(func() {
+			spec = GetDefaultTobikoSpec()
+			spec["workflow"] = []map[string]any{
+				{"stepName": "first-step", "timeout": 1},
+				{"stepName": "second-step"},
+			}
+		})
+
+		It("should delete the pod that did not start and continue with the next step", func() {
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, spec))
+
+			pod := GetTestOperatorPod(namespace, tobikoName.Name)
+			Expect(pod.Spec.ActiveDeadlineSeconds).To(HaveValue(BeEquivalentTo(1)))
+
+			Eventually(func(g Gomega) {
+				pods := GetTestOperatorPods(namespace, tobikoName.Name)
+				g.Expect(pods).To(HaveLen(1))
+				g.Expect(pods[0].Name).NotTo(Equal(pod.Name))
+
+				steps := GetTobiko(tobikoName).Status.Steps
+				g.Expect(steps).To(HaveLen(2))
+				g.Expect(steps[0].Phase).To(Equal(testv1.WorkflowStepTimedOut))
+				g.Expect(steps[0].PodName).To(Equal(pod.Name))
+				g.Expect(steps[0].PodDeleted).To(BeTrue())
+				g.Expect(steps[0].Reason).To(Equal("DeadlineExceeded"))
+				g.Expect(steps[0].FinishTime).NotTo(BeNil())
+			}, timeout*2, interval).Should(Succeed())
+
+			updatedPod := &corev1.Pod{}
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), updatedPod)
+			Expect(k8s_errors.IsNotFound(err) || !updatedPod.DeletionTimestamp.IsZero()).To(BeTrue())
+		})
+
+		It("should skip the remaining steps with StopOnFirstFailure policy", func() {
+			spec["workflowFailurePolicy"] = "StopOnFirstFailure"
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, spec))
+
+			Eventually(func(g Gomega) {
+				steps := GetTobiko(tobikoName).Status.Steps
+				g.Expect(steps).To(HaveLen(2))
+				g.Expect(steps[0].Phase).To(Equal(testv1.WorkflowStepTimedOut))
+				g.Expect(steps[1].Phase).To(Equal(testv1.WorkflowStepSkipped))
+			}, timeout*2, interval).Should(Succeed())
+
+			Expect(GetTestOperatorPods(namespace, tobikoName.Name)).To(BeEmpty())
+			ExpectTestOperatorLockReleased(namespace)
+		})
+	})
+
+	When("A workflow step has a runIf condition", func() {
+		BeforeEach(func() {
+			spec := GetDefaultTobikoSpec()
+			spec["workflow"] = []map[string]any{
+				{"stepName": "smoke"},
+				{
+					"stepName": "diagnostics",
+					"runIf": map[string]any{
+						"stepName": "smoke",
+						"outcome":  "Failed",
+					},
+				},
+				{"stepName": "faults"},
+			}
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, spec))
+		})
+
+		It("should skip the step when the condition is not met", func() {
+			pod := GetTestOperatorPod(namespace, tobikoName.Name)
+			SetTestOperatorPodPhase(pod, corev1.PodSucceeded)
+
+			Eventually(func(g Gomega) {
+				pods := GetTestOperatorPods(namespace, tobikoName.Name)
+				g.Expect(pods).To(HaveLen(2))
+				g.Expect(pods).To(ContainElement(HaveField("ObjectMeta.Labels", HaveKeyWithValue("workflowStep", "2"))))
+
+				steps := GetTobiko(tobikoName).Status.Steps
+				g.Expect(steps).To(HaveLen(3))
+				g.Expect(steps[1].Phase).To(Equal(testv1.WorkflowStepSkipped))
+			}, timeout*2, interval).Should(Succeed())
+		})
+
+		It("should reject an update that makes the condition refer to a later step", func() {
+			tobiko := GetTobiko(tobikoName)
+			tobiko.Spec.Workflow[1].RunIf.StepName = "faults"
+			err := k8sClient.Update(ctx, tobiko)
+			Expect(k8s_errors.IsInvalid(err)).To(BeTrue())
+		})
+	})
+
+	When("An instance is suspended", func() {
+		var spec map[string]any
+
+		BeforeEach(func() {
+			spec = GetDefaultTobikoSpec()
+			spec["workflow"] = []map[string]any{
+				{"stepName": "first"},
+				{"stepName": "second"},
+			}
+		})
+
+		DescribeTable("should not spawn test pods until it is resumed",
+			func(kind testKind) {
+				name := types.NamespacedName{Name: "test", Namespace: namespace}
+				kindSpec := kind.getDefaultSpec()
+				kindSpec["suspend"] = true
+				instance := kind.create(name, kindSpec)
+				DeferCleanup(th.DeleteInstance, instance)
+
+				th.ExpectConditionWithDetails(
+					name,
+					ConditionGetterFunc(kind.conditionGetter),
+					testv1.ExecutionCompletedCondition,
+					corev1.ConditionFalse,
+					testv1.SuspendedReason,
+					testv1.ExecutionCompletedSuspendedMessage,
+				)
+				Consistently(func(g Gomega) {
+					g.Expect(GetTestOperatorPods(namespace, name.Name)).To(BeEmpty())
+				}, timeout, interval).Should(Succeed())
+
+				SetSuspend(instance, false, testv1.SuspendPolicyWait)
+
+				pod := GetTestOperatorPod(namespace, name.Name)
+				Expect(pod.Labels).To(HaveKeyWithValue("workflowStep", "0"))
+			},
+			Entry("with Tempest", tempestKind),
+			Entry("with Tobiko", tobikoKind),
+			Entry("with AnsibleTest", ansibleTestKind),
+			Entry("with HorizonTest", horizonTestKind),
+		)
+
+		It("should finish the running pod and continue with the next step once resumed", func() {
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, spec))
+
+			firstPod := GetTestOperatorPod(namespace, tobikoName.Name)
+			SetTobikoSuspend(tobikoName, true, testv1.SuspendPolicyWait)
+
+			th.ExpectConditionWithDetails(
+				tobikoName,
+				ConditionGetterFunc(TobikoConditionGetter),
+				testv1.ExecutionCompletedCondition,
+				corev1.ConditionFalse,
+				testv1.SuspendedReason,
+				testv1.ExecutionCompletedSuspendedMessage,
+			)
+			Expect(GetTestOperatorLock(namespace).Spec.HolderIdentity).ToNot(BeNil())
+
+			SetTestOperatorPodPhase(firstPod, corev1.PodSucceeded)
+			ExpectTestOperatorLockReleased(namespace)
+			Consistently(func(g Gomega) {
+				g.Expect(GetTestOperatorPods(namespace, tobikoName.Name)).To(HaveLen(1))
+			}, timeout, interval).Should(Succeed())
+
+			SetTobikoSuspend(tobikoName, false, testv1.SuspendPolicyWait)
+
+			Eventually(func(g Gomega) {
+				pods := GetTestOperatorPods(namespace, tobikoName.Name)
+				g.Expect(pods).To(HaveLen(2))
+				g.Expect(pods).To(ContainElement(HaveField("ObjectMeta.Labels", HaveKeyWithValue("workflowStep", "1"))))
+			}, timeout*2, interval).Should(Succeed())
+		})
+
+		DescribeTable("should delete the running pod with the Terminate policy",
+			func(kind testKind) {
+				name := types.NamespacedName{Name: "test", Namespace: namespace}
+				instance := kind.create(name, kind.getDefaultSpec())
+				DeferCleanup(th.DeleteInstance, instance)
+
+				firstPod := GetTestOperatorPod(namespace, name.Name)
+				SetSuspend(instance, true, testv1.SuspendPolicyTerminate)
+
+				Eventually(func(g Gomega) {
+					g.Expect(GetTestOperatorPods(namespace, name.Name)).To(BeEmpty())
+				}, timeout*2, interval).Should(Succeed())
+				ExpectTestOperatorLockReleased(namespace)
+
+				SetSuspend(instance, false, testv1.SuspendPolicyTerminate)
+
+				pod := GetTestOperatorPod(namespace, name.Name)
+				Expect(pod.Name).To(Equal(firstPod.Name))
+				Expect(pod.UID).ToNot(Equal(firstPod.UID))
+			},
+			Entry("with Tempest", tempestKind),
+			Entry("with Tobiko", tobikoKind),
+			Entry("with AnsibleTest", ansibleTestKind),
+			Entry("with HorizonTest", horizonTestKind),
+		)
+	})
+
+	When("An instance is deleted", func() {
+		var spec map[string]any
+
+		BeforeEach(func() {
+			spec = GetDefaultTobikoSpec()
+		})
+
+		DescribeTable("should cancel the testing and release the lock",
+			func(kind testKind) {
+				name := types.NamespacedName{Name: "test", Namespace: namespace}
+				instance := kind.create(name, kind.getDefaultSpec())
+				DeferCleanup(th.DeleteInstance, instance)
+
+				pod := GetTestOperatorPod(namespace, name.Name)
+				Expect(pod.Spec.TerminationGracePeriodSeconds).To(HaveValue(BeEquivalentTo(30)))
+				Expect(k8sClient.Get(ctx, name, instance)).Should(Succeed())
+				Expect(instance.GetFinalizers()).ToNot(BeEmpty())
+				Expect(GetTestOperatorLock(namespace).Spec.HolderIdentity).ToNot(BeNil())
+
+				th.DeleteInstance(instance)
+
+				Expect(GetTestOperatorPods(namespace, name.Name)).To(BeEmpty())
+				ExpectTestOperatorLockReleased(namespace)
+				Eventually(func(g Gomega) {
+					events := &corev1.EventList{}
+					g.Expect(k8sClient.List(ctx, events, client.InNamespace(namespace))).Should(Succeed())
+					g.Expect(events.Items).To(ContainElement(And(
+						HaveField("Reason", "Cancelled"),
+						HaveField("InvolvedObject.Name", name.Name),
+						HaveField("InvolvedObject.Kind", instance.GetObjectKind().GroupVersionKind().Kind),
+					)))
+				}, timeout*2, interval).Should(Succeed())
+			},
+			Entry("with Tempest", tempestKind),
+			Entry("with Tobiko", tobikoKind),
+			Entry("with AnsibleTest", ansibleTestKind),
+			Entry("with HorizonTest", horizonTestKind),
+		)
+
+		It("should keep the logs PVC with the Retain policy", func() {
+			spec["logsPVCRetentionPolicy"] = "Retain"
+			tobiko := CreateTobiko(tobikoName, spec)
+			DeferCleanup(th.DeleteInstance, tobiko)
+
+			pvc := GetTestOperatorPVC(namespace, tobikoName.Name)
+			Expect(pvc.OwnerReferences).ToNot(BeEmpty())
+			DeferCleanup(k8sClient.Delete, ctx, pvc)
+
+			th.DeleteInstance(tobiko)
+
+			pvc = GetTestOperatorPVC(namespace, tobikoName.Name)
+			Expect(pvc.OwnerReferences).To(BeEmpty())
+		})
+	})
+
+	When("An instance has logsRetention rules", func() {
+		var spec map[string]any
+
+		BeforeEach(func() {
+			spec = GetDefaultTobikoSpec()
+		})
+
+		It("should delete the logs PVC of a passed run with deleteOnSuccess", func() {
+			spec["logsRetention"] = map[string]any{"deleteOnSuccess": true}
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, spec))
+
+			GetTestOperatorPVC(namespace, tobikoName.Name)
+			SetTestOperatorPodPhase(GetTestOperatorPod(namespace, tobikoName.Name), corev1.PodSucceeded)
+
+			Eventually(func(g Gomega) {
+				// The PVC protection may keep the deleted PVC around in envtest
+				for _, pvc := range GetTestOperatorPVCs(namespace, tobikoName.Name) {
+					g.Expect(pvc.DeletionTimestamp).ToNot(BeNil())
+				}
+				reclaimed := GetTobiko(tobikoName).Status.ReclaimedLogsStorage
+				g.Expect(reclaimed).ToNot(BeNil())
+				g.Expect(reclaimed.String()).To(Equal("1Gi"))
+			}, timeout*2, interval).Should(Succeed())
+
+			Eventually(func(g Gomega) {
+				events := &corev1.EventList{}
+				g.Expect(k8sClient.List(ctx, events, client.InNamespace(namespace))).Should(Succeed())
+				g.Expect(events.Items).To(ContainElement(And(
+					HaveField("Reason", "LogsReclaimed"),
+					HaveField("InvolvedObject.Name", tobikoName.Name),
+				)))
+			}, timeout*2, interval).Should(Succeed())
+		})
+
+		It("should keep the logs PVC of a failed run with keepOnFailure", func() {
+			spec["logsRetention"] = map[string]any{
+				"keepOnFailure":           true,
+				"ttlSecondsAfterFinished": 1,
+			}
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, spec))
+
+			GetTestOperatorPVC(namespace, tobikoName.Name)
+			SetTestOperatorPodPhase(GetTestOperatorPod(namespace, tobikoName.Name), corev1.PodFailed)
+
+			th.ExpectCondition(
+				tobikoName,
+				ConditionGetterFunc(TobikoConditionGetter),
+				testv1.ExecutionCompletedCondition,
+				corev1.ConditionTrue,
+			)
+			Consistently(func(g Gomega) {
+				g.Expect(GetTestOperatorPVCs(namespace, tobikoName.Name)).To(HaveLen(1))
+			}, timeout, interval).Should(Succeed())
+		})
+	})
+
+	When("An instance sets logsVolume options", func() {
+		var spec map[string]any
+
+		BeforeEach(func() {
+			spec = GetDefaultTobikoSpec()
+		})
+
+		It("should create the logs PVC with the given size and access modes", func() {
+			spec["logsVolume"] = map[string]any{
+				"size":        "5Gi",
+				"accessModes": []any{"ReadWriteMany"},
+			}
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, spec))
+
+			pvc := GetTestOperatorPVC(namespace, tobikoName.Name)
+			Expect(pvc.Spec.Resources.Requests.Storage().String()).To(Equal("5Gi"))
+			Expect(pvc.Spec.AccessModes).To(Equal([]corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}))
+		})
+
+		It("should use the logsVolume options of the workflow step", func() {
+			spec["workflow"] = []map[string]any{
+				{
+					"stepName":   "first",
+					"logsVolume": map[string]any{"size": "3Gi"},
+				},
+			}
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, spec))
+
+			pvc := GetTestOperatorPVC(namespace, tobikoName.Name)
+			Expect(pvc.Spec.Resources.Requests.Storage().String()).To(Equal("3Gi"))
+		})
+
+		It("should store the logs in an emptyDir volume with the EmptyDir type", func() {
+			spec["logsVolume"] = map[string]any{
+				"type": "EmptyDir",
+				"size": "2Gi",
+			}
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, spec))
+
+			pod := GetTestOperatorPod(namespace, tobikoName.Name)
+			var logsVolume corev1.Volume
+			Expect(pod.Spec.Volumes).To(ContainElement(HaveField("Name", "test-operator-logs"), &logsVolume))
+			Expect(logsVolume.PersistentVolumeClaim).To(BeNil())
+			Expect(logsVolume.EmptyDir).ToNot(BeNil())
+			Expect(logsVolume.EmptyDir.SizeLimit.String()).To(Equal("2Gi"))
+			Expect(GetTestOperatorPVCs(namespace, tobikoName.Name)).To(BeEmpty())
+		})
+
+		It("should be rejected by the webhook with a read-only access mode", func() {
+			spec["logsVolume"] = map[string]any{
+				"accessModes": []any{"ReadOnlyMany"},
+			}
+			tobiko := &unstructured.Unstructured{Object: map[string]any{
+				"apiVersion": "test.openstack.org/v1beta1",
+				"kind":       "Tobiko",
+				"metadata": map[string]any{
+					"name":      tobikoName.Name,
+					"namespace": tobikoName.Namespace,
+				},
+				"spec": spec,
+			}}
+			Expect(k8sClient.Create(ctx, tobiko)).ShouldNot(Succeed())
+		})
+	})
+
+	When("An instance enables the artifactsServer", func() {
+		var spec map[string]any
+		var serverName types.NamespacedName
+
+		BeforeEach(func() {
+			spec = GetDefaultTobikoSpec()
+			spec["artifactsServer"] = true
+			serverName = types.NamespacedName{
+				Name:      tobikoName.Name + "-artifacts",
+				Namespace: namespace,
+			}
+		})
+
+		It("should serve the logs PVC once the testing is finished", func() {
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, spec))
+
+			logsPVC := GetTestOperatorPVC(namespace, tobikoName.Name)
+			testPod := GetTestOperatorPod(namespace, tobikoName.Name)
+			Expect(k8sClient.Get(ctx, serverName, &corev1.Pod{})).ShouldNot(Succeed())
+			Expect(GetTobiko(tobikoName).Status.ArtifactsURL).To(BeEmpty())
+
+			SetTestOperatorPodPhase(testPod, corev1.PodSucceeded)
+
+			Eventually(func(g Gomega) {
+				status := GetTobiko(tobikoName).Status
+				g.Expect(status.ArtifactsURL).To(Equal(
+					fmt.Sprintf("http://%s.%s.svc:8080/", serverName.Name, namespace)))
+				g.Expect(status.ArtifactsServerStartTime).ToNot(BeNil())
+			}, timeout, interval).Should(Succeed())
+
+			tobiko := GetTobiko(tobikoName)
+			serverPod := &corev1.Pod{}
+			Expect(k8sClient.Get(ctx, serverName, serverPod)).Should(Succeed())
+			Expect(metav1.IsControlledBy(serverPod, tobiko)).To(BeTrue())
+			Expect(serverPod.Labels).ToNot(HaveKey("instanceName"))
+			Expect(serverPod.Spec.Volumes).To(HaveLen(1))
+			Expect(serverPod.Spec.Volumes[0].PersistentVolumeClaim).ToNot(BeNil())
+			Expect(serverPod.Spec.Volumes[0].PersistentVolumeClaim.ClaimName).To(Equal(logsPVC.Name))
+			Expect(serverPod.Spec.Volumes[0].PersistentVolumeClaim.ReadOnly).To(BeTrue())
+			Expect(serverPod.Spec.Containers[0].VolumeMounts).To(ContainElement(And(
+				HaveField("MountPath", "/var/lib/test-operator-artifacts/"+logsPVC.Name),
+				HaveField("ReadOnly", true),
+			)))
+
+			service := &corev1.Service{}
+			Expect(k8sClient.Get(ctx, serverName, service)).Should(Succeed())
+			Expect(metav1.IsControlledBy(service, tobiko)).To(BeTrue())
+			Expect(service.Spec.Selector).To(Equal(serverPod.Labels))
+			Expect(service.Spec.Ports).To(ContainElement(HaveField("Port", int32(8080))))
+		})
+
+		It("should delete the artifacts server once its TTL expired", func() {
+			spec["artifactsServerTTLSeconds"] = 1
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, spec))
+
+			SetTestOperatorPodPhase(GetTestOperatorPod(namespace, tobikoName.Name), corev1.PodSucceeded)
+
+			Eventually(func(g Gomega) {
+				g.Expect(GetTobiko(tobikoName).Status.ArtifactsURL).ToNot(BeEmpty())
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func(g Gomega) {
+				status := GetTobiko(tobikoName).Status
+				g.Expect(status.ArtifactsURL).To(BeEmpty())
+				g.Expect(status.ArtifactsServerStartTime).ToNot(BeNil())
+				g.Expect(k8s_errors.IsNotFound(k8sClient.Get(ctx, serverName, &corev1.Pod{}))).To(BeTrue())
+				g.Expect(k8s_errors.IsNotFound(k8sClient.Get(ctx, serverName, &corev1.Service{}))).To(BeTrue())
+			}, timeout*2, interval).Should(Succeed())
+		})
+	})
+
+	When("An instance sets artifactsUpload", func() {
+		var spec map[string]any
+
+		BeforeEach(func() {
+			spec = GetDefaultTobikoSpec()
+			spec["artifactsUpload"] = map[string]any{
+				"secretName": "minio-credentials",
+				"bucket":     "test-logs",
+				"endpoint":   "http://minio.minio.svc:9000",
+				"prefix":     "ci",
+			}
+
+			// The uploader has no default image
+			SetTestOperatorConfig(namespace, "artifacts-uploader-image", "quay.io/example/aws-cli:latest")
+		})
+
+		It("should report the missing image of the uploader", func() {
+			SetTestOperatorConfig(namespace, "artifacts-uploader-image", "")
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, spec))
+
+			testPod := GetTestOperatorPod(namespace, tobikoName.Name)
+			SetTestOperatorPodPhase(testPod, corev1.PodSucceeded)
+
+			Eventually(func(g Gomega) {
+				uploaded := GetTobiko(tobikoName).Status.Conditions.Get(testv1.ArtifactsUploadedCondition)
+				g.Expect(uploaded).ToNot(BeNil())
+				g.Expect(uploaded.Status).To(Equal(corev1.ConditionFalse))
+				g.Expect(uploaded.Reason).To(Equal(condition.ErrorReason))
+			}, timeout, interval).Should(Succeed())
+
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Name:      testPod.Name + "-upload-1",
+				Namespace: namespace,
+			}, &corev1.Pod{})).ShouldNot(Succeed())
+		})
+
+		It("should upload the logs PVC of the finished test pod", func() {
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, spec))
+
+			logsPVC := GetTestOperatorPVC(namespace, tobikoName.Name)
+			testPod := GetTestOperatorPod(namespace, tobikoName.Name)
+			uploaderName := types.NamespacedName{
+				Name:      testPod.Name + "-upload-1",
+				Namespace: namespace,
+			}
+			Expect(k8sClient.Get(ctx, uploaderName, &corev1.Pod{})).ShouldNot(Succeed())
+
+			SetTestOperatorPodPhase(testPod, corev1.PodSucceeded)
+
+			uploader := &corev1.Pod{}
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, uploaderName, uploader)).Should(Succeed())
+			}, timeout, interval).Should(Succeed())
+			Expect(metav1.IsControlledBy(uploader, GetTobiko(tobikoName))).To(BeTrue())
+			Expect(uploader.Labels).ToNot(HaveKey("instanceName"))
+			Expect(uploader.Spec.Volumes).To(ContainElement(HaveField("Name", "test-operator-logs")))
+			Expect(uploader.Spec.Volumes[0].PersistentVolumeClaim).ToNot(BeNil())
+			Expect(uploader.Spec.Volumes[0].PersistentVolumeClaim.ClaimName).To(Equal(logsPVC.Name))
+
+			container := uploader.Spec.Containers[0]
+			objectKey := fmt.Sprintf("ci/%s/%s/%s/", namespace, tobikoName.Name, testPod.Name)
+			// Only the logs directory of the test pod is uploaded
+			Expect(container.Command).To(ContainElements(
+				"/var/lib/test-operator-artifacts/"+testPod.Name,
+				"s3://test-logs/"+objectKey,
+				"--endpoint-url",
+				"http://minio.minio.svc:9000",
+			))
+			Expect(container.EnvFrom).To(HaveLen(1))
+			Expect(container.EnvFrom[0].SecretRef).ToNot(BeNil())
+			Expect(container.EnvFrom[0].SecretRef.Name).To(Equal("minio-credentials"))
+
+			Eventually(func(g Gomega) {
+				steps := GetTobiko(tobikoName).Status.Steps
+				g.Expect(steps).To(HaveLen(1))
+				g.Expect(steps[0].ArtifactsUploadPhase).To(Equal(corev1.PodPending))
+				g.Expect(steps[0].ArtifactsURL).To(BeEmpty())
+			}, timeout, interval).Should(Succeed())
+
+			// The lock is kept while the logs are uploaded
+			Expect(GetTestOperatorLock(namespace).Spec.HolderIdentity).To(
+				HaveValue(Equal(string(GetTobiko(tobikoName).UID))))
+
+			SetTestOperatorPodPhase(uploader, corev1.PodSucceeded)
+
+			Eventually(func(g Gomega) {
+				tobiko := GetTobiko(tobikoName)
+				g.Expect(tobiko.Status.Steps).To(HaveLen(1))
+				g.Expect(tobiko.Status.Steps[0].ArtifactsUploadPhase).To(Equal(corev1.PodSucceeded))
+				g.Expect(tobiko.Status.Steps[0].ArtifactsURL).To(Equal("http://minio.minio.svc:9000/test-logs/" + objectKey))
+				g.Expect(tobiko.Status.Conditions.IsTrue(testv1.ArtifactsUploadedCondition)).To(BeTrue())
+				g.Expect(k8s_errors.IsNotFound(k8sClient.Get(ctx, uploaderName, &corev1.Pod{}))).To(BeTrue())
+			}, timeout, interval).Should(Succeed())
+
+			ExpectTestOperatorLockReleased(namespace)
+		})
+
+		It("should retry a failed upload with a new pod", func() {
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, spec))
+
+			testPod := GetTestOperatorPod(namespace, tobikoName.Name)
+			SetTestOperatorPodPhase(testPod, corev1.PodSucceeded)
+
+			for attempt := 1; attempt <= 3; attempt++ {
+				uploaderName := types.NamespacedName{
+					Name:      fmt.Sprintf("%s-upload-%d", testPod.Name, attempt),
+					Namespace: namespace,
+				}
+
+				uploader := &corev1.Pod{}
+				Eventually(func(g Gomega) {
+					g.Expect(k8sClient.Get(ctx, uploaderName, uploader)).Should(Succeed())
+				}, timeout, interval).Should(Succeed())
+
+				Eventually(func(g Gomega) {
+					steps := GetTobiko(tobikoName).Status.Steps
+					g.Expect(steps).To(HaveLen(1))
+					g.Expect(steps[0].ArtifactsUploadAttempts).To(Equal(attempt))
+				}, timeout, interval).Should(Succeed())
+
+				SetTestOperatorPodPhase(uploader, corev1.PodFailed)
+			}
+
+			Eventually(func(g Gomega) {
+				tobiko := GetTobiko(tobikoName)
+				g.Expect(tobiko.Status.Steps[0].ArtifactsUploadPhase).To(Equal(corev1.PodFailed))
+				g.Expect(tobiko.Status.Steps[0].ArtifactsURL).To(BeEmpty())
+
+				uploaded := tobiko.Status.Conditions.Get(testv1.ArtifactsUploadedCondition)
+				g.Expect(uploaded).ToNot(BeNil())
+				g.Expect(uploaded.Status).To(Equal(corev1.ConditionFalse))
+				g.Expect(uploaded.Reason).To(Equal(testv1.ArtifactsUploadFailedReason))
+				g.Expect(tobiko.Status.Conditions.IsTrue(condition.ReadyCondition)).To(BeTrue())
+			}, timeout*2, interval).Should(Succeed())
+
+			Consistently(func(g Gomega) {
+				pods := &corev1.PodList{}
+				g.Expect(k8sClient.List(ctx, pods, client.InNamespace(namespace))).Should(Succeed())
+				g.Expect(pods.Items).ToNot(ContainElement(HaveField("Name", testPod.Name+"-upload-4")))
+			}, timeout, interval).Should(Succeed())
+
+			ExpectTestOperatorLockReleased(namespace)
+		})
+	})
+
+	When("Workflow steps are re-run", func() {
+		BeforeEach(func() {
+			spec := GetDefaultTobikoSpec()
+			spec["workflow"] = []map[string]any{
+				{"stepName": "first"},
+				{"stepName": "second"},
+			}
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, spec))
+		})
+
+		It("should re-run only the failed steps in the failed rerun mode", func() {
+			firstPod := GetTestOperatorPod(namespace, tobikoName.Name)
+			SetTestOperatorPodPhase(firstPod, corev1.PodSucceeded)
+
+			var secondPod corev1.Pod
+			Eventually(func(g Gomega) {
+				pods := GetTestOperatorPods(namespace, tobikoName.Name)
+				g.Expect(pods).To(HaveLen(2))
+				for _, pod := range pods {
+					if pod.Name != firstPod.Name {
+						secondPod = pod
+					}
+				}
+			}, timeout*2, interval).Should(Succeed())
+			SetTestOperatorPodPhase(&secondPod, corev1.PodFailed)
+
+			th.ExpectCondition(
+				tobikoName,
+				ConditionGetterFunc(TobikoConditionGetter),
+				testv1.ExecutionCompletedCondition,
+				corev1.ConditionTrue,
+			)
+
+			SetRerunAnnotation(GetTobiko(tobikoName), "failed-only", testv1.RerunModeFailed)
+
+			rerunPodName := secondPod.Name + "-run-1"
+			Eventually(func(g Gomega) {
+				pods := GetTestOperatorPods(namespace, tobikoName.Name)
+				g.Expect(pods).To(HaveLen(3))
+				g.Expect(pods).To(ContainElement(HaveField("ObjectMeta.Name", rerunPodName)))
+			}, timeout*2, interval).Should(Succeed())
+
+			rerunPod := &corev1.Pod{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: rerunPodName}, rerunPod)).Should(Succeed())
+			Expect(rerunPod.Labels).To(HaveKeyWithValue("workflowStep", "1"))
+			Expect(rerunPod.Labels).To(HaveKeyWithValue("runIndex", "1"))
+
+			SetTestOperatorPodPhase(rerunPod, corev1.PodSucceeded)
+			th.ExpectCondition(
+				tobikoName,
+				ConditionGetterFunc(TobikoConditionGetter),
+				testv1.TestsPassedCondition,
+				corev1.ConditionTrue,
+			)
+
+			status := GetTobiko(tobikoName).Status
+			Expect(status.Steps).To(HaveLen(3))
+			Expect(status.Steps[0].PodName).To(Equal(firstPod.Name))
+			Expect(status.Steps[0].Phase).To(Equal(testv1.WorkflowStepSucceeded))
+			Expect(status.Steps[1].PodName).To(Equal(secondPod.Name))
+			Expect(status.Steps[1].Phase).To(Equal(testv1.WorkflowStepFailed))
+			Expect(status.Steps[2].PodName).To(Equal(rerunPodName))
+			Expect(status.Steps[2].Phase).To(Equal(testv1.WorkflowStepSucceeded))
+			Expect(status.Steps[2].RunIndex).To(Equal(1))
+			Expect(status.StepsSummary.Total).To(Equal(2))
+			Expect(status.StepsSummary.Succeeded).To(Equal(2))
+			Expect(GetTestOperatorPods(namespace, tobikoName.Name)).To(HaveLen(3))
+		})
+	})
+
+	When("Workflow steps use dependsOn", func() {
+		BeforeEach(func() {
+			spec := GetDefaultTobikoSpec()
+			spec["workflow"] = []map[string]any{
+				{"stepName": "network"},
+				{"stepName": "compute"},
+				{
+					"stepName":  "report",
+					"dependsOn": []string{"network", "compute"},
+				},
+			}
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, spec))
+		})
+
+		It("should run independent steps at the same time", func() {
+			var pods []corev1.Pod
+			Eventually(func(g Gomega) {
+				pods = GetTestOperatorPods(namespace, tobikoName.Name)
+				g.Expect(pods).To(HaveLen(2))
+			}, timeout*2, interval).Should(Succeed())
+
+			for i := range pods {
+				SetTestOperatorPodPhase(&pods[i], corev1.PodSucceeded)
+			}
+
+			Eventually(func(g Gomega) {
+				g.Expect(GetTestOperatorPods(namespace, tobikoName.Name)).To(HaveLen(3))
+
+				steps := GetTobiko(tobikoName).Status.Steps
+				g.Expect(steps).To(HaveLen(3))
+				g.Expect(steps[2].DependsOn).To(ConsistOf("network", "compute"))
+			}, timeout*2, interval).Should(Succeed())
+		})
+
+		It("should reject an update that introduces a dependency cycle", func() {
+			tobiko := GetTobiko(tobikoName)
+			tobiko.Spec.Workflow[0].DependsOn = []string{"report"}
+			err := k8sClient.Update(ctx, tobiko)
+			Expect(k8s_errors.IsInvalid(err)).To(BeTrue())
+		})
+
+		It("should reject an update that depends on an unknown step", func() {
+			tobiko := GetTobiko(tobikoName)
+			tobiko.Spec.Workflow[2].DependsOn = []string{"storage"}
+			err := k8sClient.Update(ctx, tobiko)
+			Expect(k8s_errors.IsInvalid(err)).To(BeTrue())
+		})
+
+		It("should accept repeated step names in a workflow without dependsOn", func() {
+			spec := GetDefaultTobikoSpec()
+			spec["workflow"] = []map[string]any{
+				{"stepName": "smoke"},
+				{"stepName": "smoke"},
+			}
+			DeferCleanup(th.DeleteInstance, CreateTobiko(types.NamespacedName{
+				Name:      "tobiko-linear",
+				Namespace: namespace,
+			}, spec))
+		})
+	})
+
+	When("Multiple instances wait for the lock", func() {
+		var lowPriorityName types.NamespacedName
+		var highPriorityName types.NamespacedName
+
+		BeforeEach(func() {
+			lowPriorityName = types.NamespacedName{Name: "tobiko-low", Namespace: namespace}
+			highPriorityName = types.NamespacedName{Name: "tobiko-high", Namespace: namespace}
+
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, GetDefaultTobikoSpec()))
+		})
+
+		It("should acquire the lock in the order given by the priority", func() {
+			pod := GetTestOperatorPod(namespace, tobikoName.Name)
+
+			DeferCleanup(th.DeleteInstance, CreateTobiko(lowPriorityName, GetDefaultTobikoSpec()))
+			Eventually(func(g Gomega) {
+				g.Expect(GetTobiko(lowPriorityName).Status.QueuePosition).To(BeEquivalentTo(1))
+			}, timeout*2, interval).Should(Succeed())
+
+			spec := GetDefaultTobikoSpec()
+			spec["priority"] = 10
+			DeferCleanup(th.DeleteInstance, CreateTobiko(highPriorityName, spec))
+			Eventually(func(g Gomega) {
+				g.Expect(GetTobiko(highPriorityName).Status.QueuePosition).To(BeEquivalentTo(1))
+			}, timeout*2, interval).Should(Succeed())
+
+			SetTestOperatorPodPhase(pod, corev1.PodSucceeded)
+
+			GetTestOperatorPod(namespace, highPriorityName.Name)
+			Eventually(func(g Gomega) {
+				g.Expect(GetTobiko(highPriorityName).Status.QueuePosition).To(BeZero())
+			}, timeout*2, interval).Should(Succeed())
+
+			Eventually(func(g Gomega) {
+				g.Expect(GetTobiko(lowPriorityName).Status.QueuePosition).To(BeEquivalentTo(1))
+			}, timeout*2, interval).Should(Succeed())
+			Expect(GetTestOperatorPods(namespace, lowPriorityName.Name)).To(BeEmpty())
+		})
+
+		It("should not recreate the running test pod when the priority changes", func() {
+			pod := GetTestOperatorPod(namespace, tobikoName.Name)
+
+			Eventually(func(g Gomega) {
+				tobiko := GetTobiko(tobikoName)
+				tobiko.Spec.Priority = 10
+				g.Expect(k8sClient.Update(ctx, tobiko)).Should(Succeed())
+			}, timeout, interval).Should(Succeed())
+
+			Consistently(func(g Gomega) {
+				pods := GetTestOperatorPods(namespace, tobikoName.Name)
+				g.Expect(pods).To(HaveLen(1))
+				g.Expect(pods[0].UID).To(Equal(pod.UID))
+				g.Expect(pods[0].DeletionTimestamp).To(BeNil())
+			}, timeout, interval).Should(Succeed())
+		})
+	})
+
+	When("Instances belong to a concurrency group", func() {
+		var instanceNames []types.NamespacedName
+
+		BeforeEach(func() {
+			// The limits of the concurrency groups are read from the
+			// operator namespace
+			lockConfigMap := CreateTestOperatorConfigMap(TestOperatorLockNamespace)
+			lockConfigMap.Data["concurrency-groups"] = "smoke: 2\nserial: 1\n"
+			Expect(k8sClient.Create(ctx, lockConfigMap)).Should(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, lockConfigMap))).Should(Succeed())
+			})
+
+			instanceNames = []types.NamespacedName{}
+		})
+
+		It("should run at most the configured number of test pods at once", func() {
+			for _, name := range []string{"tobiko-a", "tobiko-b", "tobiko-c"} {
+				instanceName := types.NamespacedName{Name: name, Namespace: namespace}
+				instanceNames = append(instanceNames, instanceName)
+
+				spec := GetDefaultTobikoSpec()
+				spec["concurrencyGroup"] = "smoke"
+				DeferCleanup(th.DeleteInstance, CreateTobiko(instanceName, spec))
+			}
+
+			var runningPods []corev1.Pod
+			var waitingName types.NamespacedName
+			Eventually(func(g Gomega) {
+				runningPods = []corev1.Pod{}
+				waiting := []types.NamespacedName{}
+				for _, instanceName := range instanceNames {
+					pods := GetTestOperatorPods(namespace, instanceName.Name)
+					if len(pods) == 0 {
+						waiting = append(waiting, instanceName)
+					}
+					runningPods = append(runningPods, pods...)
+				}
+
+				g.Expect(runningPods).To(HaveLen(2))
+				g.Expect(waiting).To(HaveLen(1))
+				waitingName = waiting[0]
+				g.Expect(GetTobiko(waitingName).Status.QueuePosition).To(BeEquivalentTo(1))
+			}, timeout*3, interval).Should(Succeed())
+
+			Expect(GetSemaphoreSlots("smoke")).To(HaveLen(2))
+
+			SetTestOperatorPodPhase(&runningPods[0], corev1.PodSucceeded)
+
+			GetTestOperatorPod(namespace, waitingName.Name)
+			Eventually(func(g Gomega) {
+				slots := GetSemaphoreSlots("smoke")
+				g.Expect(slots).To(HaveLen(2))
+
+				holders := []string{}
+				for _, slot := range slots {
+					holders = append(holders, *slot.Spec.HolderIdentity)
+				}
+				g.Expect(holders).To(ContainElement(string(GetTobiko(waitingName).UID)))
+			}, timeout*2, interval).Should(Succeed())
+		})
+
+		It("should count the test pods of a workflow instead of the instances", func() {
+			spec := GetDefaultTobikoSpec()
+			spec["concurrencyGroup"] = "serial"
+			spec["workflow"] = []map[string]any{
+				{"stepName": "network"},
+				{"stepName": "compute"},
+				{
+					"stepName":  "report",
+					"dependsOn": []string{"network", "compute"},
+				},
+			}
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, spec))
+
+			pod := GetTestOperatorPod(namespace, tobikoName.Name)
+			Consistently(func(g Gomega) {
+				g.Expect(GetTestOperatorPods(namespace, tobikoName.Name)).To(HaveLen(1))
+			}, timeout, interval).Should(Succeed())
+
+			SetTestOperatorPodPhase(pod, corev1.PodSucceeded)
+
+			Eventually(func(g Gomega) {
+				g.Expect(GetTestOperatorPods(namespace, tobikoName.Name)).To(HaveLen(2))
+
+				slots := GetSemaphoreSlots("serial")
+				g.Expect(slots).To(HaveLen(1))
+				g.Expect(slots[0].Annotations).To(HaveKeyWithValue(
+					"test.openstack.org/holder-pod", Not(Equal(pod.Name))))
+			}, timeout*2, interval).Should(Succeed())
+		})
+
+		It("should reject a concurrency group together with the Cluster lock scope", func() {
+			spec := GetDefaultTobikoSpec()
+			spec["concurrencyGroup"] = "smoke"
+			spec["lockScope"] = "Cluster"
+
+			tobiko := &unstructured.Unstructured{Object: map[string]any{
+				"apiVersion": "test.openstack.org/v1beta1",
+				"kind":       "Tobiko",
+				"metadata": map[string]any{
+					"name":      tobikoName.Name,
+					"namespace": tobikoName.Namespace,
+				},
+				"spec": spec,
+			}}
+			err := k8sClient.Create(ctx, tobiko)
+			Expect(k8s_errors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("concurrencyGroup"))
+		})
+	})
+
+	When("Instances from different namespaces share the lock", func() {
+		var otherTobikoName types.NamespacedName
+
+		BeforeEach(func() {
+			otherNamespace := uuid.New().String()
+			th.CreateNamespace(otherNamespace)
+			DeferCleanup(th.DeleteNamespace, otherNamespace)
+
+			otherTobikoName = types.NamespacedName{Name: "tobiko", Namespace: otherNamespace}
+
+			CreateTestOperatorResources(otherNamespace)
+		})
+
+		DescribeTable("should run one instance at a time",
+			func(lockGroup string) {
+				spec := GetDefaultTobikoSpec()
+				spec["lockScope"] = "Cluster"
+				if lockGroup != "" {
+					spec["lockGroup"] = lockGroup
+				}
+
+				DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, spec))
+				pod := GetTestOperatorPod(namespace, tobikoName.Name)
+
+				lockName := GetTobiko(tobikoName).Status.LockName
+				Expect(lockName).To(HavePrefix(TestOperatorLockName + "-"))
+				if lockGroup != "" {
+					Expect(lockName).To(Equal(TestOperatorLockName + "-" + lockGroup))
+				}
+				DeferCleanup(func() {
+					lock := &coordinationv1.Lease{}
+					lock.Name = lockName
+					lock.Namespace = TestOperatorLockNamespace
+					Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, lock))).Should(Succeed())
+				})
+
+				DeferCleanup(th.DeleteInstance, CreateTobiko(otherTobikoName, spec))
+				Eventually(func(g Gomega) {
+					otherTobiko := GetTobiko(otherTobikoName)
+					g.Expect(otherTobiko.Status.LockName).To(Equal(lockName))
+					g.Expect(otherTobiko.Status.QueuePosition).To(BeEquivalentTo(1))
+				}, timeout*2, interval).Should(Succeed())
+				Expect(GetTestOperatorPods(otherTobikoName.Namespace, otherTobikoName.Name)).To(BeEmpty())
+
+				SetTestOperatorPodPhase(pod, corev1.PodSucceeded)
+
+				GetTestOperatorPod(otherTobikoName.Namespace, otherTobikoName.Name)
+				Eventually(func(g Gomega) {
+					g.Expect(GetTobiko(otherTobikoName).Status.QueuePosition).To(BeZero())
+				}, timeout*2, interval).Should(Succeed())
+			},
+			Entry("when they use the same lock group", "shared-cloud"),
+			Entry("when they test the same cloud", ""),
+		)
+
+		It("should release the lock it holds when the auth URL of the cloud changed", func() {
+			spec := GetDefaultTobikoSpec()
+			spec["lockScope"] = "Cluster"
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, spec))
+			pod := GetTestOperatorPod(namespace, tobikoName.Name)
+
+			lockName := types.NamespacedName{
+				Name:      GetTobiko(tobikoName).Status.LockName,
+				Namespace: TestOperatorLockNamespace,
+			}
+			Expect(k8sClient.Get(ctx, lockName, &coordinationv1.Lease{})).Should(Succeed())
+
+			Eventually(func(g Gomega) {
+				openstackConfigMap := &corev1.ConfigMap{}
+				g.Expect(k8sClient.Get(ctx, types.NamespacedName{
+					Name:      OpenStackConfigMapName,
+					Namespace: namespace,
+				}, openstackConfigMap)).Should(Succeed())
+				openstackConfigMap.Data["clouds.yaml"] = "clouds:\n  default:\n    auth:\n" +
+					"      auth_url: https://other.example.com/v3\n      username: admin"
+				g.Expect(k8sClient.Update(ctx, openstackConfigMap)).Should(Succeed())
+			}, timeout, interval).Should(Succeed())
+
+			SetTestOperatorPodPhase(pod, corev1.PodSucceeded)
+
+			Eventually(func(g Gomega) {
+				err := k8sClient.Get(ctx, lockName, &coordinationv1.Lease{})
+				g.Expect(k8s_errors.IsNotFound(err)).To(BeTrue())
+				g.Expect(GetTobiko(tobikoName).Status.LockName).To(Equal(lockName.Name))
+			}, timeout*2, interval).Should(Succeed())
+		})
+
+		It("should release the lock when deleted after its clouds.yaml ConfigMap was removed", func() {
+			spec := GetDefaultTobikoSpec()
+			spec["lockScope"] = "Cluster"
+			tobiko := CreateTobiko(tobikoName, spec)
+			GetTestOperatorPod(namespace, tobikoName.Name)
+
+			lockName := types.NamespacedName{
+				Name:      GetTobiko(tobikoName).Status.LockName,
+				Namespace: TestOperatorLockNamespace,
+			}
+			Expect(k8sClient.Get(ctx, lockName, &coordinationv1.Lease{})).Should(Succeed())
+
+			openstackConfigMap := &corev1.ConfigMap{}
+			openstackConfigMap.Name = OpenStackConfigMapName
+			openstackConfigMap.Namespace = namespace
+			Expect(k8sClient.Delete(ctx, openstackConfigMap)).Should(Succeed())
+
+			th.DeleteInstance(tobiko)
+
+			Eventually(func(g Gomega) {
+				err := k8sClient.Get(ctx, lockName, &coordinationv1.Lease{})
+				g.Expect(k8s_errors.IsNotFound(err)).To(BeTrue())
+			}, timeout*2, interval).Should(Succeed())
+		})
+	})
+
+	When("A parallel instance finishes while the lock is held", func() {
+		var parallelName types.NamespacedName
+
+		BeforeEach(func() {
+			parallelName = types.NamespacedName{Name: "tobiko-parallel", Namespace: namespace}
+
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, GetDefaultTobikoSpec()))
+		})
+
+		It("should finish without releasing the lock of the other instance", func() {
+			GetTestOperatorPod(namespace, tobikoName.Name)
+
+			spec := GetDefaultTobikoSpec()
+			spec["parallel"] = true
+			DeferCleanup(th.DeleteInstance, CreateTobiko(parallelName, spec))
+
+			SetTestOperatorPodPhase(GetTestOperatorPod(namespace, parallelName.Name), corev1.PodSucceeded)
+
+			th.ExpectCondition(
+				parallelName,
+				ConditionGetterFunc(TobikoConditionGetter),
+				testv1.ExecutionCompletedCondition,
+				corev1.ConditionTrue,
+			)
+
+			lock := GetTestOperatorLock(namespace)
+			Expect(lock.Spec.HolderIdentity).To(HaveValue(Equal(string(GetTobiko(tobikoName).UID))))
+		})
+	})
+
+	When("The lock is held by an instance that does not exist anymore", func() {
+		BeforeEach(func() {
+			staleLock := CreateTestOperatorLease(namespace, "deleted-instance-uid", time.Now())
+			staleLock.Annotations = map[string]string{
+				"test.openstack.org/holder-kind": "Tobiko",
+				"test.openstack.org/holder-name": "deleted-instance",
+			}
+			Expect(k8sClient.Create(ctx, staleLock)).Should(Succeed())
+
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, GetDefaultTobikoSpec()))
+		})
+
+		It("should take over the lock and record an event", func() {
+			GetTestOperatorPod(namespace, tobikoName.Name)
+
+			lock := GetTestOperatorLock(namespace)
+			Expect(lock.Spec.HolderIdentity).To(HaveValue(Equal(string(GetTobiko(tobikoName).UID))))
+			Expect(lock.Spec.LeaseTransitions).To(HaveValue(BeEquivalentTo(1)))
+			Expect(lock.Spec.RenewTime).NotTo(BeNil())
+			Expect(lock.Annotations).To(HaveKeyWithValue("test.openstack.org/holder-kind", "Tobiko"))
+			Expect(lock.Annotations).To(HaveKeyWithValue("test.openstack.org/holder-name", tobikoName.Name))
+
+			Eventually(func(g Gomega) {
+				events := &corev1.EventList{}
+				g.Expect(k8sClient.List(ctx, events, client.InNamespace(namespace))).Should(Succeed())
+				g.Expect(events.Items).To(ContainElement(And(
+					HaveField("Reason", "LockTakenOver"),
+					HaveField("InvolvedObject.Name", tobikoName.Name),
+				)))
+			}, timeout*2, interval).Should(Succeed())
+		})
+	})
+
+	When("The lease of the lock expired", func() {
+		BeforeEach(func() {
+			expiredLock := CreateTestOperatorLease(namespace, "unknown-holder-uid", time.Now().Add(-10*time.Minute))
+			Expect(k8sClient.Create(ctx, expiredLock)).Should(Succeed())
+
+			DeferCleanup(th.DeleteInstance, CreateTobiko(tobikoName, GetDefaultTobikoSpec()))
+		})
+
+		It("should take over the lock", func() {
+			GetTestOperatorPod(namespace, tobikoName.Name)
+
+			lock := GetTestOperatorLock(namespace)
+			Expect(lock.Spec.HolderIdentity).To(HaveValue(Equal(string(GetTobiko(tobikoName).UID))))
+			Expect(lock.Spec.RenewTime.Time).To(BeTemporally("~", time.Now(), time.Minute))
+		})
+	})
+})
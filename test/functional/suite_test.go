@@ -19,6 +19,7 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -141,6 +142,9 @@ var _ = BeforeSuite(func() {
 				CertDir: webhookInstallOptions.LocalServingCertDir,
 			}),
 		LeaderElection: false,
+		Cache: cache.Options{
+			ByObject: controller.LeaseCacheByObject(),
+		},
 	})
 	Expect(err).ToNot(HaveOccurred())
 
@@ -159,45 +163,92 @@ var _ = BeforeSuite(func() {
 	err = webhookv1beta1.SetupTobikoWebhookWithManager(k8sManager)
 	Expect(err).NotTo(HaveOccurred())
 
+	err = webhookv1beta1.SetupTestSuiteWebhookWithManager(k8sManager)
+	Expect(err).NotTo(HaveOccurred())
+
 	testv1.SetupDefaults()
 
+	th.CreateNamespace(TestOperatorLockNamespace)
+
 	err = (&controller.AnsibleTestReconciler{
 		Reconciler: controller.Reconciler{
-			Client:  k8sManager.GetClient(),
-			Scheme:  k8sManager.GetScheme(),
-			Kclient: kclient,
-			Log:     logger,
+			Client:        k8sManager.GetClient(),
+			Scheme:        k8sManager.GetScheme(),
+			Kclient:       kclient,
+			Log:           logger,
+			LockNamespace: TestOperatorLockNamespace,
+			Recorder:      k8sManager.GetEventRecorderFor("ansibletest-controller"),
 		},
 	}).SetupWithManager(k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
 	err = (&controller.HorizonTestReconciler{
 		Reconciler: controller.Reconciler{
-			Client:  k8sManager.GetClient(),
-			Scheme:  k8sManager.GetScheme(),
-			Kclient: kclient,
-			Log:     logger,
+			Client:        k8sManager.GetClient(),
+			Scheme:        k8sManager.GetScheme(),
+			Kclient:       kclient,
+			Log:           logger,
+			LockNamespace: TestOperatorLockNamespace,
+			Recorder:      k8sManager.GetEventRecorderFor("horizontest-controller"),
 		},
 	}).SetupWithManager(k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
 	err = (&controller.TempestReconciler{
 		Reconciler: controller.Reconciler{
-			Client:  k8sManager.GetClient(),
-			Scheme:  k8sManager.GetScheme(),
-			Kclient: kclient,
-			Log:     logger,
+			Client:        k8sManager.GetClient(),
+			Scheme:        k8sManager.GetScheme(),
+			Kclient:       kclient,
+			Log:           logger,
+			LockNamespace: TestOperatorLockNamespace,
+			Recorder:      k8sManager.GetEventRecorderFor("tempest-controller"),
 		},
 	}).SetupWithManager(k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
 	err = (&controller.TobikoReconciler{
 		Reconciler: controller.Reconciler{
-			Client:  k8sManager.GetClient(),
-			Scheme:  k8sManager.GetScheme(),
-			Kclient: kclient,
-			Log:     logger,
+			Client:        k8sManager.GetClient(),
+			Scheme:        k8sManager.GetScheme(),
+			Kclient:       kclient,
+			Log:           logger,
+			LockNamespace: TestOperatorLockNamespace,
+			Recorder:      k8sManager.GetEventRecorderFor("tobiko-controller"),
+		},
+	}).SetupWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = (&controller.TestScheduleReconciler{
+		Reconciler: controller.Reconciler{
+			Client:   k8sManager.GetClient(),
+			Scheme:   k8sManager.GetScheme(),
+			Kclient:  kclient,
+			Log:      logger,
+			Recorder: k8sManager.GetEventRecorderFor("testschedule-controller"),
+		},
+	}).SetupWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = (&controller.TestSuiteReconciler{
+		Reconciler: controller.Reconciler{
+			Client:   k8sManager.GetClient(),
+			Scheme:   k8sManager.GetScheme(),
+			Kclient:  kclient,
+			Log:      logger,
+			Recorder: k8sManager.GetEventRecorderFor("testsuite-controller"),
+		},
+	}).SetupWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = (&controller.LogsRetentionReconciler{
+		Reconciler: controller.Reconciler{
+			Client:   k8sManager.GetClient(),
+			Scheme:   k8sManager.GetScheme(),
+			Kclient:  kclient,
+			Log:      logger,
+			Recorder: k8sManager.GetEventRecorderFor("logsretention-controller"),
 		},
+		Interval: time.Second,
 	}).SetupWithManager(k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
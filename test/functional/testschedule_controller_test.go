@@ -0,0 +1,232 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package functional_test
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2" //revive:disable:dot-imports
+	. "github.com/onsi/gomega"    //revive:disable:dot-imports
+
+	"github.com/openstack-k8s-operators/lib-common/modules/common/condition"
+	testv1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
+	//revive:disable-next-line:dot-imports
+	. "github.com/openstack-k8s-operators/lib-common/modules/common/test/helpers"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("TestSchedule controller", func() {
+	var testScheduleName types.NamespacedName
+
+	BeforeEach(func() {
+		testScheduleName = types.NamespacedName{
+			Name:      "nightly",
+			Namespace: namespace,
+		}
+	})
+
+	When("A TestSchedule instance with an invalid schedule is created", func() {
+		BeforeEach(func() {
+			spec := GetDefaultTestScheduleSpec()
+			spec["schedule"] = "0 25 * * *"
+			DeferCleanup(th.DeleteInstance, CreateTestSchedule(testScheduleName, spec))
+		})
+
+		It("should have Ready condition false", func() {
+			th.ExpectConditionWithDetails(
+				testScheduleName,
+				ConditionGetterFunc(ScheduleConditionGetter),
+				condition.ReadyCondition,
+				corev1.ConditionFalse,
+				condition.ErrorReason,
+				fmt.Sprintf(testv1.TestScheduleInvalidMessage, "0 25 * * *", `hour "25": value out of range`),
+			)
+		})
+	})
+
+	When("A TestSchedule instance is created", func() {
+		BeforeEach(func() {
+			DeferCleanup(th.DeleteInstance, CreateTestSchedule(testScheduleName, GetDefaultTestScheduleSpec()))
+		})
+
+		It("should have Ready condition true", func() {
+			th.ExpectCondition(
+				testScheduleName,
+				ConditionGetterFunc(ScheduleConditionGetter),
+				condition.ReadyCondition,
+				corev1.ConditionTrue,
+			)
+		})
+
+		It("should report the next scheduled run", func() {
+			Eventually(func(g Gomega) {
+				testSchedule := GetTestSchedule(testScheduleName)
+				g.Expect(testSchedule.Status.NextScheduleTime).ToNot(BeNil())
+				g.Expect(testSchedule.Status.NextScheduleTime.Time).To(BeTemporally(">", time.Now()))
+				g.Expect(testSchedule.Status.NextScheduleTime.Second()).To(Equal(0))
+			}, timeout, interval).Should(Succeed())
+		})
+
+		It("should create a Tobiko instance for a missed run", func() {
+			th.ExpectCondition(
+				testScheduleName,
+				ConditionGetterFunc(ScheduleConditionGetter),
+				condition.ReadyCondition,
+				corev1.ConditionTrue,
+			)
+
+			SetTestScheduleLastScheduleTime(testScheduleName, time.Now().Add(-2*time.Minute))
+
+			tobikos := &testv1.TobikoList{}
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.List(ctx, tobikos,
+					client.InNamespace(namespace),
+					client.MatchingLabels{"testSchedule": testScheduleName.Name},
+				)).Should(Succeed())
+				g.Expect(tobikos.Items).To(HaveLen(1))
+			}, timeout, interval).Should(Succeed())
+			tobiko := &tobikos.Items[0]
+			DeferCleanup(th.DeleteInstance, tobiko)
+
+			scheduledAt, err := time.Parse(time.RFC3339, tobiko.Annotations["test.openstack.org/scheduled-at"])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tobiko.Name).To(Equal(fmt.Sprintf("%s-%d", testScheduleName.Name, scheduledAt.Unix()/60)))
+			Expect(metav1.IsControlledBy(tobiko, GetTestSchedule(testScheduleName))).To(BeTrue())
+			Expect(tobiko.Spec.Testenv).To(Equal("sanity"))
+			Expect(tobiko.Spec.StorageClass).To(Equal(DefaultStorageClass))
+
+			Eventually(func(g Gomega) {
+				testSchedule := GetTestSchedule(testScheduleName)
+				g.Expect(testSchedule.Status.LastScheduleTime).ToNot(BeNil())
+				g.Expect(testSchedule.Status.LastScheduleTime.Time.Equal(scheduledAt)).To(BeTrue())
+				g.Expect(testSchedule.Status.Active).To(HaveLen(1))
+				g.Expect(testSchedule.Status.Active[0].Kind).To(Equal("Tobiko"))
+				g.Expect(testSchedule.Status.Active[0].Name).To(Equal(tobiko.Name))
+			}, timeout, interval).Should(Succeed())
+		})
+
+		It("should shorten the name of the Tobiko instance created by a schedule with a long name", func() {
+			longScheduleName := types.NamespacedName{
+				Name:      "nightly-tobiko-faults-in-the-disruptive-tests-for-the-ha-setup",
+				Namespace: namespace,
+			}
+			DeferCleanup(th.DeleteInstance, CreateTestSchedule(longScheduleName, GetDefaultTestScheduleSpec()))
+			th.ExpectCondition(
+				longScheduleName,
+				ConditionGetterFunc(ScheduleConditionGetter),
+				condition.ReadyCondition,
+				corev1.ConditionTrue,
+			)
+
+			SetTestScheduleLastScheduleTime(longScheduleName, time.Now().Add(-2*time.Minute))
+
+			tobikos := &testv1.TobikoList{}
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.List(ctx, tobikos,
+					client.InNamespace(namespace),
+					client.MatchingLabels{"testSchedule": longScheduleName.Name},
+				)).Should(Succeed())
+				g.Expect(tobikos.Items).To(HaveLen(1))
+			}, timeout, interval).Should(Succeed())
+			tobiko := &tobikos.Items[0]
+			DeferCleanup(th.DeleteInstance, tobiko)
+
+			scheduledAt, err := time.Parse(time.RFC3339, tobiko.Annotations["test.openstack.org/scheduled-at"])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(tobiko.Name)).To(BeNumerically("<=", 63))
+			Expect(tobiko.Name).To(HavePrefix("nightly-tobiko-faults-in-the-disruptive"))
+			Expect(tobiko.Name).To(HaveSuffix(fmt.Sprintf("-%d", scheduledAt.Unix()/60)))
+		})
+
+		It("should not create a Tobiko instance for runs missing the starting deadline", func() {
+			Eventually(func(g Gomega) {
+				testSchedule := GetTestSchedule(testScheduleName)
+				deadline := int64(0)
+				testSchedule.Spec.StartingDeadlineSeconds = &deadline
+				g.Expect(k8sClient.Update(ctx, testSchedule)).Should(Succeed())
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func(g Gomega) {
+				testSchedule := GetTestSchedule(testScheduleName)
+				g.Expect(testSchedule.Status.ObservedGeneration).To(Equal(testSchedule.Generation))
+			}, timeout, interval).Should(Succeed())
+
+			SetTestScheduleLastScheduleTime(testScheduleName, time.Now().Add(-2*time.Minute))
+
+			Consistently(func(g Gomega) {
+				tobikos := &testv1.TobikoList{}
+				g.Expect(k8sClient.List(ctx, tobikos,
+					client.InNamespace(namespace),
+					client.MatchingLabels{"testSchedule": testScheduleName.Name},
+				)).Should(Succeed())
+				g.Expect(tobikos.Items).To(BeEmpty())
+			}, timeout, interval).Should(Succeed())
+		})
+	})
+
+	When("A TestSchedule instance with the Forbid concurrency policy is created", func() {
+		BeforeEach(func() {
+			spec := GetDefaultTestScheduleSpec()
+			spec["concurrencyPolicy"] = "Forbid"
+			DeferCleanup(th.DeleteInstance, CreateTestSchedule(testScheduleName, spec))
+		})
+
+		It("should skip the run while the previous run did not finish", func() {
+			th.ExpectCondition(
+				testScheduleName,
+				ConditionGetterFunc(ScheduleConditionGetter),
+				condition.ReadyCondition,
+				corev1.ConditionTrue,
+			)
+
+			SetTestScheduleLastScheduleTime(testScheduleName, time.Now().Add(-2*time.Minute))
+
+			tobikos := &testv1.TobikoList{}
+			listTobikos := func(g Gomega) {
+				g.Expect(k8sClient.List(ctx, tobikos,
+					client.InNamespace(namespace),
+					client.MatchingLabels{"testSchedule": testScheduleName.Name},
+				)).Should(Succeed())
+			}
+			Eventually(func(g Gomega) {
+				listTobikos(g)
+				g.Expect(tobikos.Items).To(HaveLen(1))
+			}, timeout, interval).Should(Succeed())
+			tobiko := tobikos.Items[0]
+			DeferCleanup(th.DeleteInstance, &tobiko)
+
+			// The skipped run is recorded as the last scheduled run so that
+			// it is not started once the previous run finishes
+			missedAt := time.Now().Add(-2 * time.Minute)
+			SetTestScheduleLastScheduleTime(testScheduleName, missedAt)
+			Eventually(func(g Gomega) {
+				testSchedule := GetTestSchedule(testScheduleName)
+				g.Expect(testSchedule.Status.LastScheduleTime).ToNot(BeNil())
+				g.Expect(testSchedule.Status.LastScheduleTime.Time).To(BeTemporally(">", missedAt))
+			}, timeout, interval).Should(Succeed())
+
+			Consistently(func(g Gomega) {
+				listTobikos(g)
+				g.Expect(tobikos.Items).To(HaveLen(1))
+			}, timeout, interval).Should(Succeed())
+		})
+	})
+})
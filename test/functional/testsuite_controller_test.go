@@ -0,0 +1,197 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package functional_test
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2" //revive:disable:dot-imports
+	. "github.com/onsi/gomega"    //revive:disable:dot-imports
+
+	"github.com/openstack-k8s-operators/lib-common/modules/common/condition"
+	testv1 "github.com/openstack-k8s-operators/test-operator/api/v1beta1"
+	//revive:disable-next-line:dot-imports
+	. "github.com/openstack-k8s-operators/lib-common/modules/common/test/helpers"
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("TestSuite controller", func() {
+	var testSuiteName types.NamespacedName
+	var firstTobikoName types.NamespacedName
+	var secondTobikoName types.NamespacedName
+
+	BeforeEach(func() {
+		testSuiteName = types.NamespacedName{
+			Name:      "suite",
+			Namespace: namespace,
+		}
+		firstTobikoName = types.NamespacedName{
+			Name:      "suite-first",
+			Namespace: namespace,
+		}
+		secondTobikoName = types.NamespacedName{
+			Name:      "suite-second",
+			Namespace: namespace,
+		}
+	})
+
+	When("A TestSuite instance with a dependency cycle is created", func() {
+		It("should be rejected by the webhook", func() {
+			spec := GetDefaultTestSuiteSpec()
+			workflow := spec["workflow"].([]any)
+			workflow[0].(map[string]any)["dependsOn"] = []any{"second"}
+			workflow[1].(map[string]any)["dependsOn"] = []any{"first"}
+
+			testSuite := &unstructured.Unstructured{Object: map[string]any{
+				"apiVersion": "test.openstack.org/v1beta1",
+				"kind":       "TestSuite",
+				"metadata": map[string]any{
+					"name":      testSuiteName.Name,
+					"namespace": testSuiteName.Namespace,
+				},
+				"spec": spec,
+			}}
+			Expect(k8sClient.Create(ctx, testSuite)).ShouldNot(Succeed())
+		})
+	})
+
+	When("A TestSuite instance creates a test CR with too long pod names", func() {
+		It("should be rejected by the webhook", func() {
+			tobikoSpec := GetDefaultTobikoSpec()
+			// The test pod suite-first-s00-<step name> exceeds 63 characters
+			tobikoSpec["workflow"] = []any{
+				map[string]any{"stepName": strings.Repeat("s", 48)},
+			}
+			spec := GetDefaultTestSuiteSpec()
+			workflow := spec["workflow"].([]any)
+			workflow[0].(map[string]any)["template"].(map[string]any)["spec"] = tobikoSpec
+
+			testSuite := &unstructured.Unstructured{Object: map[string]any{
+				"apiVersion": "test.openstack.org/v1beta1",
+				"kind":       "TestSuite",
+				"metadata": map[string]any{
+					"name":      testSuiteName.Name,
+					"namespace": testSuiteName.Namespace,
+				},
+				"spec": spec,
+			}}
+			err := k8sClient.Create(ctx, testSuite)
+			Expect(k8s_errors.IsInvalid(err)).To(BeTrue())
+		})
+	})
+
+	When("A TestSuite instance refers to a missing test CR", func() {
+		BeforeEach(func() {
+			spec := GetDefaultTestSuiteSpec()
+			workflow := spec["workflow"].([]any)
+			workflow[0] = map[string]any{
+				"stepName": "first",
+				"templateRef": map[string]any{
+					"kind": "Tempest",
+					"name": "missing",
+				},
+			}
+			DeferCleanup(th.DeleteInstance, CreateTestSuite(testSuiteName, spec))
+		})
+
+		It("should have Ready condition false", func() {
+			th.ExpectCondition(
+				testSuiteName,
+				ConditionGetterFunc(SuiteConditionGetter),
+				condition.ReadyCondition,
+				corev1.ConditionFalse,
+			)
+		})
+	})
+
+	When("A TestSuite instance is created", func() {
+		BeforeEach(func() {
+			openstackConfigMap, openstackSecret := CreateCommonOpenstackResources(namespace)
+			Expect(k8sClient.Create(ctx, openstackConfigMap)).Should(Succeed())
+			Expect(k8sClient.Create(ctx, openstackSecret)).Should(Succeed())
+
+			testOperatorConfigMap := CreateTestOperatorConfigMap(namespace)
+			Expect(k8sClient.Create(ctx, testOperatorConfigMap)).Should(Succeed())
+
+			DeferCleanup(th.DeleteInstance, CreateTestSuite(testSuiteName, GetDefaultTestSuiteSpec()))
+		})
+
+		It("should create the test CR of the first step only", func() {
+			tobiko := GetTobiko(firstTobikoName)
+			DeferCleanup(th.DeleteInstance, tobiko)
+
+			Expect(metav1.IsControlledBy(tobiko, GetTestSuite(testSuiteName))).To(BeTrue())
+			Expect(tobiko.Labels).To(HaveKeyWithValue("testSuite", testSuiteName.Name))
+			Expect(tobiko.Labels).To(HaveKeyWithValue("testSuiteStep", "first"))
+			Expect(tobiko.Spec.Testenv).To(Equal("sanity"))
+
+			Eventually(func(g Gomega) {
+				status := GetTestSuite(testSuiteName).Status
+				g.Expect(status.Steps).To(HaveLen(2))
+				g.Expect(status.Steps[0].Phase).To(Equal(testv1.WorkflowStepRunning))
+				g.Expect(status.Steps[0].Kind).To(Equal("Tobiko"))
+				g.Expect(status.Steps[0].InstanceName).To(Equal(firstTobikoName.Name))
+				g.Expect(status.Steps[1].Phase).To(Equal(testv1.WorkflowStepPending))
+			}, timeout, interval).Should(Succeed())
+
+			Consistently(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, secondTobikoName, &testv1.Tobiko{})).ShouldNot(Succeed())
+			}, timeout, interval).Should(Succeed())
+		})
+
+		It("should run the steps in order and combine their results", func() {
+			DeferCleanup(th.DeleteInstance, GetTobiko(firstTobikoName))
+			SetTestOperatorPodPhase(GetTestOperatorPod(namespace, firstTobikoName.Name), corev1.PodSucceeded)
+
+			DeferCleanup(th.DeleteInstance, GetTobiko(secondTobikoName))
+			SetTestOperatorPodPhase(GetTestOperatorPod(namespace, secondTobikoName.Name), corev1.PodFailed)
+
+			th.ExpectCondition(
+				testSuiteName,
+				ConditionGetterFunc(SuiteConditionGetter),
+				testv1.ExecutionCompletedCondition,
+				corev1.ConditionTrue,
+			)
+			th.ExpectConditionWithDetails(
+				testSuiteName,
+				ConditionGetterFunc(SuiteConditionGetter),
+				testv1.TestsPassedCondition,
+				corev1.ConditionFalse,
+				testv1.TestsFailedReason,
+				fmt.Sprintf(testv1.TestsPassedErrorMessage, "second"),
+			)
+			th.ExpectCondition(
+				testSuiteName,
+				ConditionGetterFunc(SuiteConditionGetter),
+				condition.ReadyCondition,
+				corev1.ConditionTrue,
+			)
+
+			status := GetTestSuite(testSuiteName).Status
+			Expect(status.Steps[0].Phase).To(Equal(testv1.WorkflowStepSucceeded))
+			Expect(status.Steps[1].Phase).To(Equal(testv1.WorkflowStepFailed))
+			Expect(status.StepsSummary).ToNot(BeNil())
+			Expect(status.StepsSummary.Succeeded).To(Equal(1))
+			Expect(status.StepsSummary.Failed).To(Equal(1))
+		})
+	})
+})